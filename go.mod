@@ -6,6 +6,7 @@ require (
 	github.com/aws/aws-sdk-go v1.37.0
 	github.com/go-pg/pg/v10 v10.7.4
 	github.com/karrick/godirwalk v1.16.1
+	github.com/klauspost/compress v1.16.7
 	github.com/sirupsen/logrus v1.8.1
 	github.com/stretchr/testify v1.7.0
 	google.golang.org/grpc v1.27.0
@@ -0,0 +1,101 @@
+// Package atomicfile writes local files so they are never seen half written
+package atomicfile
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// TempPrefix name prefix of the files that are being written
+const TempPrefix = ".tmp-"
+
+// FileMode default permissions of the written files
+const FileMode fs.FileMode = 0644
+
+// DirMode default permissions of the created directories
+const DirMode fs.FileMode = 0755
+
+// contextReader stops reading once the context is canceled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// Read reads from underlying reader if the context is still active
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.r.Read(p)
+}
+
+// Write stream the body into temporary file in the same directory, sync it and rename it into place,
+// so the file at the location is either the old one or the complete new one.
+// Missing parent directories are created with dirMode.
+func Write(ctx context.Context, loc string, body io.Reader, mode fs.FileMode, dirMode fs.FileMode) error {
+	dir, name := filepath.Split(loc)
+
+	if len(dir) > 0 {
+		if err := os.MkdirAll(dir, dirMode); err != nil {
+			return err
+		}
+	}
+
+	tmp, err := os.CreateTemp(dir, TempPrefix+name+"-*")
+
+	if err != nil {
+		return err
+	}
+
+	if err := copyFile(tmp, &contextReader{ctx, body}, mode); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), loc); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return SyncDir(dir)
+}
+
+// SyncDir flush directory entries to disk so the rename survives a crash,
+// it's best effort because not every platform can sync directories
+func SyncDir(dir string) error {
+	if len(dir) == 0 {
+		dir = "."
+	}
+
+	d, err := os.Open(dir)
+
+	if err != nil {
+		return err
+	}
+
+	_ = d.Sync()
+	return d.Close()
+}
+
+// copyFile write the body into the file, set permissions, flush it to disk and close it
+func copyFile(file *os.File, body io.Reader, mode fs.FileMode) error {
+	_, err := io.Copy(file, body)
+
+	if err == nil {
+		err = file.Chmod(mode)
+	}
+
+	if err == nil {
+		err = file.Sync()
+	}
+
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
@@ -0,0 +1,50 @@
+package atomicfile
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var atomicTestData = []byte("hello atomic")
+
+func TestWrite(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	t.Run("write file and create directories", func(t *testing.T) {
+		loc := filepath.Join(t.TempDir(), "a", "b", "test.txt")
+		assert.NoError(Write(ctx, loc, bytes.NewReader(atomicTestData), FileMode, DirMode))
+
+		data, err := os.ReadFile(loc)
+		assert.NoError(err)
+		assert.Equal(atomicTestData, data)
+
+		entries, err := os.ReadDir(filepath.Dir(loc))
+		assert.NoError(err)
+		assert.Len(entries, 1)
+	})
+
+	t.Run("keep previous file when canceled", func(t *testing.T) {
+		dir := t.TempDir()
+		loc := filepath.Join(dir, "test.txt")
+		assert.NoError(os.WriteFile(loc, []byte("previous"), FileMode))
+
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		assert.ErrorIs(Write(cctx, loc, bytes.NewReader(atomicTestData), FileMode, DirMode), context.Canceled)
+
+		data, err := os.ReadFile(loc)
+		assert.NoError(err)
+		assert.Equal([]byte("previous"), data)
+
+		entries, err := os.ReadDir(dir)
+		assert.NoError(err)
+		assert.Len(entries, 1)
+	})
+}
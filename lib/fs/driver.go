@@ -0,0 +1,55 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"strconv"
+
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+)
+
+func init() {
+	storage.Register("file", storage.DriverFunc(open))
+}
+
+// open opens the storage for "file:///var/data" URL, relative volumes can be opened with "file:data".
+// Supported query parameters are "file_mode" and "dir_mode" as octal permissions ("file:data?file_mode=0600").
+func open(_ context.Context, u *url.URL) (storage.Storage, string, error) {
+	if len(u.Host) > 0 && u.Host != "localhost" {
+		return nil, "", fmt.Errorf("unsupported file url host '%s'", u.Host)
+	}
+
+	vol := u.Path
+
+	if len(u.Opaque) > 0 {
+		vol = u.Opaque
+	}
+
+	if len(vol) == 0 {
+		return nil, "", ErrEmptyPath
+	}
+
+	query := u.Query()
+	options := []Option{}
+
+	for name, option := range map[string]func(fs.FileMode) Option{
+		"file_mode": WithFileMode,
+		"dir_mode":  WithDirMode,
+	} {
+		if !query.Has(name) {
+			continue
+		}
+
+		mode, err := strconv.ParseUint(query.Get(name), 8, 32)
+
+		if err != nil {
+			return nil, "", err
+		}
+
+		options = append(options, option(fs.FileMode(mode)))
+	}
+
+	return NewStorage(vol, options...), "", nil
+}
@@ -0,0 +1,108 @@
+package fs
+
+import (
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// maxDigests number of files that keep their digest in the cache
+const maxDigests = 10000
+
+// sniffLen number of bytes that are used to detect the content type
+const sniffLen = 512
+
+// digest properties of the file that are computed from the content
+type digest struct {
+	modTime     time.Time
+	size        int64
+	eTag        string
+	contentType string
+}
+
+// digestCache keeps digests of the files by inode, the digest is valid while modification time and size stay the same
+type digestCache struct {
+	mu      sync.Mutex
+	digests map[fileID]*digest
+}
+
+// newDigestCache create empty cache
+func newDigestCache() *digestCache {
+	return &digestCache{
+		digests: map[fileID]*digest{},
+	}
+}
+
+// digest get digest of the file, the file is read only when the cache has no valid digest,
+// nil cache and platforms without inodes read the file every time
+func (c *digestCache) digest(loc string, info os.FileInfo) (*digest, error) {
+	id, ok := getFileID(info)
+
+	if c == nil || !ok {
+		return readDigest(loc, info)
+	}
+
+	c.mu.Lock()
+	dgst, ok := c.digests[id]
+	c.mu.Unlock()
+
+	if ok && dgst.modTime.Equal(info.ModTime()) && dgst.size == info.Size() {
+		return dgst, nil
+	}
+
+	dgst, err := readDigest(loc, info)
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.digests[id]; !ok && len(c.digests) >= maxDigests {
+		for old := range c.digests {
+			delete(c.digests, old)
+			break
+		}
+	}
+
+	c.digests[id] = dgst
+
+	return dgst, nil
+}
+
+// readDigest compute md5 ETag of the file and sniff the content type from the first bytes
+func readDigest(loc string, info os.FileInfo) (*digest, error) {
+	file, err := os.Open(loc)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, head)
+
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+
+	hash := md5.New()
+	_, _ = hash.Write(head[:n])
+
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, err
+	}
+
+	return &digest{
+		modTime:     info.ModTime(),
+		size:        info.Size(),
+		eTag:        fmt.Sprintf("\"%x\"", hash.Sum(nil)),
+		contentType: http.DetectContentType(head[:n]),
+	}, nil
+}
@@ -0,0 +1,25 @@
+//go:build !windows && !plan9
+
+package fs
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileID device and inode of the file
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// getFileID get device and inode of the file, false if the file information has no inode
+func getFileID(info os.FileInfo) (fileID, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+
+	if !ok {
+		return fileID{}, false
+	}
+
+	return fileID{uint64(stat.Dev), uint64(stat.Ino)}, true
+}
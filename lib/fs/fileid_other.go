@@ -0,0 +1,16 @@
+//go:build windows || plan9
+
+package fs
+
+import "os"
+
+// fileID device and inode of the file
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// getFileID platform has no inodes, digests are not cached
+func getFileID(info os.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
@@ -0,0 +1,179 @@
+package fs
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+)
+
+// frame directory that is being listed
+type frame struct {
+	path    string
+	entries []os.DirEntry
+	idx     int
+}
+
+// iterator lists directories one by one in lexical order,
+// so only the directories on the current branch are kept in memory
+type iterator struct {
+	ctx       context.Context
+	store     Storage
+	token     string
+	recursive bool
+	stack     []*frame
+	entry     *storage.Entry
+	err       error
+}
+
+// Next advance to the next entry
+func (it *iterator) Next() bool {
+	it.entry = nil
+
+	for it.err == nil && len(it.stack) > 0 {
+		if it.err = it.ctx.Err(); it.err != nil {
+			break
+		}
+
+		top := it.stack[len(it.stack)-1]
+
+		if top.idx >= len(top.entries) {
+			it.stack = it.stack[:len(it.stack)-1]
+			continue
+		}
+
+		de := top.entries[top.idx]
+		top.idx++
+		path := joinPath(top.path, de.Name())
+
+		if path == metaDir {
+			continue
+		}
+
+		if !de.IsDir() && isTemp(de.Name()) {
+			continue
+		}
+
+		if de.IsDir() && it.recursive {
+			if len(it.token) == 0 || comparePaths(path, it.token) > 0 || strings.HasPrefix(it.token, path+"/") {
+				it.push(path)
+			}
+
+			continue
+		}
+
+		if len(it.token) > 0 && comparePaths(path, it.token) <= 0 {
+			continue
+		}
+
+		info, err := de.Info()
+
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			it.err = err
+			break
+		}
+
+		it.entry = &storage.Entry{
+			Path:         path,
+			LastModified: info.ModTime(),
+			IsDir:        info.IsDir(),
+		}
+
+		if !info.IsDir() {
+			it.entry.Size = info.Size()
+			it.entry.ContentEncoding = it.store.contentEncoding(path)
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// Entry get current entry
+func (it *iterator) Entry() *storage.Entry {
+	return it.entry
+}
+
+// Err get the error that stopped the iteration
+func (it *iterator) Err() error {
+	return it.err
+}
+
+// Token get continuation token for the current entry
+func (it *iterator) Token() string {
+	if it.entry == nil {
+		return ""
+	}
+
+	return it.entry.Path
+}
+
+func (it *iterator) push(path string) {
+	entries, err := os.ReadDir(it.store.vol + path)
+
+	if err != nil && !os.IsNotExist(err) {
+		it.err = err
+		return
+	}
+
+	it.stack = append(it.stack, &frame{
+		path:    path,
+		entries: entries,
+	})
+}
+
+// ListIter paginated listing of the path, supports storage.WithDelimiter and storage.WithToken options,
+// any non empty delimiter lists the direct children of the directory
+func (s Storage) ListIter(ctx context.Context, path string, options ...map[string]interface{}) storage.Iterator {
+	it := &iterator{
+		ctx:   ctx,
+		store: s,
+	}
+
+	opts, err := storage.ParseOptions(options...)
+
+	if err != nil {
+		it.err = err
+		return it
+	}
+
+	it.recursive = len(opts.Delimiter) == 0
+	it.token = opts.Token
+
+	if _, err := s.fullPath(path); err != nil {
+		it.err = err
+		return it
+	}
+
+	it.push(strings.Trim(path, "/"))
+
+	return it
+}
+
+func joinPath(dir string, name string) string {
+	if len(dir) == 0 {
+		return name
+	}
+
+	return dir + "/" + name
+}
+
+// comparePaths compares paths by their elements the way directory listing is ordered
+func comparePaths(a string, b string) int {
+	as := strings.Split(a, "/")
+	bs := strings.Split(b, "/")
+
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if c := strings.Compare(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+
+	return len(as) - len(bs)
+}
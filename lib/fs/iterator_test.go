@@ -0,0 +1,52 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComparePaths(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Zero(comparePaths("a/b", "a/b"))
+	assert.Negative(comparePaths("a/b", "a.txt"))
+	assert.Negative(comparePaths("a", "a/b"))
+	assert.Positive(comparePaths("b", "a/b/c"))
+}
+
+func TestListIter(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	store := NewStorage(t.TempDir())
+
+	for _, path := range []string{"a.txt", "a/b.txt", "a/c/d.txt", "b.txt"} {
+		assert.NoError(store.Put(path, bytes.NewReader(storageTestData)))
+	}
+
+	iter := store.ListIter(ctx, "/")
+	paths := []string{}
+	tokens := []string{}
+
+	for iter.Next() {
+		paths = append(paths, iter.Entry().Path)
+		tokens = append(tokens, iter.Token())
+	}
+
+	assert.NoError(iter.Err())
+	assert.Equal([]string{"a/b.txt", "a/c/d.txt", "a.txt", "b.txt"}, paths)
+
+	for i, token := range tokens {
+		iter = store.ListIter(ctx, "/", map[string]interface{}{"token": token})
+		rest := []string{}
+
+		for iter.Next() {
+			rest = append(rest, iter.Entry().Path)
+		}
+
+		assert.NoError(iter.Err())
+		assert.Equal(paths[i+1:], rest)
+	}
+}
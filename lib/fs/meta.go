@@ -0,0 +1,79 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+)
+
+// metaDir directory inside of the volume that keeps the attributes set by PutWithOptions,
+// it's hidden from List, Walk and ListIter
+const metaDir = ".meta"
+
+// metaPath get location of the sidecar file for the object
+func (s Storage) metaPath(path string) string {
+	return filepath.Join(s.vol, metaDir, strings.TrimPrefix(path, "/")+".json")
+}
+
+// readMeta get object attributes, returns nil for objects without the sidecar file
+func (s Storage) readMeta(path string) (*storage.PutOptions, error) {
+	data, err := os.ReadFile(s.metaPath(path))
+
+	if err != nil && os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	options := new(storage.PutOptions)
+
+	if err := json.Unmarshal(data, options); err != nil {
+		return nil, err
+	}
+
+	return options, nil
+}
+
+// contentEncoding get encoding of the object from the sidecar file, empty when the object has none
+func (s Storage) contentEncoding(path string) string {
+	options, err := s.readMeta(path)
+
+	if err != nil || options == nil {
+		return ""
+	}
+
+	return options.ContentEncoding
+}
+
+// writeMeta save object attributes, nil options remove the sidecar file
+func (s Storage) writeMeta(path string, options *storage.PutOptions) error {
+	loc := s.metaPath(path)
+
+	if options == nil {
+		if err := os.Remove(loc); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	data, err := json.Marshal(options)
+
+	if err != nil {
+		return err
+	}
+
+	return s.writeFile(context.Background(), loc, bytes.NewReader(data), s.fileMode)
+}
+
+// isMeta check if location is the attributes directory
+func (s Storage) isMeta(loc string) bool {
+	return filepath.Clean(loc) == filepath.Clean(s.vol+metaDir)
+}
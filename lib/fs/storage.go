@@ -6,34 +6,77 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"mime"
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/karrick/godirwalk"
+	"github.com/protsack-stephan/dev-toolkit/internal/atomicfile"
 	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
 )
 
 // ErrEmptyPath method call with empty file path
 var ErrEmptyPath = errors.New("empty file path")
 
+// section part of the file that closes the file when done
+type section struct {
+	*io.SectionReader
+	file *os.File
+}
+
+// Close closes the file
+func (s *section) Close() error {
+	return s.file.Close()
+}
+
+// Option changes storage settings
+type Option func(s *Storage)
+
+// WithFileMode set permissions of the files created by the storage (0644 by default)
+func WithFileMode(mode fs.FileMode) Option {
+	return func(s *Storage) {
+		s.fileMode = mode
+	}
+}
+
+// WithDirMode set permissions of the directories created by the storage (0755 by default)
+func WithDirMode(mode fs.FileMode) Option {
+	return func(s *Storage) {
+		s.dirMode = mode
+	}
+}
+
 // NewStorage create new storage instance
-func NewStorage(vol string) *Storage {
+func NewStorage(vol string, options ...Option) *Storage {
 	loc := vol
 
 	if vol[len(vol)-1:] != "/" {
 		loc = fmt.Sprintf("%s/", vol)
 	}
 
-	return &Storage{
-		loc,
+	s := &Storage{
+		vol:      loc,
+		fileMode: atomicfile.FileMode,
+		dirMode:  atomicfile.DirMode,
+		digests:  newDigestCache(),
 	}
+
+	for _, opt := range options {
+		opt(s)
+	}
+
+	return s
 }
 
 // Storage file system manipulations manager
 type Storage struct {
-	vol string
+	vol      string
+	fileMode fs.FileMode
+	dirMode  fs.FileMode
+	digests  *digestCache
 }
 
 // List reads the path content
@@ -44,6 +87,12 @@ func (s Storage) List(path string, options ...map[string]interface{}) ([]string,
 		return []string{}, err
 	}
 
+	opts, err := storage.ParseOptions(options...)
+
+	if err != nil {
+		return []string{}, err
+	}
+
 	d, err := os.Open(dir)
 
 	if err != nil {
@@ -52,36 +101,82 @@ func (s Storage) List(path string, options ...map[string]interface{}) ([]string,
 
 	defer d.Close()
 
-	return d.Readdirnames(-1)
+	entries, err := d.ReadDir(-1)
+
+	if err != nil {
+		return []string{}, err
+	}
+
+	dirs := len(opts.Delimiter) > 0
+	names := []string{}
+
+	for _, entry := range entries {
+		if entry.IsDir() && s.isMeta(filepath.Join(dir, entry.Name())) {
+			continue
+		}
+
+		if !entry.IsDir() && isTemp(entry.Name()) {
+			continue
+		}
+
+		if entry.IsDir() || !dirs {
+			names = append(names, entry.Name())
+		}
+	}
+
+	return names, nil
 }
 
 // ListWithContext reads the path content
-func (s Storage) ListWithContext(_ context.Context, path string, options ...map[string]interface{}) ([]string, error) {
+func (s Storage) ListWithContext(ctx context.Context, path string, options ...map[string]interface{}) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return []string{}, err
+	}
+
 	return s.List(path, options...)
 }
 
 // Walk recursively look for files in directory
 func (s Storage) Walk(path string, callback func(path string)) error {
+	return s.WalkWithContext(context.Background(), path, callback)
+}
+
+// WalkWithContext recursively look for files in directory
+func (s Storage) WalkWithContext(ctx context.Context, path string, callback func(path string)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	loc, err := s.fullPath(path)
 
 	if err != nil {
 		return err
 	}
 
-	slice := len(s.vol)
-
-	if s.vol[:2] == "./" {
-		slice -= 2
+	if _, err := os.Stat(loc); err != nil && os.IsNotExist(err) {
+		return nil
 	}
 
 	return godirwalk.Walk(loc, &godirwalk.Options{
 		Unsorted: true,
 		ErrorCallback: func(osPathname string, err error) godirwalk.ErrorAction {
+			if ctx.Err() != nil {
+				return godirwalk.Halt
+			}
+
 			return godirwalk.SkipNode
 		},
 		Callback: func(path string, de *godirwalk.Dirent) error {
-			if !de.IsDir() {
-				callback(path[slice:])
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if de.IsDir() && s.isMeta(path) {
+				return godirwalk.SkipThis
+			}
+
+			if !de.IsDir() && !isTemp(de.Name()) {
+				callback(s.relPath(path))
 			}
 
 			return nil
@@ -89,35 +184,172 @@ func (s Storage) Walk(path string, callback func(path string)) error {
 	})
 }
 
-// WalkWithContext recursively look for files in directory
-func (s Storage) WalkWithContext(_ context.Context, path string, callback func(path string)) error {
-	return s.Walk(path, callback)
+// WalkFunc recursively look for files and directories in sorted order, callback gets the entry details.
+// Return an error from the callback to stop the walk or storage.SkipDir to skip the directory.
+func (s Storage) WalkFunc(ctx context.Context, path string, callback func(entry *storage.Entry) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	loc, err := s.fullPath(path)
+
+	if err != nil {
+		return err
+	}
+
+	root := filepath.Clean(loc)
+
+	if _, err := os.Stat(root); err != nil && os.IsNotExist(err) {
+		return nil
+	}
+
+	return godirwalk.Walk(root, &godirwalk.Options{
+		Callback: func(path string, de *godirwalk.Dirent) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if path == root {
+				return nil
+			}
+
+			if de.IsDir() && s.isMeta(path) {
+				return godirwalk.SkipThis
+			}
+
+			if !de.IsDir() && isTemp(de.Name()) {
+				return nil
+			}
+
+			info, err := os.Lstat(path)
+
+			if err != nil {
+				return err
+			}
+
+			entry := &storage.Entry{
+				Path:         s.relPath(path),
+				LastModified: info.ModTime(),
+				IsDir:        info.IsDir(),
+			}
+
+			if !entry.IsDir {
+				entry.Size = info.Size()
+				entry.ContentEncoding = s.contentEncoding(entry.Path)
+			}
+
+			return callback(entry)
+		},
+	})
 }
 
-// Copy copies a file.
+// Copy copies a file, storage.WithFileMode sets permissions of the copy (storage file mode by default).
+// The copy is streamed into a temporary file that replaces the destination once complete.
+// 'src' and 'dst' are paths inside the volume.
 func (s Storage) Copy(src string, dst string, options ...map[string]interface{}) error {
-	mode := 0644
+	return s.CopyWithContext(context.Background(), src, dst, options...)
+}
 
-	for _, opt := range options {
-		if v, ok := opt["mode"]; ok {
-			if m, ok := v.(int); ok {
-				mode = m
-			}
-		}
+// CopyWithContext copies a file.
+// 'src' and 'dst' are paths inside the volume.
+func (s Storage) CopyWithContext(ctx context.Context, src string, dst string, options ...map[string]interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	opts, err := storage.ParseOptions(options...)
+
+	if err != nil {
+		return err
+	}
+
+	if opts.FileMode == 0 {
+		opts.FileMode = s.fileMode
+	}
+
+	srcLoc, err := s.fullPath(src)
+
+	if err != nil {
+		return err
+	}
+
+	dstLoc, err := s.fullPath(dst)
+
+	if err != nil {
+		return err
+	}
+
+	input, err := os.Open(srcLoc)
+
+	if err != nil {
+		return err
+	}
+
+	defer input.Close()
+
+	if err := s.writeFile(ctx, dstLoc, input, opts.FileMode); err != nil {
+		return err
 	}
 
-	input, err := os.ReadFile(src)
+	meta, err := s.readMeta(strings.TrimPrefix(srcLoc, s.vol))
 
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(dst, input, fs.FileMode(mode))
+	return s.writeMeta(strings.TrimPrefix(dstLoc, s.vol), meta)
 }
 
-// CopyWithContext copies a file.
-func (s Storage) CopyWithContext(_ context.Context, src string, dst string, options ...map[string]interface{}) error {
-	return s.Copy(src, dst, options...)
+// Move renames the file inside the volume, files on different devices are copied and removed.
+// Attributes set by PutWithOptions are moved together with the file.
+func (s Storage) Move(ctx context.Context, src string, dst string) error {
+	if err := ctx.Err(); err != nil {
+		return &storage.MoveError{Src: src, Dst: dst, Err: err}
+	}
+
+	srcLoc, err := s.fullPath(src)
+
+	if err != nil {
+		return &storage.MoveError{Src: src, Dst: dst, Err: err}
+	}
+
+	dstLoc, err := s.fullPath(dst)
+
+	if err != nil {
+		return &storage.MoveError{Src: src, Dst: dst, Err: err}
+	}
+
+	if _, err := os.Stat(srcLoc); err != nil {
+		return &storage.MoveError{Src: src, Dst: dst, Err: err}
+	}
+
+	if srcLoc == dstLoc {
+		return nil
+	}
+
+	meta, err := s.readMeta(src)
+
+	if err != nil {
+		return &storage.MoveError{Src: src, Dst: dst, Err: err}
+	}
+
+	if err := s.mkdir(dstLoc); err != nil {
+		return &storage.MoveError{Src: src, Dst: dst, Err: err}
+	}
+
+	if copied, err := s.rename(ctx, srcLoc, dstLoc); err != nil {
+		return &storage.MoveError{Src: src, Dst: dst, Copied: copied, Err: err}
+	}
+
+	if err := s.writeMeta(dst, meta); err != nil {
+		return &storage.MoveError{Src: src, Dst: dst, Copied: true, Err: err}
+	}
+
+	if err := s.writeMeta(src, nil); err != nil {
+		return &storage.MoveError{Src: src, Dst: dst, Copied: true, Err: err}
+	}
+
+	return nil
 }
 
 // Create create new file or open existing one and truncate it
@@ -128,18 +360,24 @@ func (s Storage) Create(path string) (io.ReadWriteCloser, error) {
 		return nil, err
 	}
 
-	dir, _ := filepath.Split(loc)
-	_, err = os.Stat(dir)
+	if err := s.mkdir(loc); err != nil {
+		return nil, err
+	}
 
-	if err != nil && os.IsNotExist(err) {
-		err = os.MkdirAll(dir, 0766)
+	if err := s.writeMeta(path, nil); err != nil {
+		return nil, err
 	}
 
-	if err != nil {
+	return os.OpenFile(loc, os.O_RDWR|os.O_CREATE|os.O_TRUNC, s.fileMode)
+}
+
+// CreateWithContext create new file or open existing one and truncate it
+func (s Storage) CreateWithContext(ctx context.Context, path string) (io.ReadWriteCloser, error) {
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 
-	return os.OpenFile(loc, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0766)
+	return s.Create(path)
 }
 
 // Get get object from storage
@@ -154,41 +392,110 @@ func (s Storage) Get(path string) (io.ReadCloser, error) {
 }
 
 // GetWithContext get object from storage
-func (s Storage) GetWithContext(_ context.Context, path string) (io.ReadCloser, error) {
+func (s Storage) GetWithContext(ctx context.Context, path string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	return s.Get(path)
 }
 
-// Put object into storage
+// GetRange get part of the object from storage
+func (s Storage) GetRange(ctx context.Context, path string, offset int64, length int64) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if offset < 0 {
+		return nil, storage.ErrInvalidRange
+	}
+
+	loc, err := s.fullPath(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(loc)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if length >= 0 {
+		return &section{io.NewSectionReader(file, offset, length), file}, nil
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// Put object into storage, the body is streamed into a temporary file that replaces the object once complete
 func (s Storage) Put(path string, body io.Reader) error {
+	return s.PutWithContext(context.Background(), path, body)
+}
+
+// PutWithContext object into storage, canceling the context keeps the previous version of the object
+func (s Storage) PutWithContext(ctx context.Context, path string, body io.Reader) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	loc, err := s.fullPath(path)
 
 	if err != nil {
 		return err
 	}
 
-	dir, _ := filepath.Split(loc)
-	_, err = os.Stat(dir)
+	if err := s.writeFile(ctx, loc, body, s.fileMode); err != nil {
+		return err
+	}
 
-	if err != nil && os.IsNotExist(err) {
-		err = os.MkdirAll(dir, 0766)
+	return s.writeMeta(path, nil)
+}
+
+// PutWithOptions object into storage, attributes are kept in the sidecar file under the volume
+func (s Storage) PutWithOptions(ctx context.Context, path string, body io.Reader, options *storage.PutOptions) error {
+	if err := s.PutWithContext(ctx, path, body); err != nil {
+		return err
+	}
+
+	return s.writeMeta(path, options)
+}
+
+// SetMetadata replace user metadata of the object in the sidecar file
+func (s Storage) SetMetadata(ctx context.Context, path string, metadata map[string]string) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
+	loc, err := s.fullPath(path)
+
 	if err != nil {
 		return err
 	}
 
-	buff, err := io.ReadAll(body)
+	if _, err := os.Stat(loc); err != nil {
+		return err
+	}
+
+	meta, err := s.readMeta(path)
 
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(loc, buff, 0766)
-}
+	if meta == nil {
+		meta = new(storage.PutOptions)
+	}
+
+	meta.Metadata = metadata
 
-// PutWithContext object into storage
-func (s Storage) PutWithContext(_ context.Context, path string, body io.Reader) error {
-	return s.Put(path, body)
+	return s.writeMeta(path, meta)
 }
 
 // Link generate expiration link for storage
@@ -196,7 +503,7 @@ func (s Storage) Link(path string, expire time.Duration) (string, error) {
 	return s.fullPath(path)
 }
 
-// Delete remove object from storage
+// Delete remove object from storage, does nothing if object does not exist
 func (s *Storage) Delete(path string) error {
 	loc, err := s.fullPath(path)
 
@@ -204,15 +511,88 @@ func (s *Storage) Delete(path string) error {
 		return err
 	}
 
-	return os.Remove(loc)
+	if err := os.Remove(loc); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return s.writeMeta(path, nil)
 }
 
-// DeleteWithContext remove object from storage
-func (s *Storage) DeleteWithContext(_ context.Context, path string) error {
+// DeleteWithContext remove object from storage, does nothing if object does not exist
+func (s *Storage) DeleteWithContext(ctx context.Context, path string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	return s.Delete(path)
 }
 
-// Stat get file information
+// DeleteMany remove objects from storage, objects that do not exist are skipped
+func (s *Storage) DeleteMany(ctx context.Context, paths []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	errs := map[string]error{}
+
+	for _, path := range paths {
+		if err := s.DeleteWithContext(ctx, path); err != nil {
+			errs[path] = err
+		}
+	}
+
+	return storage.NewDeleteError(errs)
+}
+
+// DeleteAll remove the file or the directory at the path with everything inside of it, same as os.RemoveAll.
+// Files are removed one by one, so the report has every file that could not be removed.
+func (s *Storage) DeleteAll(ctx context.Context, prefix string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	dir := strings.Trim(prefix, "/")
+
+	for _, elem := range strings.Split(dir, "/") {
+		if elem == ".." {
+			return &fs.PathError{Op: "delete", Path: prefix, Err: storage.ErrInvalidPath}
+		}
+	}
+
+	loc := s.vol + dir
+	info, err := os.Stat(loc)
+
+	if err != nil && os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return s.DeleteMany(ctx, []string{dir})
+	}
+
+	paths := []string{}
+	err = s.WalkWithContext(ctx, "/"+dir, func(path string) {
+		paths = append(paths, strings.TrimPrefix(path, "/"))
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := s.DeleteMany(ctx, paths); err != nil {
+		return err
+	}
+
+	return s.removeDir(dir)
+}
+
+// Stat get file information, the ETag is md5 of the content (cached while the file does not change)
+// and the content type is detected from the extension or the content unless it was set with PutOptions.
+// Directories are reported like prefixes of the object storage: without size, ETag and content type.
 func (s Storage) Stat(path string) (storage.FileInfo, error) {
 	loc, err := s.fullPath(path)
 
@@ -226,12 +606,59 @@ func (s Storage) Stat(path string) (storage.FileInfo, error) {
 		return nil, err
 	}
 
+	if (info.IsDir() && s.isMeta(loc)) || (!info.IsDir() && isTemp(info.Name())) {
+		return nil, &fs.PathError{Op: "stat", Path: path, Err: fs.ErrNotExist}
+	}
+
 	inf := &FileInfo{
-		size:         info.Size(),
+		name:         info.Name(),
 		lastModified: info.ModTime(),
+		mode:         info.Mode(),
+		isDir:        info.IsDir(),
+		metadata:     map[string]*string{},
+	}
+
+	if info.IsDir() {
+		return inf, nil
+	}
+
+	meta, err := s.readMeta(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if meta != nil {
+		inf.cacheControl = meta.CacheControl
+		inf.contentDisposition = meta.ContentDisposition
+		inf.contentEncoding = meta.ContentEncoding
+		inf.contentLanguage = meta.ContentLanguage
+		inf.contentType = meta.ContentType
+
+		for name, value := range meta.Metadata {
+			value := value
+			inf.metadata[name] = &value
+		}
 	}
 
-	return inf, err
+	dgst, err := s.digests.digest(loc, info)
+
+	if err != nil {
+		return nil, err
+	}
+
+	inf.size = info.Size()
+	inf.eTag = dgst.eTag
+
+	if len(inf.contentType) == 0 {
+		inf.contentType = mime.TypeByExtension(filepath.Ext(loc))
+	}
+
+	if len(inf.contentType) == 0 {
+		inf.contentType = dgst.contentType
+	}
+
+	return inf, nil
 }
 
 func (s Storage) fullPath(path string) (string, error) {
@@ -243,3 +670,87 @@ func (s Storage) fullPath(path string) (string, error) {
 
 	return fmt.Sprintf("%s%s", s.vol, strings.TrimPrefix(path, "/")), err
 }
+
+func (s Storage) relPath(path string) string {
+	slice := len(s.vol)
+
+	if strings.HasPrefix(s.vol, "./") {
+		slice -= 2
+	}
+
+	return path[slice:]
+}
+
+// removeDir remove the directory that is left after the files are deleted, the root keeps the volume directory
+func (s Storage) removeDir(dir string) error {
+	if len(dir) > 0 {
+		if err := os.RemoveAll(s.vol + dir); err != nil {
+			return err
+		}
+
+		return os.RemoveAll(filepath.Join(s.vol, metaDir, dir))
+	}
+
+	entries, err := os.ReadDir(s.vol)
+
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(s.vol, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rename move the file, when the locations are on different devices the file is copied and removed,
+// copied reports that the copy is done but the source is left in place
+func (s Storage) rename(ctx context.Context, srcLoc string, dstLoc string) (bool, error) {
+	err := renameFile(srcLoc, dstLoc)
+
+	if err == nil {
+		return false, atomicfile.SyncDir(filepath.Dir(dstLoc))
+	}
+
+	if !errors.Is(err, syscall.EXDEV) {
+		return false, err
+	}
+
+	file, err := os.Open(srcLoc)
+
+	if err != nil {
+		return false, err
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+
+	if err != nil {
+		return false, err
+	}
+
+	if err := s.writeFile(ctx, dstLoc, file, info.Mode().Perm()); err != nil {
+		return false, err
+	}
+
+	if err := os.Remove(srcLoc); err != nil {
+		return true, err
+	}
+
+	return false, nil
+}
+
+func (s Storage) mkdir(loc string) error {
+	dir, _ := filepath.Split(loc)
+	_, err := os.Stat(dir)
+
+	if err != nil && os.IsNotExist(err) {
+		err = os.MkdirAll(dir, s.dirMode)
+	}
+
+	return err
+}
@@ -3,15 +3,20 @@ package fs
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
+	"path/filepath"
+	"syscall"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage/storagetest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -154,7 +159,7 @@ func TestStorage(t *testing.T) {
 		options := []map[string]interface{}{
 			{"mode": 0711},
 		}
-		assert.NoError(store.Copy(fmt.Sprintf("%s/%s", storageTestVol, storageTestPath), fmt.Sprintf("%s/%s", storageTestVol, copyDestPath), options...))
+		assert.NoError(store.Copy(storageTestPath, copyDestPath, options...))
 		assert.NoError(compareFileContent(store, copyDestPath, storageTestData))
 		assert.NoError(compareFileMode(copyDestPath, options[0]["mode"].(int)))
 		assert.NoError(os.Remove(fmt.Sprintf("%s/%s", storageTestVol, storageTestPath)))
@@ -163,10 +168,387 @@ func TestStorage(t *testing.T) {
 
 	t.Run("copy file with context", func(t *testing.T) {
 		assert.NoError(store.Put(storageTestPath, bytes.NewReader(storageTestData)))
-		assert.NoError(store.CopyWithContext(ctx, fmt.Sprintf("%s/%s", storageTestVol, storageTestPath), fmt.Sprintf("%s/%s", storageTestVol, copyDestPath)))
+		assert.NoError(store.CopyWithContext(ctx, storageTestPath, copyDestPath))
 		assert.NoError(compareFileContent(store, copyDestPath, storageTestData))
 		assert.NoError(compareFileMode(copyDestPath, 0644))
 		assert.NoError(os.Remove(fmt.Sprintf("%s/%s", storageTestVol, storageTestPath)))
 		assert.NoError(os.Remove(fmt.Sprintf("%s/%s", storageTestVol, copyDestPath)))
 	})
+
+	t.Run("copy keys that start with the volume", func(t *testing.T) {
+		vol := t.TempDir()
+		store := NewStorage(vol)
+		key := vol + "/test.txt"
+
+		assert.NoError(store.Put(key, bytes.NewReader(storageTestData)))
+		assert.NoError(store.Copy(key, copyDestPath))
+		assert.NoError(compareFileContent(store, copyDestPath, storageTestData))
+
+		_, err := os.Stat(filepath.Join(vol, "test.txt"))
+		assert.True(os.IsNotExist(err))
+	})
+}
+
+func TestAtomicWrite(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	vol := t.TempDir()
+	store := NewStorage(vol)
+
+	t.Run("failed put keeps previous object", func(t *testing.T) {
+		assert.NoError(store.Put("atomic/test.txt", bytes.NewReader(storageTestData)))
+
+		body := io.MultiReader(bytes.NewReader([]byte("partial")), iotest.ErrReader(io.ErrUnexpectedEOF))
+		assert.ErrorIs(store.Put("atomic/test.txt", body), io.ErrUnexpectedEOF)
+
+		data, err := os.ReadFile(filepath.Join(vol, "atomic", "test.txt"))
+		assert.NoError(err)
+		assert.Equal(storageTestData, data)
+
+		entries, err := os.ReadDir(filepath.Join(vol, "atomic"))
+		assert.NoError(err)
+		assert.Len(entries, 1)
+	})
+
+	t.Run("failed put does not create object", func(t *testing.T) {
+		assert.Error(store.Put("atomic/new.txt", iotest.ErrReader(io.ErrUnexpectedEOF)))
+
+		_, err := os.Stat(filepath.Join(vol, "atomic", "new.txt"))
+		assert.True(os.IsNotExist(err))
+	})
+
+	t.Run("canceled put keeps previous object", func(t *testing.T) {
+		cctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		body := io.MultiReader(&cancelReader{cancel}, bytes.NewReader([]byte("more")))
+
+		assert.ErrorIs(store.PutWithContext(cctx, "atomic/test.txt", body), context.Canceled)
+
+		data, err := os.ReadFile(filepath.Join(vol, "atomic", "test.txt"))
+		assert.NoError(err)
+		assert.Equal(storageTestData, data)
+	})
+
+	t.Run("put large object", func(t *testing.T) {
+		size := int64(8 << 20)
+		assert.NoError(store.Put("atomic/large.bin", io.LimitReader(zeroReader{}, size)))
+
+		info, err := store.Stat("atomic/large.bin")
+		assert.NoError(err)
+		assert.Equal(size, info.Size())
+	})
+
+	t.Run("copy replaces destination", func(t *testing.T) {
+		assert.NoError(store.Put("atomic/dst.txt", bytes.NewReader([]byte("old"))))
+		assert.NoError(store.Copy("atomic/test.txt", "atomic/dst.txt"))
+
+		data, err := os.ReadFile(filepath.Join(vol, "atomic", "dst.txt"))
+		assert.NoError(err)
+		assert.Equal(storageTestData, data)
+	})
+
+	t.Run("temporary files are hidden", func(t *testing.T) {
+		assert.NoError(os.WriteFile(filepath.Join(vol, "atomic", tempPrefix+"test.txt-1"), []byte("partial"), 0644))
+
+		names, err := store.List("atomic")
+		assert.NoError(err)
+		assert.ElementsMatch([]string{"dst.txt", "large.bin", "test.txt"}, names)
+
+		paths := []string{}
+		assert.NoError(store.Walk("atomic", func(path string) {
+			paths = append(paths, path)
+		}))
+		assert.ElementsMatch([]string{"atomic/dst.txt", "atomic/large.bin", "atomic/test.txt"}, paths)
+
+		entries := []string{}
+		assert.NoError(store.WalkFunc(ctx, "atomic", func(entry *storage.Entry) error {
+			entries = append(entries, entry.Path)
+			return nil
+		}))
+		assert.ElementsMatch([]string{"atomic/dst.txt", "atomic/large.bin", "atomic/test.txt"}, entries)
+
+		it := store.ListIter(ctx, "atomic")
+
+		for it.Next() {
+			assert.False(isTemp(filepath.Base(it.Entry().Path)))
+		}
+
+		assert.NoError(it.Err())
+	})
+}
+
+func TestPermissions(t *testing.T) {
+	assert := assert.New(t)
+	vol := t.TempDir()
+
+	t.Run("default permissions", func(t *testing.T) {
+		store := NewStorage(vol)
+		assert.NoError(store.Put("default/test.txt", bytes.NewReader(storageTestData)))
+
+		info, err := os.Stat(filepath.Join(vol, "default", "test.txt"))
+		assert.NoError(err)
+		assert.Equal(fs.FileMode(0644), info.Mode().Perm())
+
+		info, err = os.Stat(filepath.Join(vol, "default"))
+		assert.NoError(err)
+		assert.Equal(fs.FileMode(0755), info.Mode().Perm())
+	})
+
+	t.Run("configured permissions", func(t *testing.T) {
+		store := NewStorage(vol, WithFileMode(0600), WithDirMode(0700))
+		assert.NoError(store.Put("private/test.txt", bytes.NewReader(storageTestData)))
+		assert.NoError(store.Copy("private/test.txt", "private/copy.txt"))
+
+		for _, name := range []string{"test.txt", "copy.txt"} {
+			info, err := os.Stat(filepath.Join(vol, "private", name))
+			assert.NoError(err)
+			assert.Equal(fs.FileMode(0600), info.Mode().Perm())
+		}
+
+		info, err := os.Stat(filepath.Join(vol, "private"))
+		assert.NoError(err)
+		assert.Equal(fs.FileMode(0700), info.Mode().Perm())
+	})
+}
+
+func TestStat(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	vol := t.TempDir()
+	store := NewStorage(vol)
+	eTag := fmt.Sprintf("\"%x\"", md5.Sum(storageTestData))
+
+	t.Run("stat file", func(t *testing.T) {
+		assert.NoError(store.Put("stat/test.txt", bytes.NewReader(storageTestData)))
+
+		info, err := store.Stat("stat/test.txt")
+		assert.NoError(err)
+		assert.Equal("test.txt", info.Name())
+		assert.Equal(int64(len(storageTestData)), info.Size())
+		assert.False(info.IsDir())
+		assert.Equal(eTag, info.ETag())
+		assert.Equal("text/plain; charset=utf-8", info.ContentType())
+		assert.Equal(fs.FileMode(0644), info.(*FileInfo).Mode().Perm())
+	})
+
+	t.Run("detect content type", func(t *testing.T) {
+		assert.NoError(store.Put("stat/page", bytes.NewReader([]byte("<html><body>hello</body></html>"))))
+		assert.NoError(store.Put("stat/data.json", bytes.NewReader([]byte("{}"))))
+		assert.NoError(store.PutWithOptions(ctx, "stat/typed.json", bytes.NewReader([]byte("{}")), &storage.PutOptions{ContentType: "text/plain"}))
+
+		for path, contentType := range map[string]string{
+			"stat/page":       "text/html; charset=utf-8",
+			"stat/data.json":  "application/json",
+			"stat/typed.json": "text/plain",
+		} {
+			info, err := store.Stat(path)
+			assert.NoError(err)
+			assert.Equal(contentType, info.ContentType(), path)
+		}
+	})
+
+	t.Run("cache etag while the file does not change", func(t *testing.T) {
+		loc := filepath.Join(vol, "stat", "cached.txt")
+		assert.NoError(store.Put("stat/cached.txt", bytes.NewReader(storageTestData)))
+
+		info, err := store.Stat("stat/cached.txt")
+		assert.NoError(err)
+		assert.Equal(eTag, info.ETag())
+
+		modTime := info.ModTime()
+		data := bytes.ToUpper(storageTestData)
+		assert.NoError(os.WriteFile(loc, data, 0644))
+		assert.NoError(os.Chtimes(loc, modTime, modTime))
+
+		info, err = store.Stat("stat/cached.txt")
+		assert.NoError(err)
+		assert.Equal(eTag, info.ETag())
+
+		assert.NoError(os.Chtimes(loc, modTime, modTime.Add(time.Second)))
+
+		info, err = store.Stat("stat/cached.txt")
+		assert.NoError(err)
+		assert.Equal(fmt.Sprintf("\"%x\"", md5.Sum(data)), info.ETag())
+	})
+
+	t.Run("stat directory", func(t *testing.T) {
+		info, err := store.Stat("stat")
+		assert.NoError(err)
+		assert.Equal("stat", info.Name())
+		assert.True(info.IsDir())
+		assert.Zero(info.Size())
+		assert.Empty(info.ETag())
+		assert.Empty(info.ContentType())
+		assert.True(info.(*FileInfo).Mode().IsDir())
+	})
+
+	t.Run("stat hidden files", func(t *testing.T) {
+		assert.NoError(os.WriteFile(filepath.Join(vol, "stat", tempPrefix+"test.txt-1"), storageTestData, 0644))
+
+		for _, path := range []string{metaDir, "stat/" + tempPrefix + "test.txt-1", "stat/missing.txt"} {
+			_, err := store.Stat(path)
+			assert.True(errors.Is(err, storage.ErrNotExist), path)
+		}
+	})
+}
+
+func TestMove(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	vol := t.TempDir()
+	store := NewStorage(vol)
+	options := &storage.PutOptions{ContentType: "text/plain"}
+
+	t.Run("rename file with attributes", func(t *testing.T) {
+		assert.NoError(store.PutWithOptions(ctx, "move/src.txt", bytes.NewReader(storageTestData), options))
+		assert.NoError(store.Move(ctx, "move/src.txt", "moved/dst.txt"))
+
+		info, err := store.Stat("moved/dst.txt")
+		assert.NoError(err)
+		assert.Equal("text/plain", info.ContentType())
+
+		_, err = os.Stat(filepath.Join(vol, "move", "src.txt"))
+		assert.True(os.IsNotExist(err))
+		_, err = os.Stat(store.metaPath("move/src.txt"))
+		assert.True(os.IsNotExist(err))
+	})
+
+	t.Run("copy file across devices", func(t *testing.T) {
+		renameFile = func(oldpath, newpath string) error {
+			return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+		}
+		defer func() { renameFile = os.Rename }()
+
+		assert.NoError(store.Put("move/src.txt", bytes.NewReader(storageTestData)))
+		assert.NoError(os.Chmod(filepath.Join(vol, "move", "src.txt"), 0600))
+		assert.NoError(store.Move(ctx, "move/src.txt", "moved/copy.txt"))
+
+		data, err := os.ReadFile(filepath.Join(vol, "moved", "copy.txt"))
+		assert.NoError(err)
+		assert.Equal(storageTestData, data)
+
+		info, err := os.Stat(filepath.Join(vol, "moved", "copy.txt"))
+		assert.NoError(err)
+		assert.Equal(fs.FileMode(0600), info.Mode().Perm())
+
+		_, err = os.Stat(filepath.Join(vol, "move", "src.txt"))
+		assert.True(os.IsNotExist(err))
+	})
+
+	t.Run("move missing file", func(t *testing.T) {
+		err := store.Move(ctx, "move/missing.txt", "moved/missing.txt")
+		assert.ErrorIs(err, storage.ErrNotExist)
+
+		merr := new(storage.MoveError)
+		assert.True(errors.As(err, &merr))
+		assert.False(merr.Copied)
+	})
+}
+
+func TestDeleteAll(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	vol := t.TempDir()
+	store := NewStorage(vol)
+	options := &storage.PutOptions{ContentType: "text/plain"}
+
+	t.Run("remove directory with attributes", func(t *testing.T) {
+		assert.NoError(store.PutWithOptions(ctx, "jobs/1/a.txt", bytes.NewReader(storageTestData), options))
+		assert.NoError(store.Put("jobs/1/b/c.txt", bytes.NewReader(storageTestData)))
+		assert.NoError(store.Put("jobs/2/a.txt", bytes.NewReader(storageTestData)))
+
+		assert.NoError(store.DeleteAll(ctx, "jobs/1"))
+
+		_, err := os.Stat(filepath.Join(vol, "jobs", "1"))
+		assert.True(os.IsNotExist(err))
+		_, err = os.Stat(filepath.Join(vol, metaDir, "jobs", "1"))
+		assert.True(os.IsNotExist(err))
+		_, err = os.Stat(filepath.Join(vol, "jobs", "2", "a.txt"))
+		assert.NoError(err)
+	})
+
+	t.Run("remove everything but the volume", func(t *testing.T) {
+		assert.NoError(store.DeleteAll(ctx, "/"))
+
+		entries, err := os.ReadDir(vol)
+		assert.NoError(err)
+		assert.Empty(entries)
+	})
+
+	t.Run("reject paths outside of the volume", func(t *testing.T) {
+		root := t.TempDir()
+		sibling := filepath.Join(root, "sibling")
+		assert.NoError(os.MkdirAll(sibling, 0755))
+		assert.NoError(os.WriteFile(filepath.Join(sibling, "a.txt"), storageTestData, 0644))
+		store := NewStorage(filepath.Join(root, "vol"))
+
+		for _, prefix := range []string{"../sibling", "../", "a/../../sibling"} {
+			err := store.DeleteAll(ctx, prefix)
+			assert.True(errors.Is(err, storage.ErrInvalidPath), "delete all '%s': %v", prefix, err)
+		}
+
+		_, err := os.Stat(filepath.Join(sibling, "a.txt"))
+		assert.NoError(err)
+	})
+}
+
+type cancelReader struct {
+	cancel context.CancelFunc
+}
+
+func (r *cancelReader) Read(p []byte) (int, error) {
+	r.cancel()
+	return copy(p, "partial"), io.EOF
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+
+	return len(p), nil
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return NewStorage(t.TempDir())
+	})
+}
+
+func TestOpen(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	vol := t.TempDir()
+
+	t.Run("open volume", func(t *testing.T) {
+		store, err := storage.Open(ctx, "file://"+vol)
+		assert.NoError(err)
+		assert.NoError(store.Put("open/test.txt", bytes.NewReader(storageTestData)))
+
+		_, err = os.Stat(filepath.Join(vol, "open", "test.txt"))
+		assert.NoError(err)
+	})
+
+	t.Run("open with invalid url", func(t *testing.T) {
+		_, err := storage.Open(ctx, "file://host/data")
+		assert.Error(err)
+
+		_, err = storage.Open(ctx, "file://")
+		assert.ErrorIs(err, ErrEmptyPath)
+	})
+
+	t.Run("open with permissions", func(t *testing.T) {
+		store, err := storage.Open(ctx, "file://"+vol+"?file_mode=0600&dir_mode=0700")
+		assert.NoError(err)
+		assert.NoError(store.Put("private/test.txt", bytes.NewReader(storageTestData)))
+
+		info, err := os.Stat(filepath.Join(vol, "private", "test.txt"))
+		assert.NoError(err)
+		assert.Equal(fs.FileMode(0600), info.Mode().Perm())
+
+		_, err = storage.Open(ctx, "file://"+vol+"?file_mode=rw")
+		assert.Error(err)
+	})
 }
@@ -0,0 +1,28 @@
+package fs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/protsack-stephan/dev-toolkit/internal/atomicfile"
+)
+
+// tempPrefix name prefix of the files that are being written,
+// they are hidden from List, Walk and ListIter until renamed into place
+const tempPrefix = atomicfile.TempPrefix
+
+// renameFile renames the file, replaced in tests to simulate moves across devices
+var renameFile = os.Rename
+
+// isTemp check if file name belongs to the file that is being written
+func isTemp(name string) bool {
+	return strings.HasPrefix(name, tempPrefix)
+}
+
+// writeFile write the file atomically, so the file at the location is either the old one or the complete new one
+func (s Storage) writeFile(ctx context.Context, loc string, body io.Reader, mode fs.FileMode) error {
+	return atomicfile.Write(ctx, loc, body, mode, s.dirMode)
+}
@@ -0,0 +1,38 @@
+package mem
+
+import (
+	"context"
+	"net/url"
+	"sync"
+
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+)
+
+var (
+	namedMu sync.Mutex
+	named   = map[string]*Storage{}
+)
+
+func init() {
+	storage.Register("mem", storage.DriverFunc(open))
+}
+
+// open opens the storage for "mem://name/prefix" URL, storages with the same name are shared
+// inside of the process, "mem://" without the name always creates new storage
+func open(_ context.Context, u *url.URL) (storage.Storage, string, error) {
+	if len(u.Host) == 0 {
+		return NewStorage(), u.Path, nil
+	}
+
+	namedMu.Lock()
+	defer namedMu.Unlock()
+
+	store, ok := named[u.Host]
+
+	if !ok {
+		store = NewStorage()
+		named[u.Host] = store
+	}
+
+	return store, u.Path, nil
+}
@@ -0,0 +1,74 @@
+package mem
+
+import (
+	"io"
+	"io/fs"
+	"time"
+)
+
+// file read and write handle for the object, shares the offset between reads and writes
+type file struct {
+	store  *Storage
+	key    string
+	offset int
+	closed bool
+}
+
+// Read reads object data from the current offset
+func (f *file) Read(p []byte) (int, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+
+	f.store.mu.RLock()
+	defer f.store.mu.RUnlock()
+
+	obj, ok := f.store.objects[f.key]
+
+	if !ok || f.offset >= len(obj.data) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, obj.data[f.offset:])
+	f.offset += n
+
+	return n, nil
+}
+
+// Write writes data to the object at the current offset
+func (f *file) Write(p []byte) (int, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+
+	f.store.mu.Lock()
+	defer f.store.mu.Unlock()
+
+	obj, ok := f.store.objects[f.key]
+
+	if !ok {
+		obj = &object{data: []byte{}}
+		f.store.objects[f.key] = obj
+	}
+
+	if end := f.offset + len(p); end > len(obj.data) {
+		obj.data = append(obj.data, make([]byte, end-len(obj.data))...)
+	}
+
+	n := copy(obj.data[f.offset:], p)
+	f.offset += n
+	obj.lastModified = time.Now()
+
+	return n, nil
+}
+
+// Close closes the handle, object stays in the storage
+func (f *file) Close() error {
+	if f.closed {
+		return fs.ErrClosed
+	}
+
+	f.closed = true
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package mem
+
+import "time"
+
+// FileInfo struct to get file information
+type FileInfo struct {
+	name               string
+	size               int64
+	lastModified       time.Time
+	isDir              bool
+	eTag               string
+	contentType        string
+	cacheControl       string
+	contentDisposition string
+	contentEncoding    string
+	contentLanguage    string
+	metadata           map[string]*string
+}
+
+// Name get base name of the file
+func (fi FileInfo) Name() string {
+	return fi.name
+}
+
+// Size get file size
+func (fi FileInfo) Size() int64 {
+	return fi.size
+}
+
+// ModTime get modification time
+func (fi FileInfo) ModTime() time.Time {
+	return fi.lastModified
+}
+
+// IsDir check if the path is a directory
+func (fi FileInfo) IsDir() bool {
+	return fi.isDir
+}
+
+// ETag get ETag
+func (fi FileInfo) ETag() string {
+	return fi.eTag
+}
+
+// ContentType get Content-Type
+func (fi FileInfo) ContentType() string {
+	return fi.contentType
+}
+
+// CacheControl get Cache-Control
+func (fi FileInfo) CacheControl() string {
+	return fi.cacheControl
+}
+
+// ContentDisposition get Content-Disposition
+func (fi FileInfo) ContentDisposition() string {
+	return fi.contentDisposition
+}
+
+// ContentEncoding get Content-Encoding
+func (fi FileInfo) ContentEncoding() string {
+	return fi.contentEncoding
+}
+
+// ContentLanguage get Content-Language
+func (fi FileInfo) ContentLanguage() string {
+	return fi.contentLanguage
+}
+
+// Metadata get Metadata
+func (fi FileInfo) Metadata() map[string]*string {
+	return fi.metadata
+}
+
+// Sys get the file information itself, it implements storage.ContentInfo
+func (fi FileInfo) Sys() interface{} {
+	return fi
+}
@@ -0,0 +1,130 @@
+package mem
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+)
+
+type item struct {
+	token string
+	entry *storage.Entry
+}
+
+// iterator goes through the snapshot of the storage taken on creation
+type iterator struct {
+	ctx   context.Context
+	items []*item
+	idx   int
+	err   error
+}
+
+// Next advance to the next entry
+func (it *iterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	if it.err = it.ctx.Err(); it.err != nil {
+		return false
+	}
+
+	if it.idx+1 >= len(it.items) {
+		return false
+	}
+
+	it.idx++
+
+	return true
+}
+
+// Entry get current entry
+func (it *iterator) Entry() *storage.Entry {
+	if it.idx < 0 || it.idx >= len(it.items) {
+		return nil
+	}
+
+	return it.items[it.idx].entry
+}
+
+// Err get the error that stopped the iteration
+func (it *iterator) Err() error {
+	return it.err
+}
+
+// Token get continuation token for the current entry
+func (it *iterator) Token() string {
+	if it.idx < 0 || it.idx >= len(it.items) {
+		return ""
+	}
+
+	return it.items[it.idx].token
+}
+
+// ListIter paginated listing of the path, supports storage.WithDelimiter and storage.WithToken options
+func (s *Storage) ListIter(ctx context.Context, path string, options ...map[string]interface{}) storage.Iterator {
+	opts, err := storage.ParseOptions(options...)
+
+	if err != nil {
+		return &iterator{ctx: ctx, idx: -1, err: err}
+	}
+
+	delimiter, token := opts.Delimiter, opts.Token
+	prefix := s.dirPrefix(path)
+	items := map[string]*item{}
+
+	s.mu.RLock()
+
+	for key, obj := range s.objects {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		rest := key[len(prefix):]
+
+		if idx := strings.Index(rest, delimiter); len(delimiter) > 0 && idx >= 0 {
+			dir := key[:len(prefix)+idx+len(delimiter)]
+			items[dir] = &item{
+				token: dir,
+				entry: &storage.Entry{
+					Path:  key[:len(prefix)+idx],
+					IsDir: true,
+				},
+			}
+			continue
+		}
+
+		items[key] = &item{
+			token: key,
+			entry: &storage.Entry{
+				Path:            key,
+				Size:            int64(len(obj.data)),
+				LastModified:    obj.lastModified,
+				ETag:            obj.eTag(),
+				ContentEncoding: obj.options.ContentEncoding,
+			},
+		}
+	}
+
+	s.mu.RUnlock()
+
+	it := &iterator{
+		ctx:   ctx,
+		items: make([]*item, 0, len(items)),
+		idx:   -1,
+	}
+
+	for _, itm := range items {
+		if len(token) == 0 || itm.token > token {
+			it.items = append(it.items, itm)
+		}
+	}
+
+	sort.Slice(it.items, func(i, j int) bool {
+		return it.items[i].token < it.items[j].token
+	})
+
+	return it
+}
@@ -0,0 +1,535 @@
+// Package mem in-memory implementation of the storage interfaces
+// intended for unit testing and short-lived caches
+package mem
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+)
+
+// ErrEmptyPath method call with empty file path
+var ErrEmptyPath = errors.New("empty file path")
+
+type object struct {
+	data         []byte
+	lastModified time.Time
+	options      storage.PutOptions
+}
+
+func (o *object) eTag() string {
+	return fmt.Sprintf("\"%x\"", md5.Sum(o.data))
+}
+
+// NewStorage create new storage instance
+func NewStorage() *Storage {
+	return &Storage{
+		objects: map[string]*object{},
+	}
+}
+
+// Storage thread safe in-memory storage
+type Storage struct {
+	mu      sync.RWMutex
+	objects map[string]*object
+}
+
+// List reads the path content, with storage.WithDelimiter option returns only the prefixes
+func (s *Storage) List(path string, options ...map[string]interface{}) ([]string, error) {
+	opts, err := storage.ParseOptions(options...)
+
+	if err != nil {
+		return []string{}, err
+	}
+
+	delimiter := opts.Delimiter
+	prefix := s.dirPrefix(path)
+	sep := "/"
+
+	if len(delimiter) > 0 {
+		sep = delimiter
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := map[string]bool{}
+
+	for key := range s.objects {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		rest := key[len(prefix):]
+
+		if idx := strings.Index(rest, sep); idx >= 0 {
+			names[rest[:idx]] = true
+		} else if len(delimiter) == 0 {
+			names[rest] = true
+		}
+	}
+
+	result := make([]string, 0, len(names))
+
+	for name := range names {
+		result = append(result, name)
+	}
+
+	sort.Strings(result)
+
+	return result, nil
+}
+
+// ListWithContext reads the path content, with storage.WithDelimiter option returns only the prefixes
+func (s *Storage) ListWithContext(ctx context.Context, path string, options ...map[string]interface{}) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return []string{}, err
+	}
+
+	return s.List(path, options...)
+}
+
+// Walk recursively look for files in directory
+func (s *Storage) Walk(path string, callback func(path string)) error {
+	return s.WalkWithContext(context.Background(), path, callback)
+}
+
+// WalkWithContext recursively look for files in directory
+func (s *Storage) WalkWithContext(ctx context.Context, path string, callback func(path string)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for _, key := range s.keys(path) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		callback(key)
+	}
+
+	return nil
+}
+
+// WalkFunc recursively look for files in directory, callback gets the entry details.
+// Prefixes are reported as directories, storage.SkipDir skips the directory.
+func (s *Storage) WalkFunc(ctx context.Context, path string, callback func(entry *storage.Entry) error) error {
+	return storage.WalkIter(s.ListIter(ctx, path), s.dirPrefix(path), callback)
+}
+
+// Copy copies an object to another path inside the storage
+func (s *Storage) Copy(src string, dst string, options ...map[string]interface{}) error {
+	if _, err := storage.ParseOptions(options...); err != nil {
+		return err
+	}
+
+	srcKey, err := s.key(src)
+
+	if err != nil {
+		return err
+	}
+
+	dstKey, err := s.key(dst)
+
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj, ok := s.objects[srcKey]
+
+	if !ok {
+		return &fs.PathError{Op: "copy", Path: src, Err: fs.ErrNotExist}
+	}
+
+	s.objects[dstKey] = &object{
+		data:         append([]byte{}, obj.data...),
+		lastModified: time.Now(),
+		options:      obj.options,
+	}
+
+	return nil
+}
+
+// CopyWithContext copies an object to another path inside the storage
+func (s *Storage) CopyWithContext(ctx context.Context, src string, dst string, options ...map[string]interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.Copy(src, dst, options...)
+}
+
+// Move moves an object to another path inside the storage, the object is moved as it is
+func (s *Storage) Move(ctx context.Context, src string, dst string) error {
+	if err := ctx.Err(); err != nil {
+		return &storage.MoveError{Src: src, Dst: dst, Err: err}
+	}
+
+	srcKey, err := s.key(src)
+
+	if err != nil {
+		return &storage.MoveError{Src: src, Dst: dst, Err: err}
+	}
+
+	dstKey, err := s.key(dst)
+
+	if err != nil {
+		return &storage.MoveError{Src: src, Dst: dst, Err: err}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj, ok := s.objects[srcKey]
+
+	if !ok {
+		return &storage.MoveError{Src: src, Dst: dst, Err: fs.ErrNotExist}
+	}
+
+	delete(s.objects, srcKey)
+	s.objects[dstKey] = obj
+
+	return nil
+}
+
+// Create create new object or truncate existing one
+func (s *Storage) Create(path string) (io.ReadWriteCloser, error) {
+	key, err := s.key(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects[key] = &object{
+		data:         []byte{},
+		lastModified: time.Now(),
+	}
+
+	return &file{store: s, key: key}, nil
+}
+
+// CreateWithContext create new object or truncate existing one
+func (s *Storage) CreateWithContext(ctx context.Context, path string) (io.ReadWriteCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.Create(path)
+}
+
+// Get get object from storage
+func (s *Storage) Get(path string) (io.ReadCloser, error) {
+	key, err := s.key(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	obj, ok := s.objects[key]
+
+	if !ok {
+		return nil, &fs.PathError{Op: "get", Path: path, Err: fs.ErrNotExist}
+	}
+
+	return io.NopCloser(bytes.NewReader(append([]byte{}, obj.data...))), nil
+}
+
+// GetWithContext get object from storage
+func (s *Storage) GetWithContext(ctx context.Context, path string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.Get(path)
+}
+
+// GetRange get part of the object from storage
+func (s *Storage) GetRange(ctx context.Context, path string, offset int64, length int64) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if offset < 0 {
+		return nil, storage.ErrInvalidRange
+	}
+
+	key, err := s.key(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	obj, ok := s.objects[key]
+
+	if !ok {
+		return nil, &fs.PathError{Op: "get", Path: path, Err: fs.ErrNotExist}
+	}
+
+	size := int64(len(obj.data))
+	start, end := offset, size
+
+	if start > size {
+		start = size
+	}
+
+	if length >= 0 && start+length < end {
+		end = start + length
+	}
+
+	return io.NopCloser(bytes.NewReader(append([]byte{}, obj.data[start:end]...))), nil
+}
+
+// Put object into storage
+func (s *Storage) Put(path string, body io.Reader) error {
+	return s.PutWithContext(context.Background(), path, body)
+}
+
+// PutWithContext object into storage
+func (s *Storage) PutWithContext(ctx context.Context, path string, body io.Reader) error {
+	return s.PutWithOptions(ctx, path, body, nil)
+}
+
+// PutWithOptions object into storage together with its attributes
+func (s *Storage) PutWithOptions(ctx context.Context, path string, body io.Reader, options *storage.PutOptions) error {
+	key, err := s.key(path)
+
+	if err != nil {
+		return err
+	}
+
+	data, err := io.ReadAll(body)
+
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj := &object{
+		data:         data,
+		lastModified: time.Now(),
+	}
+
+	if options != nil {
+		obj.options = *options
+		obj.options.Metadata = map[string]string{}
+
+		for name, value := range options.Metadata {
+			obj.options.Metadata[name] = value
+		}
+	}
+
+	s.objects[key] = obj
+
+	return nil
+}
+
+// SetMetadata replace user metadata of the object
+func (s *Storage) SetMetadata(ctx context.Context, path string, metadata map[string]string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	key, err := s.key(path)
+
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj, ok := s.objects[key]
+
+	if !ok {
+		return &fs.PathError{Op: "metadata", Path: path, Err: fs.ErrNotExist}
+	}
+
+	obj.options.Metadata = map[string]string{}
+
+	for name, value := range metadata {
+		obj.options.Metadata[name] = value
+	}
+
+	return nil
+}
+
+// Link generate link for the object, has no expiration in memory
+func (s *Storage) Link(path string, expire time.Duration) (string, error) {
+	key, err := s.key(path)
+
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("mem://%s", key), nil
+}
+
+// Delete remove object from storage, does nothing if object does not exist
+func (s *Storage) Delete(path string) error {
+	key, err := s.key(path)
+
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.objects, key)
+
+	return nil
+}
+
+// DeleteWithContext remove object from storage, does nothing if object does not exist
+func (s *Storage) DeleteWithContext(ctx context.Context, path string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.Delete(path)
+}
+
+// DeleteMany remove objects from storage, objects that do not exist are skipped
+func (s *Storage) DeleteMany(ctx context.Context, paths []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	errs := map[string]error{}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, path := range paths {
+		key, err := s.key(path)
+
+		if err != nil {
+			errs[path] = err
+			continue
+		}
+
+		delete(s.objects, key)
+	}
+
+	return storage.NewDeleteError(errs)
+}
+
+// DeleteAll remove the object at the path and every object under it
+func (s *Storage) DeleteAll(ctx context.Context, prefix string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	keys := s.keys(prefix)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.objects, key)
+	}
+
+	return nil
+}
+
+// Stat get object info
+func (s *Storage) Stat(path string) (storage.FileInfo, error) {
+	key, err := s.key(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	obj, ok := s.objects[key]
+
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: path, Err: fs.ErrNotExist}
+	}
+
+	info := &FileInfo{
+		name:               key[strings.LastIndex(key, "/")+1:],
+		size:               int64(len(obj.data)),
+		eTag:               obj.eTag(),
+		lastModified:       obj.lastModified,
+		cacheControl:       obj.options.CacheControl,
+		contentDisposition: obj.options.ContentDisposition,
+		contentEncoding:    obj.options.ContentEncoding,
+		contentLanguage:    obj.options.ContentLanguage,
+		contentType:        obj.options.ContentType,
+		metadata:           map[string]*string{},
+	}
+
+	for name, value := range obj.options.Metadata {
+		value := value
+		info.metadata[name] = &value
+	}
+
+	return info, nil
+}
+
+func (s *Storage) key(path string) (string, error) {
+	if len(path) <= 0 {
+		return "", ErrEmptyPath
+	}
+
+	return strings.TrimPrefix(path, "/"), nil
+}
+
+func (s *Storage) dirPrefix(path string) string {
+	dir := strings.Trim(path, "/")
+
+	if len(dir) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%s/", dir)
+}
+
+func (s *Storage) keys(path string) []string {
+	prefix := s.dirPrefix(path)
+	keys := []string{}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for key := range s.objects {
+		if strings.HasPrefix(key, prefix) || key == strings.TrimSuffix(prefix, "/") {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,203 @@
+package mem
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage/storagetest"
+	"github.com/stretchr/testify/assert"
+)
+
+const storageTestPath = "test/test.txt"
+const storageTestNestedPath = "test/nested/test.txt"
+const copyDestPath = "test/dest.txt"
+
+var storageTestExpire = time.Second * 1
+var storageTestData = []byte("hello storage")
+
+func testStorage(store storage.Storage) error {
+	return nil
+}
+
+func TestStorage(t *testing.T) {
+	assert := assert.New(t)
+	store := NewStorage()
+	ctx := context.Background()
+
+	assert.Nil(testStorage(store))
+
+	t.Run("put file", func(t *testing.T) {
+		assert.NoError(store.Put(storageTestPath, bytes.NewReader(storageTestData)))
+	})
+
+	t.Run("put file with context", func(t *testing.T) {
+		assert.NoError(store.PutWithContext(ctx, storageTestNestedPath, bytes.NewReader(storageTestData)))
+	})
+
+	t.Run("put file with canceled context", func(t *testing.T) {
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+		assert.ErrorIs(store.PutWithContext(cctx, storageTestPath, bytes.NewReader(storageTestData)), context.Canceled)
+	})
+
+	t.Run("put file with empty path", func(t *testing.T) {
+		assert.ErrorIs(store.Put("", bytes.NewReader(storageTestData)), ErrEmptyPath)
+	})
+
+	t.Run("list path's content", func(t *testing.T) {
+		content, err := store.List("/test")
+		assert.NoError(err)
+		assert.Equal([]string{"nested", "test.txt"}, content)
+	})
+
+	t.Run("list path's content with context", func(t *testing.T) {
+		content, err := store.ListWithContext(ctx, "/")
+		assert.NoError(err)
+		assert.Equal([]string{"test"}, content)
+	})
+
+	t.Run("list path's prefixes", func(t *testing.T) {
+		content, err := store.List("test/", map[string]interface{}{"delimiter": "/"})
+		assert.NoError(err)
+		assert.Equal([]string{"nested"}, content)
+	})
+
+	t.Run("walk path", func(t *testing.T) {
+		paths := []string{}
+		assert.NoError(store.Walk("/", func(path string) {
+			paths = append(paths, path)
+		}))
+		assert.Equal([]string{storageTestNestedPath, storageTestPath}, paths)
+	})
+
+	t.Run("walk path with context", func(t *testing.T) {
+		paths := []string{}
+		assert.NoError(store.WalkWithContext(ctx, "test/nested", func(path string) {
+			paths = append(paths, path)
+		}))
+		assert.Equal([]string{storageTestNestedPath}, paths)
+	})
+
+	t.Run("stat file", func(t *testing.T) {
+		info, err := store.Stat(storageTestPath)
+		assert.NoError(err)
+		assert.Equal(int64(len(storageTestData)), info.Size())
+		assert.NotEmpty(info.ETag())
+		assert.False(info.ModTime().IsZero())
+	})
+
+	t.Run("stat missing file", func(t *testing.T) {
+		_, err := store.Stat("missing.txt")
+		assert.True(errors.Is(err, fs.ErrNotExist))
+	})
+
+	t.Run("get file", func(t *testing.T) {
+		body, err := store.Get(storageTestPath)
+		assert.NoError(err)
+		defer body.Close()
+
+		data, err := io.ReadAll(body)
+		assert.NoError(err)
+		assert.Equal(storageTestData, data)
+	})
+
+	t.Run("get file with context", func(t *testing.T) {
+		body, err := store.GetWithContext(ctx, storageTestNestedPath)
+		assert.NoError(err)
+		defer body.Close()
+
+		data, err := io.ReadAll(body)
+		assert.NoError(err)
+		assert.Equal(storageTestData, data)
+	})
+
+	t.Run("get missing file", func(t *testing.T) {
+		_, err := store.Get("missing.txt")
+		assert.True(errors.Is(err, fs.ErrNotExist))
+	})
+
+	t.Run("create file", func(t *testing.T) {
+		file, err := store.Create(storageTestPath)
+		assert.NoError(err)
+
+		_, err = file.Write(storageTestData)
+		assert.NoError(err)
+
+		data, err := io.ReadAll(file)
+		assert.NoError(err)
+		assert.Empty(data)
+		assert.NoError(file.Close())
+
+		_, err = file.Write(storageTestData)
+		assert.ErrorIs(err, fs.ErrClosed)
+
+		body, err := store.Get(storageTestPath)
+		assert.NoError(err)
+
+		data, err = io.ReadAll(body)
+		assert.NoError(err)
+		assert.Equal(storageTestData, data)
+	})
+
+	t.Run("copy file", func(t *testing.T) {
+		assert.NoError(store.Copy(storageTestPath, copyDestPath))
+
+		body, err := store.Get(copyDestPath)
+		assert.NoError(err)
+
+		data, err := io.ReadAll(body)
+		assert.NoError(err)
+		assert.Equal(storageTestData, data)
+	})
+
+	t.Run("copy missing file with context", func(t *testing.T) {
+		assert.True(errors.Is(store.CopyWithContext(ctx, "missing.txt", copyDestPath), fs.ErrNotExist))
+	})
+
+	t.Run("link file", func(t *testing.T) {
+		loc, err := store.Link(storageTestPath, storageTestExpire)
+		assert.NoError(err)
+		assert.Equal("mem://test/test.txt", loc)
+	})
+
+	t.Run("delete file", func(t *testing.T) {
+		assert.NoError(store.Delete(copyDestPath))
+		_, err := store.Stat(copyDestPath)
+		assert.Error(err)
+	})
+
+	t.Run("delete file with context", func(t *testing.T) {
+		assert.NoError(store.DeleteWithContext(ctx, storageTestNestedPath))
+		assert.NoError(store.DeleteWithContext(ctx, storageTestNestedPath))
+	})
+
+	t.Run("concurrent access", func(t *testing.T) {
+		wg := new(sync.WaitGroup)
+
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+				assert.NoError(store.Put(storageTestPath, bytes.NewReader(storageTestData)))
+				_, err := store.List("/")
+				assert.NoError(err)
+			}()
+		}
+
+		wg.Wait()
+	})
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return NewStorage()
+	})
+}
@@ -0,0 +1,63 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+)
+
+// ErrEmptyBucket storage url without the bucket name
+var ErrEmptyBucket = errors.New("empty bucket name")
+
+func init() {
+	storage.Register("s3", storage.DriverFunc(open))
+}
+
+// open opens the storage for "s3://bucket/prefix" URL, credentials are taken from the environment.
+// Supported query parameters are "region", "endpoint", "force_path_style" and "disable_ssl".
+func open(_ context.Context, u *url.URL) (storage.Storage, string, error) {
+	if len(u.Host) == 0 {
+		return nil, "", ErrEmptyBucket
+	}
+
+	query := u.Query()
+	cfg := aws.NewConfig()
+
+	if region := query.Get("region"); len(region) > 0 {
+		cfg.WithRegion(region)
+	}
+
+	if endpoint := query.Get("endpoint"); len(endpoint) > 0 {
+		cfg.WithEndpoint(endpoint)
+	}
+
+	for name, set := range map[string]func(bool) *aws.Config{
+		"force_path_style": cfg.WithS3ForcePathStyle,
+		"disable_ssl":      cfg.WithDisableSSL,
+	} {
+		if !query.Has(name) {
+			continue
+		}
+
+		val, err := strconv.ParseBool(query.Get(name))
+
+		if err != nil {
+			return nil, "", err
+		}
+
+		set(val)
+	}
+
+	ses, err := session.NewSession(cfg)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	return NewStorage(ses, u.Host), u.Path, nil
+}
@@ -0,0 +1,79 @@
+package s3
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+)
+
+var errorKinds = map[string]error{
+	s3.ErrCodeNoSuchKey:               storage.ErrNotExist,
+	s3.ErrCodeNoSuchBucket:            storage.ErrNotExist,
+	s3.ErrCodeNoSuchUpload:            storage.ErrNotExist,
+	"NotFound":                        storage.ErrNotExist,
+	"AccessDenied":                    storage.ErrPermission,
+	"Forbidden":                       storage.ErrPermission,
+	s3.ErrCodeBucketAlreadyExists:     storage.ErrExist,
+	s3.ErrCodeBucketAlreadyOwnedByYou: storage.ErrExist,
+}
+
+var statusKinds = map[int]error{
+	http.StatusNotFound:  storage.ErrNotExist,
+	http.StatusForbidden: storage.ErrPermission,
+}
+
+// wrapError classifies aws error with one of the storage sentinel errors
+func wrapError(op string, path string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if kind := errorKind(err); kind != nil {
+		return storage.NewError(op, path, kind, err)
+	}
+
+	return err
+}
+
+func errorKind(err error) error {
+	var aerr awserr.Error
+
+	if !errors.As(err, &aerr) {
+		return nil
+	}
+
+	if kind, ok := errorKinds[aerr.Code()]; ok {
+		return kind
+	}
+
+	if rerr, ok := aerr.(awserr.RequestFailure); ok {
+		if kind, ok := statusKinds[rerr.StatusCode()]; ok {
+			return kind
+		}
+	}
+
+	if aerr.OrigErr() != nil {
+		return errorKind(aerr.OrigErr())
+	}
+
+	return nil
+}
+
+// Retryable check if the error is transient: throttling, 5xx responses and connection errors
+func (s *Storage) Retryable(err error) bool {
+	var aerr awserr.Error
+
+	if !errors.As(err, &aerr) {
+		return storage.Retryable(err)
+	}
+
+	if rerr, ok := aerr.(awserr.RequestFailure); ok && (rerr.StatusCode() >= http.StatusInternalServerError || rerr.StatusCode() == http.StatusTooManyRequests) {
+		return true
+	}
+
+	return request.IsErrorRetryable(aerr) || request.IsErrorThrottle(aerr)
+}
@@ -0,0 +1,88 @@
+package s3
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+const errorsTestPath = "test.txt"
+
+func TestWrapError(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Run("nil error", func(t *testing.T) {
+		assert.NoError(wrapError("get", errorsTestPath, nil))
+	})
+
+	t.Run("no such key", func(t *testing.T) {
+		cause := awserr.New(s3.ErrCodeNoSuchKey, "key does not exist", nil)
+		err := wrapError("get", errorsTestPath, cause)
+
+		assert.True(errors.Is(err, storage.ErrNotExist))
+
+		var aerr awserr.Error
+		assert.True(errors.As(err, &aerr))
+		assert.Equal(s3.ErrCodeNoSuchKey, aerr.Code())
+	})
+
+	t.Run("head not found", func(t *testing.T) {
+		cause := awserr.NewRequestFailure(awserr.New("NotFound", "not found", nil), http.StatusNotFound, "id")
+		assert.True(errors.Is(wrapError("stat", errorsTestPath, cause), storage.ErrNotExist))
+	})
+
+	t.Run("access denied", func(t *testing.T) {
+		cause := awserr.NewRequestFailure(awserr.New("AccessDenied", "access denied", nil), http.StatusForbidden, "id")
+		assert.True(errors.Is(wrapError("put", errorsTestPath, cause), storage.ErrPermission))
+	})
+
+	t.Run("forbidden by status", func(t *testing.T) {
+		cause := awserr.NewRequestFailure(awserr.New("Unknown", "forbidden", nil), http.StatusForbidden, "id")
+		assert.True(errors.Is(wrapError("stat", errorsTestPath, cause), storage.ErrPermission))
+	})
+
+	t.Run("bucket exists", func(t *testing.T) {
+		cause := awserr.New(s3.ErrCodeBucketAlreadyOwnedByYou, "bucket exists", nil)
+		assert.True(errors.Is(wrapError("create", errorsTestPath, cause), storage.ErrExist))
+	})
+
+	t.Run("nested upload error", func(t *testing.T) {
+		cause := awserr.New("MultipartUpload", "upload failed", awserr.New(s3.ErrCodeNoSuchBucket, "no bucket", nil))
+		assert.True(errors.Is(wrapError("put", errorsTestPath, cause), storage.ErrNotExist))
+	})
+
+	t.Run("unknown error", func(t *testing.T) {
+		cause := errors.New("connection reset")
+		assert.Equal(cause, wrapError("get", errorsTestPath, cause))
+	})
+}
+
+func TestRetryable(t *testing.T) {
+	assert := assert.New(t)
+	store := new(Storage)
+
+	for _, tc := range []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"internal error", awserr.NewRequestFailure(awserr.New("InternalError", "internal error", nil), http.StatusInternalServerError, "id"), true},
+		{"slow down", awserr.NewRequestFailure(awserr.New("SlowDown", "slow down", nil), http.StatusServiceUnavailable, "id"), true},
+		{"too many requests", awserr.NewRequestFailure(awserr.New("Unknown", "too many requests", nil), http.StatusTooManyRequests, "id"), true},
+		{"throttling", awserr.New("Throttling", "throttling", nil), true},
+		{"connection error", awserr.New("RequestError", "send request failed", errors.New("connection reset")), true},
+		{"wrapped server error", wrapError("get", errorsTestPath, awserr.NewRequestFailure(awserr.New("InternalError", "internal error", nil), http.StatusInternalServerError, "id")), true},
+		{"no such key", wrapError("get", errorsTestPath, awserr.NewRequestFailure(awserr.New(s3.ErrCodeNoSuchKey, "no such key", nil), http.StatusNotFound, "id")), false},
+		{"access denied", awserr.NewRequestFailure(awserr.New("AccessDenied", "access denied", nil), http.StatusForbidden, "id"), false},
+		{"unknown error", errors.New("unknown"), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(tc.retryable, store.Retryable(tc.err))
+		})
+	}
+}
@@ -4,6 +4,7 @@ import "time"
 
 // FileInfo struct to get file information
 type FileInfo struct {
+	name                      string
 	size                      int64
 	acceptRanges              string
 	activeStatus              string
@@ -36,11 +37,31 @@ type FileInfo struct {
 	websiteRedirectLocation   string
 }
 
+// Name get base name of the key
+func (fi FileInfo) Name() string {
+	return fi.name
+}
+
 // Size get file size
 func (fi FileInfo) Size() int64 {
 	return fi.size
 }
 
+// ModTime get Last-Modified
+func (fi FileInfo) ModTime() time.Time {
+	return fi.lastModified
+}
+
+// IsDir always false, keys are not directories
+func (fi FileInfo) IsDir() bool {
+	return false
+}
+
+// Sys get the file information itself, it implements storage.ContentInfo and storage.ObjectInfo
+func (fi FileInfo) Sys() interface{} {
+	return fi
+}
+
 // AcceptRanges get accept-ranges
 func (fi FileInfo) AcceptRanges() string {
 	return fi.acceptRanges
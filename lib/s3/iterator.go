@@ -0,0 +1,162 @@
+package s3
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+)
+
+func newEntry(object *s3.Object) *storage.Entry {
+	return &storage.Entry{
+		Path:         aws.StringValue(object.Key),
+		Size:         aws.Int64Value(object.Size),
+		LastModified: aws.TimeValue(object.LastModified),
+		ETag:         aws.StringValue(object.ETag),
+	}
+}
+
+type item struct {
+	token string
+	entry *storage.Entry
+}
+
+// iterator requests the listing page by page, only one page is kept in memory
+type iterator struct {
+	ctx       context.Context
+	s3        *s3.S3
+	input     *s3.ListObjectsInput
+	items     []*item
+	idx       int
+	truncated bool
+	err       error
+}
+
+// Next advance to the next entry, requests next page when the current one is over
+func (it *iterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	if it.idx+1 < len(it.items) {
+		it.idx++
+		return true
+	}
+
+	for it.truncated {
+		it.fetch()
+
+		if it.err != nil {
+			return false
+		}
+
+		if len(it.items) > 0 {
+			it.idx = 0
+			return true
+		}
+	}
+
+	it.idx = len(it.items)
+
+	return false
+}
+
+// Entry get current entry
+func (it *iterator) Entry() *storage.Entry {
+	if it.idx < 0 || it.idx >= len(it.items) {
+		return nil
+	}
+
+	return it.items[it.idx].entry
+}
+
+// Err get the error that stopped the iteration
+func (it *iterator) Err() error {
+	return it.err
+}
+
+// Token get continuation token for the current entry
+func (it *iterator) Token() string {
+	if it.idx < 0 || it.idx >= len(it.items) {
+		return ""
+	}
+
+	return it.items[it.idx].token
+}
+
+func (it *iterator) fetch() {
+	if it.err = it.ctx.Err(); it.err != nil {
+		return
+	}
+
+	res, err := it.s3.ListObjectsWithContext(it.ctx, it.input)
+
+	if err != nil {
+		it.err = wrapError("list", *it.input.Prefix, err)
+		return
+	}
+
+	it.items = make([]*item, 0, len(res.Contents)+len(res.CommonPrefixes))
+	it.idx = -1
+	it.truncated = res.IsTruncated != nil && *res.IsTruncated
+
+	for _, object := range res.Contents {
+		it.items = append(it.items, &item{token: *object.Key, entry: newEntry(object)})
+	}
+
+	for _, prefix := range res.CommonPrefixes {
+		it.items = append(it.items, &item{
+			token: *prefix.Prefix,
+			entry: &storage.Entry{
+				Path:  strings.TrimSuffix(*prefix.Prefix, aws.StringValue(it.input.Delimiter)),
+				IsDir: true,
+			},
+		})
+	}
+
+	sort.Slice(it.items, func(i, j int) bool {
+		return it.items[i].token < it.items[j].token
+	})
+
+	switch {
+	case res.NextMarker != nil && len(*res.NextMarker) > 0:
+		it.input.SetMarker(*res.NextMarker)
+	case len(it.items) > 0:
+		it.input.SetMarker(it.items[len(it.items)-1].token)
+	default:
+		it.truncated = false
+	}
+}
+
+// ListIter paginated listing of the path, supports storage.WithDelimiter and storage.WithToken options
+func (s *Storage) ListIter(ctx context.Context, path string, options ...map[string]interface{}) storage.Iterator {
+	input := &s3.ListObjectsInput{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(dirPrefix(path)),
+	}
+
+	opts, err := storage.ParseOptions(options...)
+
+	if err != nil {
+		return &iterator{err: err}
+	}
+
+	if len(opts.Delimiter) > 0 {
+		input.SetDelimiter(opts.Delimiter)
+	}
+
+	if len(opts.Token) > 0 {
+		input.SetMarker(opts.Token)
+	}
+
+	return &iterator{
+		ctx:       ctx,
+		s3:        s.s3,
+		input:     input,
+		idx:       -1,
+		truncated: true,
+	}
+}
@@ -0,0 +1,92 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+var iteratorTestData = []byte("hello storage")
+
+func TestListIter(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	srv := newServer()
+	srv.maxKeys = 3
+	store := newServerStorage(t, srv)
+
+	for i := 0; i < 5; i++ {
+		srv.put(fmt.Sprintf("iter/file%d.txt", i), iteratorTestData)
+		srv.put(fmt.Sprintf("iter/dir%d/file.txt", i), iteratorTestData)
+	}
+
+	srv.put("other/file.txt", iteratorTestData)
+
+	collect := func(iter storage.Iterator) []*storage.Entry {
+		entries := []*storage.Entry{}
+
+		for iter.Next() {
+			entries = append(entries, iter.Entry())
+		}
+
+		assert.NoError(iter.Err())
+
+		return entries
+	}
+
+	t.Run("list all objects page by page", func(t *testing.T) {
+		before := srv.count("list")
+		entries := collect(store.ListIter(ctx, "iter/"))
+
+		assert.Len(entries, 10)
+		assert.Equal("iter/dir0/file.txt", entries[0].Path)
+		assert.Equal(int64(len(iteratorTestData)), entries[0].Size)
+		assert.False(entries[0].LastModified.IsZero())
+		assert.Equal(4, srv.count("list")-before)
+	})
+
+	t.Run("list with delimiter", func(t *testing.T) {
+		entries := collect(store.ListIter(ctx, "iter/", map[string]interface{}{"delimiter": "/"}))
+
+		assert.Len(entries, 10)
+		assert.Equal("iter/dir0", entries[0].Path)
+		assert.True(entries[0].IsDir)
+		assert.Equal("iter/file0.txt", entries[5].Path)
+		assert.False(entries[5].IsDir)
+	})
+
+	t.Run("resume with token", func(t *testing.T) {
+		iter := store.ListIter(ctx, "iter/", map[string]interface{}{"delimiter": "/"})
+
+		for i := 0; i < 4; i++ {
+			assert.True(iter.Next())
+		}
+
+		assert.Equal("iter/dir3", iter.Entry().Path)
+		entries := collect(store.ListIter(ctx, "iter/", map[string]interface{}{"delimiter": "/", "token": iter.Token()}))
+
+		assert.Len(entries, 6)
+		assert.Equal("iter/dir4", entries[0].Path)
+	})
+
+	t.Run("list canceled", func(t *testing.T) {
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		iter := store.ListIter(cctx, "iter/")
+		assert.False(iter.Next())
+		assert.Error(iter.Err())
+	})
+
+	t.Run("list missing bucket", func(t *testing.T) {
+		store.bucket = "missing"
+		defer func() { store.bucket = serverTestBucket }()
+
+		iter := store.ListIter(ctx, "iter/")
+		assert.False(iter.Next())
+		assert.ErrorIs(iter.Err(), storage.ErrNotExist)
+	})
+}
@@ -0,0 +1,596 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+const serverTestBucket = "bucket"
+
+const serverOtherBucket = "other"
+
+type serverObject struct {
+	data         []byte
+	lastModified time.Time
+	header       http.Header
+}
+
+// serverHeaders object headers that are stored on upload and returned back on get
+var serverHeaders = []string{
+	"Cache-Control",
+	"Content-Disposition",
+	"Content-Encoding",
+	"Content-Language",
+	"Content-Type",
+}
+
+// objectHeader get the stored headers out of the upload request
+func objectHeader(r *http.Request) http.Header {
+	header := http.Header{}
+
+	for name, values := range r.Header {
+		if strings.HasPrefix(name, "X-Amz-Meta-") {
+			header[name] = values
+		}
+	}
+
+	for _, name := range serverHeaders {
+		if value := r.Header.Get(name); len(value) > 0 {
+			header.Set(name, value)
+		}
+	}
+
+	return header
+}
+
+func (obj *serverObject) eTag() string {
+	return fmt.Sprintf("\"%x\"", md5.Sum(obj.data))
+}
+
+type serverCopyResult struct {
+	XMLName      xml.Name `xml:"CopyObjectResult"`
+	ETag         string
+	LastModified string
+}
+
+type serverCopyPartResult struct {
+	XMLName      xml.Name `xml:"CopyPartResult"`
+	ETag         string
+	LastModified string
+}
+
+type serverDeleteRequest struct {
+	Objects []struct {
+		Key string
+	} `xml:"Object"`
+}
+
+type serverDeleteError struct {
+	Key     string
+	Code    string
+	Message string
+}
+
+type serverDeleteResult struct {
+	XMLName xml.Name            `xml:"DeleteResult"`
+	Errors  []serverDeleteError `xml:"Error"`
+}
+
+type serverMultipartResult struct {
+	XMLName  xml.Name
+	Bucket   string
+	Key      string
+	UploadId string `xml:",omitempty"`
+	ETag     string `xml:",omitempty"`
+}
+
+type serverListContents struct {
+	Key          string
+	LastModified string
+	ETag         string
+	Size         int
+}
+
+type serverListPrefix struct {
+	Prefix string
+}
+
+type serverListResult struct {
+	XMLName        xml.Name `xml:"ListBucketResult"`
+	Name           string
+	Prefix         string
+	Marker         string
+	NextMarker     string `xml:",omitempty"`
+	MaxKeys        int
+	Delimiter      string `xml:",omitempty"`
+	IsTruncated    bool
+	Contents       []serverListContents
+	CommonPrefixes []serverListPrefix
+}
+
+// server fake s3 api that keeps the objects in memory
+type server struct {
+	mu         sync.Mutex
+	objects    map[string]*serverObject
+	buckets    map[string]map[string]*serverObject
+	uploads    map[string]map[int][]byte
+	headers    map[string]http.Header
+	lastUpload int
+	requests   map[string]int
+	maxKeys    int
+	failures   int
+	locked     map[string]bool
+}
+
+func newServer() *server {
+	objects := map[string]*serverObject{}
+
+	return &server{
+		objects: objects,
+		buckets: map[string]map[string]*serverObject{
+			serverTestBucket:  objects,
+			serverOtherBucket: {},
+		},
+		uploads:  map[string]map[int][]byte{},
+		headers:  map[string]http.Header{},
+		requests: map[string]int{},
+		maxKeys:  1000,
+		locked:   map[string]bool{},
+	}
+}
+
+func (srv *server) put(key string, data []byte) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	srv.objects[key] = &serverObject{
+		data:         data,
+		lastModified: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+// lock make the key fail to delete with AccessDenied in DeleteObjects requests
+func (srv *server) lock(key string) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	srv.locked[key] = true
+}
+
+func (srv *server) fail(failures int) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	srv.failures = failures
+}
+
+func (srv *server) count(op string) int {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	return srv.requests[op]
+}
+
+func (srv *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	bucket, key := path, ""
+
+	if idx := strings.Index(path, "/"); idx >= 0 {
+		bucket, key = path[:idx], path[idx+1:]
+	}
+
+	objects, ok := srv.buckets[bucket]
+
+	if !ok {
+		srv.error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	query := r.URL.Query()
+
+	if srv.failures > 0 {
+		srv.failures--
+		srv.requests["failed"]++
+		srv.error(w, http.StatusServiceUnavailable, "SlowDown")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && len(key) == 0:
+		srv.requests["list"]++
+		srv.list(w, r, objects)
+	case r.Method == http.MethodPost && query.Has("delete"):
+		srv.requests["deleteObjects"]++
+		srv.deleteObjects(w, r, objects)
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		srv.requests["createMultipart"]++
+		srv.createMultipart(w, r, key)
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		srv.requests["uploadPart"]++
+		srv.uploadPart(w, r)
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		srv.requests["completeMultipart"]++
+		srv.completeMultipart(w, r, objects, key)
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		srv.requests["abortMultipart"]++
+		delete(srv.uploads, query.Get("uploadId"))
+		delete(srv.headers, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut && len(r.Header.Get("X-Amz-Copy-Source")) > 0:
+		srv.requests["copy"]++
+		srv.copyObject(w, r, objects, key)
+	case r.Method == http.MethodPut:
+		srv.requests["put"]++
+		srv.putObject(w, r, objects, key)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		srv.requests["get"]++
+		srv.getObject(w, r, objects, key)
+	case r.Method == http.MethodDelete:
+		srv.requests["delete"]++
+		delete(objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		srv.error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+func (srv *server) putObject(w http.ResponseWriter, r *http.Request, objects map[string]*serverObject, key string) {
+	data, err := io.ReadAll(r.Body)
+
+	if err != nil {
+		srv.error(w, http.StatusBadRequest, "IncompleteBody")
+		return
+	}
+
+	obj := &serverObject{
+		data:         data,
+		lastModified: time.Now().UTC().Truncate(time.Second),
+		header:       objectHeader(r),
+	}
+	objects[key] = obj
+	w.Header().Set("ETag", obj.eTag())
+}
+
+// source get the object from X-Amz-Copy-Source header
+func (srv *server) source(r *http.Request) (*serverObject, bool) {
+	source := strings.TrimPrefix(r.Header.Get("X-Amz-Copy-Source"), "/")
+	bucket, src := source, ""
+
+	if idx := strings.Index(source, "/"); idx >= 0 {
+		bucket, src = source[:idx], source[idx+1:]
+	}
+
+	obj, ok := srv.buckets[bucket][src]
+	return obj, ok
+}
+
+func (srv *server) copyObject(w http.ResponseWriter, r *http.Request, objects map[string]*serverObject, key string) {
+	obj, ok := srv.source(r)
+
+	if !ok {
+		srv.error(w, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+
+	cpy := &serverObject{
+		data:         append([]byte{}, obj.data...),
+		lastModified: time.Now().UTC().Truncate(time.Second),
+		header:       obj.header,
+	}
+
+	if r.Header.Get("X-Amz-Metadata-Directive") == "REPLACE" {
+		cpy.header = objectHeader(r)
+	}
+	objects[key] = cpy
+
+	_ = xml.NewEncoder(w).Encode(&serverCopyResult{
+		ETag:         cpy.eTag(),
+		LastModified: cpy.lastModified.Format(time.RFC3339),
+	})
+}
+
+func (srv *server) getObject(w http.ResponseWriter, r *http.Request, objects map[string]*serverObject, key string) {
+	obj, ok := objects[key]
+
+	if !ok {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			srv.error(w, http.StatusNotFound, "NoSuchKey")
+		}
+
+		return
+	}
+
+	data, status := obj.data, http.StatusOK
+
+	if rng := r.Header.Get("Range"); len(rng) > 0 {
+		start, end, ok := serverRange(rng, len(obj.data))
+
+		if !ok {
+			srv.error(w, http.StatusRequestedRangeNotSatisfiable, "InvalidRange")
+			return
+		}
+
+		data, status = obj.data[start:end+1], http.StatusPartialContent
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(obj.data)))
+	}
+
+	for name, values := range obj.header {
+		w.Header()[name] = values
+	}
+
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("ETag", obj.eTag())
+	w.Header().Set("Last-Modified", obj.lastModified.Format(http.TimeFormat))
+	w.WriteHeader(status)
+
+	if r.Method == http.MethodGet {
+		_, _ = w.Write(data)
+	}
+}
+
+// serverRange parses "bytes=start-end" header, end is inclusive and optional
+func serverRange(rng string, size int) (int, int, bool) {
+	bounds := strings.SplitN(strings.TrimPrefix(rng, "bytes="), "-", 2)
+	start, err := strconv.Atoi(bounds[0])
+
+	if err != nil || len(bounds) != 2 || start >= size {
+		return 0, 0, false
+	}
+
+	end := size - 1
+
+	if len(bounds[1]) > 0 {
+		if end, err = strconv.Atoi(bounds[1]); err != nil || end < start {
+			return 0, 0, false
+		}
+	}
+
+	if end >= size {
+		end = size - 1
+	}
+
+	return start, end, true
+}
+
+func (srv *server) createMultipart(w http.ResponseWriter, r *http.Request, key string) {
+	srv.lastUpload++
+	id := strconv.Itoa(srv.lastUpload)
+	srv.uploads[id] = map[int][]byte{}
+	srv.headers[id] = objectHeader(r)
+
+	_ = xml.NewEncoder(w).Encode(&serverMultipartResult{
+		XMLName:  xml.Name{Local: "InitiateMultipartUploadResult"},
+		Bucket:   serverTestBucket,
+		Key:      key,
+		UploadId: id,
+	})
+}
+
+func (srv *server) uploadPart(w http.ResponseWriter, r *http.Request) {
+	parts, ok := srv.uploads[r.URL.Query().Get("uploadId")]
+
+	if !ok {
+		srv.error(w, http.StatusNotFound, "NoSuchUpload")
+		return
+	}
+
+	num, _ := strconv.Atoi(r.URL.Query().Get("partNumber"))
+
+	if len(r.Header.Get("X-Amz-Copy-Source")) > 0 {
+		srv.requests["uploadPartCopy"]++
+		obj, ok := srv.source(r)
+
+		if !ok {
+			srv.error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+
+		start, end, ok := serverRange(r.Header.Get("X-Amz-Copy-Source-Range"), len(obj.data))
+
+		if !ok {
+			srv.error(w, http.StatusRequestedRangeNotSatisfiable, "InvalidRange")
+			return
+		}
+
+		parts[num] = append([]byte{}, obj.data[start:end+1]...)
+
+		_ = xml.NewEncoder(w).Encode(&serverCopyPartResult{
+			ETag:         fmt.Sprintf("\"%d\"", num),
+			LastModified: obj.lastModified.Format(time.RFC3339),
+		})
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+
+	if err != nil {
+		srv.error(w, http.StatusBadRequest, "IncompleteBody")
+		return
+	}
+
+	parts[num] = data
+	w.Header().Set("ETag", fmt.Sprintf("\"%d\"", num))
+}
+
+func (srv *server) completeMultipart(w http.ResponseWriter, r *http.Request, objects map[string]*serverObject, key string) {
+	id := r.URL.Query().Get("uploadId")
+	parts, ok := srv.uploads[id]
+
+	if !ok {
+		srv.error(w, http.StatusNotFound, "NoSuchUpload")
+		return
+	}
+
+	nums := []int{}
+
+	for num := range parts {
+		nums = append(nums, num)
+	}
+
+	sort.Ints(nums)
+	data := []byte{}
+
+	for _, num := range nums {
+		data = append(data, parts[num]...)
+	}
+
+	objects[key] = &serverObject{
+		data:         data,
+		lastModified: time.Now().UTC().Truncate(time.Second),
+		header:       srv.headers[id],
+	}
+	delete(srv.uploads, id)
+	delete(srv.headers, id)
+
+	_ = xml.NewEncoder(w).Encode(&serverMultipartResult{
+		XMLName: xml.Name{Local: "CompleteMultipartUploadResult"},
+		Bucket:  serverTestBucket,
+		Key:     key,
+		ETag:    objects[key].eTag(),
+	})
+}
+
+func (srv *server) deleteObjects(w http.ResponseWriter, r *http.Request, objects map[string]*serverObject) {
+	req := new(serverDeleteRequest)
+
+	if err := xml.NewDecoder(r.Body).Decode(req); err != nil || len(req.Objects) == 0 || len(req.Objects) > 1000 {
+		srv.error(w, http.StatusBadRequest, "MalformedXML")
+		return
+	}
+
+	res := new(serverDeleteResult)
+
+	for _, object := range req.Objects {
+		if srv.locked[object.Key] {
+			res.Errors = append(res.Errors, serverDeleteError{Key: object.Key, Code: "AccessDenied", Message: "Access Denied"})
+			continue
+		}
+
+		delete(objects, object.Key)
+	}
+
+	_ = xml.NewEncoder(w).Encode(res)
+}
+
+func (srv *server) error(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte("<Error><Code>" + code + "</Code><Message>" + code + "</Message></Error>"))
+}
+
+func (srv *server) list(w http.ResponseWriter, r *http.Request, objects map[string]*serverObject) {
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+	delimiter := query.Get("delimiter")
+	marker := query.Get("marker")
+	maxKeys := srv.maxKeys
+
+	if mk, err := strconv.Atoi(query.Get("max-keys")); err == nil && mk < maxKeys {
+		maxKeys = mk
+	}
+
+	keys := []string{}
+
+	for key := range objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	res := &serverListResult{
+		Name:      serverTestBucket,
+		Prefix:    prefix,
+		Marker:    marker,
+		MaxKeys:   maxKeys,
+		Delimiter: delimiter,
+	}
+
+	last := ""
+
+	for _, key := range keys {
+		if key <= marker {
+			continue
+		}
+
+		token := key
+
+		if idx := strings.Index(key[len(prefix):], delimiter); len(delimiter) > 0 && idx >= 0 {
+			token = key[:len(prefix)+idx+len(delimiter)]
+
+			if token == last || token <= marker {
+				continue
+			}
+		}
+
+		if len(res.Contents)+len(res.CommonPrefixes) >= maxKeys {
+			res.IsTruncated = true
+			break
+		}
+
+		if token != key {
+			res.CommonPrefixes = append(res.CommonPrefixes, serverListPrefix{Prefix: token})
+		} else {
+			obj := objects[key]
+			res.Contents = append(res.Contents, serverListContents{
+				Key:          key,
+				LastModified: obj.lastModified.Format(time.RFC3339),
+				ETag:         obj.eTag(),
+				Size:         len(obj.data),
+			})
+		}
+
+		last = token
+	}
+
+	if res.IsTruncated && len(delimiter) > 0 {
+		res.NextMarker = last
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(res)
+}
+
+func newServerURL(t *testing.T, srv *server) string {
+	t.Helper()
+
+	hsrv := httptest.NewServer(srv)
+	t.Cleanup(hsrv.Close)
+
+	return hsrv.URL
+}
+
+func newServerStorage(t *testing.T, srv *server) *Storage {
+	t.Helper()
+
+	ses := session.Must(session.NewSession(&aws.Config{
+		Region:           aws.String("us-east-2"),
+		Credentials:      credentials.NewStaticCredentials("1234", "5678", ""),
+		Endpoint:         aws.String(newServerURL(t, srv)),
+		S3ForcePathStyle: aws.Bool(true),
+		DisableSSL:       aws.Bool(true),
+		MaxRetries:       aws.Int(0),
+	}))
+
+	return NewStorage(ses, serverTestBucket)
+}
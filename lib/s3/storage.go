@@ -4,15 +4,19 @@
 package s3
 
 import (
-	"errors"
+	"bytes"
+	"context"
 	"fmt"
 	"io"
-	"math"
+	"net/http"
 	pathTool "path"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	s3manager "github.com/aws/aws-sdk-go/service/s3/s3manager"
@@ -21,22 +25,45 @@ import (
 
 const partSize = 1024 * 1024 * 5 * 5
 
+// maxUploadSizeBytes objects up to this size are copied in one request, bigger ones are copied in parts of this size
 const maxUploadSizeBytes = 4294967296
 
+// deleteBatchSize max number of keys in one DeleteObjects request
+const deleteBatchSize = 1000
+
+// deleteConcurrency number of DeleteObjects requests that are sent in parallel
+const deleteConcurrency = 4
+
+// identityEncoding get the body as it is stored, otherwise http client decompresses objects with gzip Content-Encoding.
+// It's used only for storage.WithRawContent calls, so plain Get keeps decoding gzip objects.
+var identityEncoding = request.WithSetRequestHeaders(map[string]string{"Accept-Encoding": "identity"})
+
+// dirPrefix get the listing prefix of the directory, the root of the bucket has empty prefix
+func dirPrefix(path string) string {
+	dir := strings.Trim(path, "/")
+
+	if len(dir) == 0 {
+		return ""
+	}
+
+	return dir + "/"
+}
+
 func contents(res *s3.ListObjectsOutput) []string {
 	result := make([]string, 0)
+	dirs := map[string]bool{}
 
 	for _, object := range res.Contents {
 		// Check whether the object is nested in a path
-		p := strings.Split(*object.Key, "/")
+		name := strings.TrimPrefix(*object.Key, aws.StringValue(res.Prefix))
+		p := strings.Split(name, "/")
 
 		if len(p) == 1 {
 			// It's a file
-			result = append(result, *object.Key)
-		} else if result[len(p)-1] != p[0] {
-			// It's a folder
-			// And it is not added yet
-			// res.Contents is sorted so if p[0] is not unique it would appear last in the result
+			result = append(result, name)
+		} else if !dirs[p[0]] {
+			// It's a folder that is not added yet
+			dirs[p[0]] = true
 			result = append(result, p[0])
 		}
 	}
@@ -57,8 +84,9 @@ func prefixes(res *s3.ListObjectsOutput) []string {
 // NewStorage create new storage instance
 func NewStorage(ses *session.Session, bucket string) *Storage {
 	return &Storage{
-		s3:     s3.New(ses),
-		bucket: bucket,
+		s3:           s3.New(ses),
+		bucket:       bucket,
+		copyPartSize: maxUploadSizeBytes,
 		uploader: s3manager.NewUploader(ses, func(upl *s3manager.Uploader) {
 			upl.PartSize = partSize
 		}),
@@ -67,27 +95,33 @@ func NewStorage(ses *session.Session, bucket string) *Storage {
 
 // Storage interface adaptation for s3
 type Storage struct {
-	bucket   string
-	uploader *s3manager.Uploader
-	s3       *s3.S3
+	bucket       string
+	copyPartSize int64
+	uploader     *s3manager.Uploader
+	s3           *s3.S3
 }
 
-// List reads the path content or prefixes.
+// List reads the path content or prefixes with storage.WithDelimiter option.
+// The path is a directory, "a" lists "a/b.txt" but not "ab.txt", names are relative to the path.
 func (s *Storage) List(path string, options ...map[string]interface{}) ([]string, error) {
 	var result []string
 
 	input := s3.ListObjectsInput{
 		Bucket: aws.String(s.bucket),
-		Prefix: aws.String(path),
+		Prefix: aws.String(dirPrefix(path)),
 	}
 
-	if len(options) > 0 {
-		if options[0]["delimiter"] != nil {
-			input.SetDelimiter(options[0]["delimiter"].(string))
-		}
+	opts, err := storage.ParseOptions(options...)
+
+	if err != nil {
+		return []string{}, err
 	}
 
-	err := s.s3.ListObjectsPages(
+	if len(opts.Delimiter) > 0 {
+		input.SetDelimiter(opts.Delimiter)
+	}
+
+	err = s.s3.ListObjectsPages(
 		&input,
 		// handle bulks of 1000 keys
 		func(res *s3.ListObjectsOutput, _ bool) bool {
@@ -101,25 +135,29 @@ func (s *Storage) List(path string, options ...map[string]interface{}) ([]string
 		},
 	)
 
-	return result, err
+	return result, wrapError("list", path, err)
 }
 
-// ListWithContext reads the path content or prefixes
+// ListWithContext reads the path content or prefixes with storage.WithDelimiter option
 func (s *Storage) ListWithContext(ctx aws.Context, path string, options ...map[string]interface{}) ([]string, error) {
 	var result []string
 
 	input := s3.ListObjectsInput{
 		Bucket: aws.String(s.bucket),
-		Prefix: aws.String(path),
+		Prefix: aws.String(dirPrefix(path)),
 	}
 
-	if len(options) > 0 {
-		if options[0]["delimiter"] != nil {
-			input.SetDelimiter(options[0]["delimiter"].(string))
-		}
+	opts, err := storage.ParseOptions(options...)
+
+	if err != nil {
+		return []string{}, err
+	}
+
+	if len(opts.Delimiter) > 0 {
+		input.SetDelimiter(opts.Delimiter)
 	}
 
-	err := s.s3.ListObjectsPagesWithContext(
+	err = s.s3.ListObjectsPagesWithContext(
 		ctx,
 		&input,
 		// handle bulks of 1000 keys
@@ -134,238 +172,243 @@ func (s *Storage) ListWithContext(ctx aws.Context, path string, options ...map[s
 		},
 	)
 
-	return result, err
+	return result, wrapError("list", path, err)
 }
 
 // Walk recursively look for files in directory
 func (s *Storage) Walk(path string, callback func(path string)) error {
-	res, err := s.s3.ListObjects(&s3.ListObjectsInput{
-		Bucket: aws.String(s.bucket),
-		Prefix: aws.String(path),
-	})
-
-	if err != nil {
-		return err
-	}
-
-	for _, object := range res.Contents {
-		callback(*object.Key)
-	}
-
-	return nil
+	return s.WalkWithContext(context.Background(), path, callback)
 }
 
 // WalkWithContext recursively look for files in directory
 func (s *Storage) WalkWithContext(ctx aws.Context, path string, callback func(path string)) error {
-	res, err := s.s3.ListObjectsWithContext(ctx, &s3.ListObjectsInput{
-		Bucket: aws.String(s.bucket),
-		Prefix: aws.String(path),
-	})
-
-	if err != nil {
-		return err
-	}
+	return s.WalkFunc(ctx, path, func(entry *storage.Entry) error {
+		if !entry.IsDir {
+			callback(entry.Path)
+		}
 
-	for _, object := range res.Contents {
-		callback(*object.Key)
-	}
+		return nil
+	})
+}
 
-	return nil
+// WalkFunc recursively look for files in directory page by page, prefixes are reported as directories.
+// Callback gets the object details and stops the walk by returning an error or skips the prefix with storage.SkipDir.
+func (s *Storage) WalkFunc(ctx aws.Context, path string, callback func(entry *storage.Entry) error) error {
+	return storage.WalkIter(s.ListIter(ctx, path), dirPrefix(path), callback)
 }
 
 // Copy copies an object from the a path in a bucket to another path in the same or different bucket.
-// 'src' and 'dst' are absolute paths of the file.
+// 'src' and 'dst' are absolute paths of the file, storage.WithDestinationBucket sets the bucket of 'dst'.
 func (s *Storage) Copy(src string, dst string, options ...map[string]interface{}) error {
+	return s.CopyWithContext(context.Background(), src, dst, options...)
+}
+
+// CopyWithContext copies an object from the a path in a bucket to another path in the same or different bucket.
+// 'src' and 'dst' are absolute paths of the file, storage.WithDestinationBucket sets the bucket of 'dst'.
+func (s *Storage) CopyWithContext(ctx aws.Context, src string, dst string, options ...map[string]interface{}) error {
+	opts, err := storage.ParseOptions(options...)
+
+	if err != nil {
+		return err
+	}
+
 	bucket := s.bucket
 
-	for _, opt := range options {
-		if v, ok := opt["bucket"]; ok {
-			if bkt, ok := v.(string); ok {
-				bucket = bkt
-			}
-		}
+	if len(opts.DestinationBucket) > 0 {
+		bucket = opts.DestinationBucket
 	}
 
-	hr, err := s.s3.HeadObject(&s3.HeadObjectInput{
-		Bucket: aws.String(bucket),
+	hr, err := s.s3.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
 		Key:    aws.String(src),
 	})
 
 	if err != nil {
-		return err
+		return wrapError("copy", src, err)
 	}
 
-	if *hr.ContentLength <= maxUploadSizeBytes {
-		_, err = s.s3.CopyObject(&s3.CopyObjectInput{
+	if *hr.ContentLength <= s.copyPartSize {
+		_, err = s.s3.CopyObjectWithContext(ctx, &s3.CopyObjectInput{
 			Bucket:     aws.String(bucket),
 			CopySource: aws.String(fmt.Sprintf("%s/%s", s.bucket, src)),
 			Key:        aws.String(dst),
 		})
 
-		return err
+		return wrapError("copy", dst, err)
 	}
 
-	cmr, err := s.s3.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(dst),
+	return wrapError("copy", dst, s.copyMultipart(ctx, bucket, src, dst, hr))
+}
+
+// copyMultipart copy the object in parts of copyPartSize, content headers and metadata are kept.
+// Unfinished upload is aborted so the copied parts are not left in the bucket.
+func (s *Storage) copyMultipart(ctx aws.Context, bucket string, src string, dst string, head *s3.HeadObjectOutput) error {
+	cmr, err := s.s3.CreateMultipartUploadWithContext(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:             aws.String(bucket),
+		Key:                aws.String(dst),
+		CacheControl:       head.CacheControl,
+		ContentDisposition: head.ContentDisposition,
+		ContentEncoding:    head.ContentEncoding,
+		ContentLanguage:    head.ContentLanguage,
+		ContentType:        head.ContentType,
+		Metadata:           head.Metadata,
 	})
 
 	if err != nil {
 		return err
 	}
 
-	cmu := &s3.CompletedMultipartUpload{}
-	maxPart := int(math.Ceil(float64(*hr.ContentLength) / float64(maxUploadSizeBytes)))
+	abort := func(err error) error {
+		_, _ = s.s3.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(bucket),
+			Key:      aws.String(dst),
+			UploadId: cmr.UploadId,
+		})
+
+		return err
+	}
 
-	for prt := 0; prt < maxPart; prt++ {
-		from := prt * maxUploadSizeBytes
-		to := (prt * maxUploadSizeBytes) + maxUploadSizeBytes
+	cmu := &s3.CompletedMultipartUpload{}
+	size := *head.ContentLength
 
-		if prt != 0 {
-			from += 1
-		}
+	for from, num := int64(0), int64(1); from < size; from, num = from+s.copyPartSize, num+1 {
+		to := from + s.copyPartSize - 1
 
-		if to > int(*hr.ContentLength) {
-			to = int(*hr.ContentLength) - 1
+		if to >= size {
+			to = size - 1
 		}
 
-		upr, err := s.s3.UploadPartCopy(&s3.UploadPartCopyInput{
+		upr, err := s.s3.UploadPartCopyWithContext(ctx, &s3.UploadPartCopyInput{
 			Bucket:          aws.String(bucket),
-			CopySource:      aws.String(fmt.Sprintf("%s/%s", bucket, src)),
+			CopySource:      aws.String(fmt.Sprintf("%s/%s", s.bucket, src)),
 			CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", from, to)),
 			Key:             aws.String(dst),
-			PartNumber:      aws.Int64(int64(prt) + 1),
-			UploadId:        aws.String(*cmr.UploadId),
+			PartNumber:      aws.Int64(num),
+			UploadId:        cmr.UploadId,
 		})
 
 		if err != nil {
-			return err
+			return abort(err)
 		}
 
 		cmu.Parts = append(cmu.Parts, &s3.CompletedPart{
 			ETag:       upr.CopyPartResult.ETag,
-			PartNumber: aws.Int64(int64(prt) + 1),
+			PartNumber: aws.Int64(num),
 		})
 	}
 
-	_, err = s.s3.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+	_, err = s.s3.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
 		Bucket:          aws.String(bucket),
 		Key:             aws.String(dst),
-		UploadId:        aws.String(*cmr.UploadId),
+		UploadId:        cmr.UploadId,
 		MultipartUpload: cmu,
 	})
 
-	return err
+	if err != nil {
+		return abort(err)
+	}
+
+	return nil
 }
 
-// CopyWithContext copies an object from the a path in a bucket to another path in the same or different bucket.
-// 'src' and 'dst' are absolute paths of the file.
-func (s *Storage) CopyWithContext(ctx aws.Context, src string, dst string, options ...map[string]interface{}) error {
-	bucket := s.bucket
+// Move copies the object to 'dst' and removes 'src', objects bigger than the copy part size are copied in parts.
+// When the source can't be removed after the copy storage.MoveError has Copied set.
+func (s *Storage) Move(ctx aws.Context, src string, dst string) error {
+	if src == dst {
+		_, err := s.s3.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(src),
+		})
 
-	for _, opt := range options {
-		if v, ok := opt["dstBucket"]; ok {
-			if bkt, ok := v.(string); ok {
-				bucket = bkt
-			}
+		if err != nil {
+			return &storage.MoveError{Src: src, Dst: dst, Err: wrapError("move", src, err)}
 		}
+
+		return nil
 	}
 
-	hr, err := s.s3.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(src),
-	})
+	if err := s.CopyWithContext(ctx, src, dst); err != nil {
+		return &storage.MoveError{Src: src, Dst: dst, Err: err}
+	}
 
-	if err != nil {
-		return err
+	if err := s.DeleteWithContext(ctx, src); err != nil {
+		return &storage.MoveError{Src: src, Dst: dst, Copied: true, Err: err}
 	}
 
-	if *hr.ContentLength <= maxUploadSizeBytes {
-		_, err = s.s3.CopyObjectWithContext(ctx, &s3.CopyObjectInput{
-			Bucket:     aws.String(bucket),
-			CopySource: aws.String(fmt.Sprintf("%s/%s", s.bucket, src)),
-			Key:        aws.String(dst),
-		})
+	return nil
+}
 
-		return err
-	}
+// Create open the object for streaming writes, see CreateWithContext
+func (s *Storage) Create(path string) (io.ReadWriteCloser, error) {
+	return s.CreateWithContext(context.Background(), path)
+}
 
-	cmr, err := s.s3.CreateMultipartUploadWithContext(ctx, &s3.CreateMultipartUploadInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(dst),
+// Get file from s3 bucket
+func (s *Storage) Get(path string) (io.ReadCloser, error) {
+	out, err := s.s3.GetObjectWithContext(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
 	})
 
 	if err != nil {
-		return err
+		return nil, wrapError("get", path, err)
 	}
 
-	cmu := &s3.CompletedMultipartUpload{}
-	maxPart := int(math.Ceil(float64(*hr.ContentLength) / float64(maxUploadSizeBytes)))
+	return out.Body, nil
+}
 
-	for prt := 0; prt < maxPart; prt++ {
-		from := prt * maxUploadSizeBytes
-		to := (prt * maxUploadSizeBytes) + maxUploadSizeBytes
+// GetWithContext gets file from s3 bucket, objects with gzip Content-Encoding are decoded
+// unless the context comes from storage.WithRawContent
+func (s *Storage) GetWithContext(ctx aws.Context, path string) (io.ReadCloser, error) {
+	opts := []request.Option{}
 
-		if prt != 0 {
-			from += 1
-		}
+	if storage.RawContent(ctx) {
+		opts = append(opts, identityEncoding)
+	}
 
-		if to > int(*hr.ContentLength) {
-			to = int(*hr.ContentLength) - 1
-		}
+	out, err := s.s3.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
+	}, opts...)
 
-		upr, err := s.s3.UploadPartCopyWithContext(ctx, &s3.UploadPartCopyInput{
-			Bucket:          aws.String(bucket),
-			CopySource:      aws.String(fmt.Sprintf("%s/%s", bucket, src)),
-			CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", from, to)),
-			Key:             aws.String(dst),
-			PartNumber:      aws.Int64(int64(prt) + 1),
-			UploadId:        aws.String(*cmr.UploadId),
-		})
+	if err != nil {
+		return nil, wrapError("get", path, err)
+	}
 
-		if err != nil {
-			return err
-		}
+	return out.Body, nil
+}
 
-		cmu.Parts = append(cmu.Parts, &s3.CompletedPart{
-			ETag:       upr.CopyPartResult.ETag,
-			PartNumber: aws.Int64(int64(prt) + 1),
-		})
+// GetRange gets part of the file from s3 bucket using the Range header
+func (s *Storage) GetRange(ctx aws.Context, path string, offset int64, length int64) (io.ReadCloser, error) {
+	if offset < 0 {
+		return nil, storage.ErrInvalidRange
 	}
 
-	_, err = s.s3.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
-		Bucket:          aws.String(bucket),
-		Key:             aws.String(dst),
-		UploadId:        aws.String(*cmr.UploadId),
-		MultipartUpload: cmu,
-	})
+	rng := fmt.Sprintf("bytes=%d-", offset)
 
-	return err
-}
+	if length == 0 {
+		return io.NopCloser(bytes.NewReader([]byte{})), nil
+	}
 
-// Create for create interface
-func (s *Storage) Create(_ string) (io.ReadWriteCloser, error) {
-	return nil, errors.New("method unimplemented")
-}
+	if length > 0 {
+		rng = fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+	}
 
-// Get file from s3 bucket
-func (s *Storage) Get(path string) (io.ReadCloser, error) {
-	out, err := s.s3.GetObject(&s3.GetObjectInput{
+	out, err := s.s3.GetObjectWithContext(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(path),
+		Range:  aws.String(rng),
 	})
 
-	return out.Body, err
-}
+	if rerr, ok := err.(awserr.RequestFailure); ok && rerr.StatusCode() == http.StatusRequestedRangeNotSatisfiable {
+		return io.NopCloser(bytes.NewReader([]byte{})), nil
+	}
 
-// GetWithContext gets file from s3 bucket
-func (s *Storage) GetWithContext(ctx aws.Context, path string) (io.ReadCloser, error) {
-	out, err := s.s3.GetObjectWithContext(ctx, &s3.GetObjectInput{
-		Bucket: aws.String(s.bucket),
-		Key:    aws.String(path),
-	})
+	if err != nil {
+		return nil, wrapError("get", path, err)
+	}
 
-	return out.Body, err
+	return out.Body, nil
 }
 
 // Put file into s3 bucket
@@ -376,7 +419,7 @@ func (s *Storage) Put(path string, body io.Reader) error {
 		Body:   body,
 	})
 
-	return err
+	return wrapError("put", path, err)
 }
 
 // PutWithContext puts file into s3 bucket
@@ -387,7 +430,72 @@ func (s *Storage) PutWithContext(ctx aws.Context, path string, body io.Reader) e
 		Body:   body,
 	})
 
-	return err
+	return wrapError("put", path, err)
+}
+
+// PutWithOptions file into s3 bucket together with content headers and user metadata
+func (s *Storage) PutWithOptions(ctx aws.Context, path string, body io.Reader, options *storage.PutOptions) error {
+	input := &s3manager.UploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
+		Body:   body,
+	}
+
+	if options != nil {
+		input.CacheControl = optionalString(options.CacheControl)
+		input.ContentDisposition = optionalString(options.ContentDisposition)
+		input.ContentEncoding = optionalString(options.ContentEncoding)
+		input.ContentLanguage = optionalString(options.ContentLanguage)
+		input.ContentType = optionalString(options.ContentType)
+
+		if len(options.Metadata) > 0 {
+			input.Metadata = aws.StringMap(options.Metadata)
+		}
+	}
+
+	_, err := s.uploader.UploadWithContext(ctx, input)
+
+	return wrapError("put", path, err)
+}
+
+// SetMetadata replace user metadata of the object. S3 objects can't be changed in place,
+// the object is copied onto itself with the same content headers, objects bigger than the copy part size are copied in parts.
+func (s *Storage) SetMetadata(ctx aws.Context, path string, metadata map[string]string) error {
+	hr, err := s.s3.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
+	})
+
+	if err != nil {
+		return wrapError("metadata", path, err)
+	}
+
+	hr.Metadata = nil
+
+	if len(metadata) > 0 {
+		hr.Metadata = aws.StringMap(metadata)
+	}
+
+	if aws.Int64Value(hr.ContentLength) > s.copyPartSize {
+		return wrapError("metadata", path, s.copyMultipart(ctx, s.bucket, path, path, hr))
+	}
+
+	input := &s3.CopyObjectInput{
+		Bucket:             aws.String(s.bucket),
+		CopySource:         aws.String(fmt.Sprintf("%s/%s", s.bucket, path)),
+		Key:                aws.String(path),
+		MetadataDirective:  aws.String(s3.MetadataDirectiveReplace),
+		CacheControl:       hr.CacheControl,
+		ContentDisposition: hr.ContentDisposition,
+		ContentEncoding:    hr.ContentEncoding,
+		ContentLanguage:    hr.ContentLanguage,
+		ContentType:        hr.ContentType,
+		Metadata:           hr.Metadata,
+	}
+
+	_, err = s.s3.CopyObjectWithContext(ctx, input)
+
+	return wrapError("metadata", path, err)
 }
 
 // Link generate expiration link for s3 access
@@ -407,7 +515,7 @@ func (s *Storage) Delete(path string) error {
 		Bucket: aws.String(s.bucket),
 	})
 
-	return err
+	return wrapError("delete", path, err)
 }
 
 // DeleteWithContext removes object from s3
@@ -417,7 +525,133 @@ func (s *Storage) DeleteWithContext(ctx aws.Context, path string) error {
 		Bucket: aws.String(s.bucket),
 	})
 
-	return err
+	return wrapError("delete", path, err)
+}
+
+// DeleteMany remove objects with DeleteObjects requests of up to 1000 keys, requests are sent in parallel.
+// Keys that s3 could not delete are reported with storage.DeleteError.
+func (s *Storage) DeleteMany(ctx aws.Context, paths []string) error {
+	batches := make(chan []string)
+
+	go func() {
+		defer close(batches)
+
+		for start := 0; start < len(paths); start += deleteBatchSize {
+			end := start + deleteBatchSize
+
+			if end > len(paths) {
+				end = len(paths)
+			}
+
+			batches <- paths[start:end]
+		}
+	}()
+
+	return s.deleteBatches(ctx, batches)
+}
+
+// DeleteAll remove the object at the path and every object under it,
+// every page of the listing is deleted with one DeleteObjects request while the next page is listed
+func (s *Storage) DeleteAll(ctx aws.Context, prefix string) error {
+	dir := strings.TrimSuffix(prefix, "/")
+	batches := make(chan []string)
+	result := make(chan error, 1)
+
+	go func() {
+		result <- s.deleteBatches(ctx, batches)
+	}()
+
+	input := &s3.ListObjectsInput{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(dir),
+	}
+
+	err := s.s3.ListObjectsPagesWithContext(ctx, input, func(res *s3.ListObjectsOutput, _ bool) bool {
+		batch := []string{}
+
+		for _, object := range res.Contents {
+			if key := aws.StringValue(object.Key); len(dir) == 0 || key == dir || strings.HasPrefix(key, dir+"/") {
+				batch = append(batch, key)
+			}
+		}
+
+		if len(batch) > 0 {
+			batches <- batch
+		}
+
+		return true
+	})
+
+	close(batches)
+	derr := <-result
+
+	if err != nil {
+		return wrapError("delete", prefix, err)
+	}
+
+	return derr
+}
+
+// deleteBatches delete the batches with deleteConcurrency parallel requests
+func (s *Storage) deleteBatches(ctx aws.Context, batches <-chan []string) error {
+	mu := new(sync.Mutex)
+	wg := new(sync.WaitGroup)
+	errs := map[string]error{}
+
+	for i := 0; i < deleteConcurrency; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for batch := range batches {
+				failed := s.deleteBatch(ctx, batch)
+
+				mu.Lock()
+				for key, err := range failed {
+					errs[key] = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return storage.NewDeleteError(errs)
+}
+
+// deleteBatch delete the keys with one DeleteObjects request, returns errors of the keys that were not deleted
+func (s *Storage) deleteBatch(ctx aws.Context, keys []string) map[string]error {
+	errs := map[string]error{}
+	objects := make([]*s3.ObjectIdentifier, 0, len(keys))
+
+	for _, key := range keys {
+		objects = append(objects, &s3.ObjectIdentifier{Key: aws.String(key)})
+	}
+
+	res, err := s.s3.DeleteObjectsWithContext(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String(s.bucket),
+		Delete: &s3.Delete{
+			Objects: objects,
+			Quiet:   aws.Bool(true),
+		},
+	})
+
+	if err != nil {
+		for _, key := range keys {
+			errs[key] = wrapError("delete", key, err)
+		}
+
+		return errs
+	}
+
+	for _, oerr := range res.Errors {
+		key := aws.StringValue(oerr.Key)
+		errs[key] = wrapError("delete", key, awserr.New(aws.StringValue(oerr.Code), aws.StringValue(oerr.Message), nil))
+	}
+
+	return errs
 }
 
 // Stat get object info
@@ -428,10 +662,12 @@ func (s *Storage) Stat(path string) (storage.FileInfo, error) {
 	})
 
 	if err != nil {
-		return nil, err
+		return nil, wrapError("stat", path, err)
 	}
 
-	file := new(FileInfo)
+	file := &FileInfo{
+		name: pathTool.Base(path),
+	}
 
 	if out.AcceptRanges != nil {
 		file.acceptRanges = *out.AcceptRanges
@@ -555,3 +791,12 @@ func (s *Storage) Stat(path string) (storage.FileInfo, error) {
 
 	return file, nil
 }
+
+// optionalString get pointer to the value, empty value is not sent
+func optionalString(value string) *string {
+	if len(value) == 0 {
+		return nil
+	}
+
+	return aws.String(value)
+}
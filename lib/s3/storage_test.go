@@ -1,15 +1,27 @@
 package s3
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage/storagetest"
 	"github.com/stretchr/testify/assert"
 )
 
+var walkTestData = []byte("hello storage")
+var errWalkTestStop = errors.New("stop walk")
+
 func testStorage(store storage.Storage) error {
 	return nil
 }
@@ -25,3 +37,486 @@ func TestStorage(t *testing.T) {
 	assert.NotNil(store)
 	assert.Nil(testStorage(store))
 }
+
+func TestWalk(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	srv := newServer()
+	store := newServerStorage(t, srv)
+
+	for i := 0; i < 2500; i++ {
+		srv.put(fmt.Sprintf("walk/%04d.txt", i), walkTestData)
+	}
+
+	t.Run("walk all of the pages", func(t *testing.T) {
+		before := srv.count("list")
+		paths := []string{}
+
+		assert.NoError(store.Walk("walk/", func(path string) {
+			paths = append(paths, path)
+		}))
+		assert.Len(paths, 2500)
+		assert.Equal("walk/2499.txt", paths[2499])
+		assert.Equal(3, srv.count("list")-before)
+	})
+
+	t.Run("walk with context", func(t *testing.T) {
+		count := 0
+
+		assert.NoError(store.WalkWithContext(ctx, "/walk", func(path string) {
+			count++
+		}))
+		assert.Equal(2500, count)
+	})
+
+	t.Run("walk with entries", func(t *testing.T) {
+		entries := []*storage.Entry{}
+
+		assert.NoError(store.WalkFunc(ctx, "walk/", func(entry *storage.Entry) error {
+			entries = append(entries, entry)
+			return nil
+		}))
+		assert.Len(entries, 2500)
+		assert.Equal(int64(len(walkTestData)), entries[0].Size)
+		assert.NotEmpty(entries[0].ETag)
+		assert.False(entries[0].LastModified.IsZero())
+	})
+
+	t.Run("stop walk with error", func(t *testing.T) {
+		before := srv.count("list")
+		count := 0
+
+		err := store.WalkFunc(ctx, "walk/", func(entry *storage.Entry) error {
+			if count++; count == 5 {
+				return errWalkTestStop
+			}
+
+			return nil
+		})
+		assert.ErrorIs(err, errWalkTestStop)
+		assert.Equal(5, count)
+		assert.Equal(1, srv.count("list")-before)
+	})
+
+	t.Run("cancel walk between pages", func(t *testing.T) {
+		before := srv.count("list")
+		cctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		count := 0
+
+		err := store.WalkWithContext(cctx, "walk/", func(path string) {
+			if count++; count == 1000 {
+				cancel()
+			}
+		})
+		assert.ErrorIs(err, context.Canceled)
+		assert.Equal(1000, count)
+		assert.Equal(1, srv.count("list")-before)
+	})
+}
+
+func TestGetRange(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	srv := newServer()
+	store := newServerStorage(t, srv)
+	srv.put("range/test.txt", walkTestData)
+
+	for _, tc := range []struct {
+		name   string
+		offset int64
+		length int64
+		data   string
+	}{
+		{"read part", 6, 4, "stor"},
+		{"read to the end", 6, -1, "storage"},
+		{"read past the end", 6, 100, "storage"},
+		{"read from the end", 13, 5, ""},
+		{"read empty range", 0, 0, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			body, err := store.GetRange(ctx, "range/test.txt", tc.offset, tc.length)
+			assert.NoError(err)
+			defer body.Close()
+
+			data, err := io.ReadAll(body)
+			assert.NoError(err)
+			assert.Equal(tc.data, string(data))
+		})
+	}
+
+	t.Run("read missing object", func(t *testing.T) {
+		_, err := store.GetRange(ctx, "range/missing.txt", 0, 5)
+		assert.ErrorIs(err, storage.ErrNotExist)
+	})
+
+	t.Run("read with negative offset", func(t *testing.T) {
+		_, err := store.GetRange(ctx, "range/test.txt", -1, 5)
+		assert.ErrorIs(err, storage.ErrInvalidRange)
+	})
+
+	t.Run("read through range reader", func(t *testing.T) {
+		before := srv.count("get")
+		reader := storage.NewRangeReader(ctx, store, "range/test.txt", int64(len(walkTestData)))
+		defer reader.Close()
+
+		data, err := io.ReadAll(io.NewSectionReader(reader, 6, 4))
+		assert.NoError(err)
+		assert.Equal("stor", string(data))
+		assert.Equal(1, srv.count("get")-before)
+	})
+}
+
+func TestPutWithOptions(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	srv := newServer()
+	store := newServerStorage(t, srv)
+	options := &storage.PutOptions{
+		ContentType:  "text/plain",
+		CacheControl: "no-cache",
+		Metadata:     map[string]string{"Author": "test"},
+	}
+
+	t.Run("put with options", func(t *testing.T) {
+		assert.NoError(store.PutWithOptions(ctx, "options/test.txt", bytes.NewReader(walkTestData), options))
+
+		info, err := store.Stat("options/test.txt")
+		assert.NoError(err)
+		assert.Equal("text/plain", info.ContentType())
+		assert.Equal("no-cache", storage.Content(info).CacheControl())
+		assert.Empty(storage.Content(info).ContentEncoding())
+		assert.Equal("test", aws.StringValue(info.Metadata()["Author"]))
+		assert.Equal("test.txt", info.Name())
+		assert.False(info.IsDir())
+
+		_, ok := storage.Object(info)
+		assert.True(ok)
+	})
+
+	t.Run("put without options", func(t *testing.T) {
+		assert.NoError(store.PutWithOptions(ctx, "options/empty.txt", bytes.NewReader(walkTestData), nil))
+
+		info, err := store.Stat("options/empty.txt")
+		assert.NoError(err)
+		assert.Equal(int64(len(walkTestData)), info.Size())
+		assert.Empty(info.Metadata())
+	})
+
+	t.Run("set metadata", func(t *testing.T) {
+		assert.NoError(store.PutWithOptions(ctx, "options/meta.txt", bytes.NewReader(walkTestData), options))
+		assert.NoError(store.SetMetadata(ctx, "options/meta.txt", map[string]string{"Reviewer": "test"}))
+
+		info, err := store.Stat("options/meta.txt")
+		assert.NoError(err)
+		assert.Equal("text/plain", info.ContentType())
+		assert.Equal("no-cache", storage.Content(info).CacheControl())
+		assert.NotContains(info.Metadata(), "Author")
+		assert.Equal("test", aws.StringValue(info.Metadata()["Reviewer"]))
+
+		err = store.SetMetadata(ctx, "options/missing.txt", nil)
+		assert.ErrorIs(err, storage.ErrNotExist)
+	})
+
+	t.Run("set metadata of big object in parts", func(t *testing.T) {
+		store.copyPartSize = 4
+		defer func() { store.copyPartSize = maxUploadSizeBytes }()
+
+		assert.NoError(store.PutWithOptions(ctx, "options/big.txt", bytes.NewReader(walkTestData), options))
+		before := srv.count("uploadPartCopy")
+
+		assert.NoError(store.SetMetadata(ctx, "options/big.txt", map[string]string{"Reviewer": "test"}))
+		assert.Equal((len(walkTestData)+3)/4, srv.count("uploadPartCopy")-before)
+		assert.Equal(walkTestData, srv.objects["options/big.txt"].data)
+
+		info, err := store.Stat("options/big.txt")
+		assert.NoError(err)
+		assert.Equal("text/plain", info.ContentType())
+		assert.Equal("no-cache", storage.Content(info).CacheControl())
+		assert.NotContains(info.Metadata(), "Author")
+		assert.Equal("test", aws.StringValue(info.Metadata()["Reviewer"]))
+	})
+
+	t.Run("get encoded body", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		zw := gzip.NewWriter(buf)
+		_, err := zw.Write(walkTestData)
+		assert.NoError(err)
+		assert.NoError(zw.Close())
+
+		assert.NoError(store.PutWithOptions(ctx, "options/test.txt.gz", bytes.NewReader(buf.Bytes()), &storage.PutOptions{ContentEncoding: "gzip"}))
+		raw := storage.WithRawContent(ctx)
+
+		for _, get := range []struct {
+			get  func() (io.ReadCloser, error)
+			data []byte
+		}{
+			{func() (io.ReadCloser, error) { return store.Get("options/test.txt.gz") }, walkTestData},
+			{func() (io.ReadCloser, error) { return store.GetWithContext(ctx, "options/test.txt.gz") }, walkTestData},
+			{func() (io.ReadCloser, error) { return store.GetWithContext(raw, "options/test.txt.gz") }, buf.Bytes()},
+		} {
+			body, err := get.get()
+			assert.NoError(err)
+
+			if err != nil {
+				continue
+			}
+
+			data, err := io.ReadAll(body)
+			assert.NoError(err)
+			assert.NoError(body.Close())
+			assert.Equal(get.data, data)
+		}
+
+		compressed := storage.WithCompression(store, storage.Gzip)
+		body, err := compressed.Get("options/test.txt.gz")
+		assert.NoError(err)
+
+		data, err := io.ReadAll(body)
+		assert.NoError(err)
+		assert.NoError(body.Close())
+		assert.Equal(walkTestData, data)
+	})
+}
+
+func TestCopy(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	srv := newServer()
+	store := newServerStorage(t, srv)
+	srv.put("copy/src.txt", walkTestData)
+
+	t.Run("copy in the same bucket", func(t *testing.T) {
+		assert.NoError(store.Copy("copy/src.txt", "copy/dst.txt"))
+		assert.Equal(walkTestData, srv.objects["copy/dst.txt"].data)
+	})
+
+	t.Run("copy to destination bucket", func(t *testing.T) {
+		assert.NoError(store.CopyWithContext(ctx, "copy/src.txt", "copy/typed.txt", storage.WithDestinationBucket(serverOtherBucket)))
+		assert.Equal(walkTestData, srv.buckets[serverOtherBucket]["copy/typed.txt"].data)
+		assert.NotContains(srv.objects, "copy/typed.txt")
+	})
+
+	t.Run("copy with legacy options", func(t *testing.T) {
+		assert.NoError(store.Copy("copy/src.txt", "copy/bucket.txt", map[string]interface{}{"bucket": serverOtherBucket}))
+		assert.Contains(srv.buckets[serverOtherBucket], "copy/bucket.txt")
+
+		assert.NoError(store.CopyWithContext(ctx, "copy/src.txt", "copy/dstBucket.txt", map[string]interface{}{"dstBucket": serverOtherBucket}))
+		assert.Contains(srv.buckets[serverOtherBucket], "copy/dstBucket.txt")
+	})
+
+	t.Run("copy with invalid options", func(t *testing.T) {
+		before := srv.count("get")
+		assert.ErrorIs(store.Copy("copy/src.txt", "copy/dst.txt", map[string]interface{}{"bucket": 1}), storage.ErrInvalidOption)
+		assert.Equal(before, srv.count("get"))
+	})
+
+	t.Run("copy missing object", func(t *testing.T) {
+		assert.ErrorIs(store.Copy("copy/missing.txt", "copy/dst.txt", storage.WithDestinationBucket(serverOtherBucket)), storage.ErrNotExist)
+	})
+
+	t.Run("list with invalid options", func(t *testing.T) {
+		_, err := store.List("copy/", map[string]interface{}{"delimiter": 1})
+		assert.ErrorIs(err, storage.ErrInvalidOption)
+	})
+}
+
+func TestMove(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	srv := newServer()
+	store := newServerStorage(t, srv)
+
+	t.Run("move object", func(t *testing.T) {
+		srv.put("move/src.txt", walkTestData)
+
+		assert.NoError(store.Move(ctx, "move/src.txt", "move/dst.txt"))
+		assert.Equal(walkTestData, srv.objects["move/dst.txt"].data)
+		assert.NotContains(srv.objects, "move/src.txt")
+	})
+
+	t.Run("move big object in parts", func(t *testing.T) {
+		store.copyPartSize = 4
+		defer func() { store.copyPartSize = maxUploadSizeBytes }()
+
+		options := &storage.PutOptions{ContentType: "text/plain", Metadata: map[string]string{"Author": "test"}}
+		assert.NoError(store.PutWithOptions(ctx, "move/big.txt", bytes.NewReader(walkTestData), options))
+		before := srv.count("uploadPartCopy")
+
+		assert.NoError(store.Move(ctx, "move/big.txt", "move/parts.txt"))
+		assert.Equal(walkTestData, srv.objects["move/parts.txt"].data)
+		assert.NotContains(srv.objects, "move/big.txt")
+		assert.Equal((len(walkTestData)+3)/4, srv.count("uploadPartCopy")-before)
+
+		info, err := store.Stat("move/parts.txt")
+		assert.NoError(err)
+		assert.Equal("text/plain", info.ContentType())
+		assert.Equal("test", *info.Metadata()["Author"])
+	})
+
+	t.Run("move missing object", func(t *testing.T) {
+		err := store.Move(ctx, "move/missing.txt", "move/dst.txt")
+		assert.ErrorIs(err, storage.ErrNotExist)
+
+		merr := new(storage.MoveError)
+		assert.True(errors.As(err, &merr))
+		assert.False(merr.Copied)
+	})
+
+	t.Run("move object onto itself", func(t *testing.T) {
+		assert.NoError(store.Move(ctx, "move/dst.txt", "move/dst.txt"))
+		assert.Equal(walkTestData, srv.objects["move/dst.txt"].data)
+	})
+}
+
+func TestDeleteMany(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	srv := newServer()
+	store := newServerStorage(t, srv)
+
+	t.Run("delete in batches", func(t *testing.T) {
+		paths := []string{}
+
+		for i := 0; i < 2500; i++ {
+			path := fmt.Sprintf("many/%04d.txt", i)
+			srv.put(path, walkTestData)
+			paths = append(paths, path)
+		}
+
+		assert.NoError(store.DeleteMany(ctx, paths))
+		assert.Empty(srv.objects)
+		assert.Equal(3, srv.count("deleteObjects"))
+	})
+
+	t.Run("report keys that were not deleted", func(t *testing.T) {
+		srv.put("many/a.txt", walkTestData)
+		srv.put("many/b.txt", walkTestData)
+		srv.lock("many/b.txt")
+
+		err := store.DeleteMany(ctx, []string{"many/a.txt", "many/b.txt"})
+		assert.ErrorIs(err, storage.ErrPermission)
+
+		derr := new(storage.DeleteError)
+		assert.True(errors.As(err, &derr))
+		assert.Equal([]string{"many/b.txt"}, derr.Paths())
+		assert.NotContains(srv.objects, "many/a.txt")
+		assert.Contains(srv.objects, "many/b.txt")
+	})
+
+	t.Run("report failed request for every key", func(t *testing.T) {
+		srv.fail(1)
+
+		err := store.DeleteMany(ctx, []string{"many/c.txt", "many/d.txt"})
+		derr := new(storage.DeleteError)
+		assert.True(errors.As(err, &derr))
+		assert.Equal([]string{"many/c.txt", "many/d.txt"}, derr.Paths())
+	})
+}
+
+func TestDeleteAll(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	srv := newServer()
+	srv.maxKeys = 2
+	store := newServerStorage(t, srv)
+
+	for _, path := range []string{"jobs/1", "jobs/1/a.txt", "jobs/1/b.txt", "jobs/1/c/d.txt", "jobs/10/a.txt", "jobs/2/a.txt"} {
+		srv.put(path, walkTestData)
+	}
+
+	assert.NoError(store.DeleteAll(ctx, "jobs/1/"))
+	assert.Len(srv.objects, 2)
+	assert.Contains(srv.objects, "jobs/10/a.txt")
+	assert.Contains(srv.objects, "jobs/2/a.txt")
+
+	assert.NoError(store.DeleteAll(ctx, ""))
+	assert.Empty(srv.objects)
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return newServerStorage(t, newServer())
+	})
+}
+
+func TestOpen(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	srv := newServer()
+	query := url.Values{
+		"region":           {"us-east-2"},
+		"endpoint":         {newServerURL(t, srv)},
+		"force_path_style": {"true"},
+		"disable_ssl":      {"true"},
+	}
+	t.Setenv("AWS_ACCESS_KEY_ID", "1234")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "5678")
+
+	t.Run("open bucket with prefix", func(t *testing.T) {
+		store, err := storage.Open(ctx, "s3://bucket/open/prefix?"+query.Encode())
+		assert.NoError(err)
+		assert.NoError(store.Put("test.txt", bytes.NewReader(walkTestData)))
+		assert.Equal(walkTestData, srv.objects["open/prefix/test.txt"].data)
+
+		paths := []string{}
+		assert.NoError(store.Walk("/", func(path string) {
+			paths = append(paths, path)
+		}))
+		assert.Equal([]string{"test.txt"}, paths)
+	})
+
+	t.Run("open without bucket", func(t *testing.T) {
+		_, err := storage.Open(ctx, "s3:///prefix")
+		assert.ErrorIs(err, ErrEmptyBucket)
+	})
+
+	t.Run("open with invalid option", func(t *testing.T) {
+		_, err := storage.Open(ctx, "s3://bucket?disable_ssl=maybe")
+		assert.Error(err)
+	})
+}
+
+func TestWithRetry(t *testing.T) {
+	assert := assert.New(t)
+	srv := newServer()
+	store := storage.WithRetry(newServerStorage(t, srv), storage.RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+	})
+	srv.put("retry/test.txt", walkTestData)
+
+	t.Run("retry server errors", func(t *testing.T) {
+		srv.fail(2)
+		body, err := store.Get("retry/test.txt")
+		assert.NoError(err)
+		defer body.Close()
+
+		data, err := io.ReadAll(body)
+		assert.NoError(err)
+		assert.Equal(walkTestData, data)
+	})
+
+	t.Run("give up after max attempts", func(t *testing.T) {
+		before := srv.count("failed")
+		srv.fail(5)
+		defer srv.fail(0)
+
+		_, err := store.Stat("retry/test.txt")
+		assert.Error(err)
+		assert.Equal(3, srv.count("failed")-before)
+	})
+
+	t.Run("do not retry missing objects", func(t *testing.T) {
+		before := srv.count("get")
+		_, err := store.Stat("retry/missing.txt")
+		assert.ErrorIs(err, storage.ErrNotExist)
+		assert.Equal(1, srv.count("get")-before)
+	})
+
+	t.Run("retry put with seekable body", func(t *testing.T) {
+		srv.fail(1)
+		assert.NoError(store.Put("retry/put.txt", bytes.NewReader(walkTestData)))
+		assert.Equal(walkTestData, srv.objects["retry/put.txt"].data)
+	})
+}
@@ -0,0 +1,99 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	s3manager "github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+)
+
+// Writer streams the data into s3 object through the multipart upload running in background.
+// The object is committed on Close, reads always fail with storage.ErrWriteOnly.
+type Writer struct {
+	pw     *io.PipeWriter
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// Read handle is write only, always returns storage.ErrWriteOnly
+func (w *Writer) Read(_ []byte) (int, error) {
+	return 0, storage.ErrWriteOnly
+}
+
+// Write sends the data to the upload, fails if upload was aborted
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+// Close finishes the upload and waits for the object to be committed
+func (w *Writer) Close() error {
+	return w.CloseWithError(nil)
+}
+
+// CloseWithError aborts the upload and returns the error back, nil error commits the upload same as Close
+func (w *Writer) CloseWithError(err error) error {
+	_ = w.pw.CloseWithError(err)
+	<-w.done
+	w.cancel()
+
+	if err != nil {
+		return err
+	}
+
+	return w.err
+}
+
+// CreateWithContext open the object for streaming writes, data is uploaded in parts of partSize.
+// Upload is aborted if the context is canceled or the writer is closed with an error.
+func (s *Storage) CreateWithContext(ctx context.Context, path string) (io.ReadWriteCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+	w := &Writer{
+		pw:     pw,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		defer close(w.done)
+
+		_, err := s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(path),
+			Body:   pr,
+		})
+
+		if err != nil {
+			s.abort(ctx, path, err)
+		}
+
+		w.err = wrapError("create", path, err)
+		_ = pr.CloseWithError(w.err)
+	}()
+
+	return w, nil
+}
+
+// abort makes sure the parts are removed when uploader could not do it because of canceled context
+func (s *Storage) abort(ctx context.Context, path string, err error) {
+	var mfe s3manager.MultiUploadFailure
+
+	if ctx.Err() == nil || !errors.As(err, &mfe) {
+		return
+	}
+
+	_, _ = s.s3.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.bucket),
+		Key:      aws.String(path),
+		UploadId: aws.String(mfe.UploadID()),
+	})
+}
@@ -0,0 +1,104 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	s3manager "github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+const writerTestPath = "create/test.txt"
+
+var writerTestData = []byte("hello storage")
+var errWriterTestAbort = errors.New("abort upload")
+
+func TestWriter(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	srv := newServer()
+	store := newServerStorage(t, srv)
+	store.uploader.PartSize = s3manager.MinUploadPartSize
+	chunk := bytes.Repeat([]byte("a"), 1024*1024)
+
+	t.Run("create small object", func(t *testing.T) {
+		file, err := store.Create(writerTestPath)
+		assert.NoError(err)
+
+		_, err = file.Write(writerTestData)
+		assert.NoError(err)
+		assert.NoError(file.Close())
+		assert.NoError(file.Close())
+		assert.Equal(writerTestData, srv.objects[writerTestPath].data)
+	})
+
+	t.Run("read from the handle", func(t *testing.T) {
+		file, err := store.Create(writerTestPath)
+		assert.NoError(err)
+		defer file.Close()
+
+		_, err = file.Read(make([]byte, 10))
+		assert.ErrorIs(err, storage.ErrWriteOnly)
+	})
+
+	t.Run("create multipart object", func(t *testing.T) {
+		before := srv.count("uploadPart")
+		file, err := store.CreateWithContext(ctx, writerTestPath)
+		assert.NoError(err)
+
+		for i := 0; i < 11; i++ {
+			_, err = file.Write(chunk)
+			assert.NoError(err)
+		}
+
+		assert.NoError(file.Close())
+		assert.Equal(3, srv.count("uploadPart")-before)
+		assert.Len(srv.objects[writerTestPath].data, 11*len(chunk))
+	})
+
+	t.Run("abort with error", func(t *testing.T) {
+		before := srv.count("abortMultipart")
+		file, err := store.CreateWithContext(ctx, "create/aborted.txt")
+		assert.NoError(err)
+
+		for i := 0; i < 6; i++ {
+			_, err = file.Write(chunk)
+			assert.NoError(err)
+		}
+
+		assert.ErrorIs(file.(*Writer).CloseWithError(errWriterTestAbort), errWriterTestAbort)
+		assert.NotContains(srv.objects, "create/aborted.txt")
+		assert.Equal(1, srv.count("abortMultipart")-before)
+		assert.Empty(srv.uploads)
+	})
+
+	t.Run("abort with canceled context", func(t *testing.T) {
+		cctx, cancel := context.WithCancel(ctx)
+		file, err := store.CreateWithContext(cctx, "create/canceled.txt")
+		assert.NoError(err)
+
+		for i := 0; i < 6; i++ {
+			_, err = file.Write(chunk)
+			assert.NoError(err)
+		}
+
+		cancel()
+		assert.Error(file.Close())
+		assert.NotContains(srv.objects, "create/canceled.txt")
+		assert.Empty(srv.uploads)
+
+		_, err = file.Write(chunk)
+		assert.Error(err)
+	})
+
+	t.Run("create with canceled context", func(t *testing.T) {
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := store.CreateWithContext(cctx, writerTestPath)
+		assert.ErrorIs(err, context.Canceled)
+	})
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DeleteError report of the batch delete, Errs keeps the error of every path that was not deleted.
+// The rest of the paths are deleted.
+type DeleteError struct {
+	Errs map[string]error
+}
+
+// NewDeleteError create the report from errors of the paths, returns nil when there are no errors
+func NewDeleteError(errs map[string]error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return &DeleteError{Errs: errs}
+}
+
+// Error get error message
+func (e *DeleteError) Error() string {
+	msgs := []string{}
+
+	for _, path := range e.Paths() {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", path, e.Errs[path]))
+	}
+
+	return fmt.Sprintf("delete %d paths failed: %s", len(msgs), strings.Join(msgs, "; "))
+}
+
+// Unwrap get the error of the first failed path in lexical order
+func (e *DeleteError) Unwrap() error {
+	if paths := e.Paths(); len(paths) > 0 {
+		return e.Errs[paths[0]]
+	}
+
+	return nil
+}
+
+// Paths get the paths that were not deleted in lexical order
+func (e *DeleteError) Paths() []string {
+	paths := make([]string, 0, len(e.Errs))
+
+	for path := range e.Errs {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
+
+// DeleteMany deletes the paths with BatchDeleter when storage implements it, otherwise deletes them one by one
+func DeleteMany(ctx context.Context, store Storage, paths []string) error {
+	if deleter, ok := store.(BatchDeleter); ok {
+		return deleter.DeleteMany(ctx, paths)
+	}
+
+	errs := map[string]error{}
+
+	for _, path := range paths {
+		if err := store.DeleteWithContext(ctx, path); err != nil {
+			errs[path] = err
+		}
+	}
+
+	return NewDeleteError(errs)
+}
+
+// DeleteAll deletes the object at the path and every object under it with BatchDeleter when storage implements it,
+// otherwise walks the path and deletes the objects one by one
+func DeleteAll(ctx context.Context, store Storage, prefix string) error {
+	if deleter, ok := store.(BatchDeleter); ok {
+		return deleter.DeleteAll(ctx, prefix)
+	}
+
+	paths := []string{}
+	err := store.WalkWithContext(ctx, prefix, func(path string) {
+		paths = append(paths, path)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return DeleteMany(ctx, store, paths)
+}
@@ -0,0 +1,113 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/dev-toolkit/lib/mem"
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+var batchTestData = []byte("hello batch")
+
+func TestDeleteError(t *testing.T) {
+	assert := assert.New(t)
+	errFirst := errors.New("first")
+	errSecond := errors.New("second")
+
+	assert.NoError(storage.NewDeleteError(map[string]error{}))
+
+	err := storage.NewDeleteError(map[string]error{"b.txt": errSecond, "a.txt": errFirst})
+	assert.EqualError(err, "delete 2 paths failed: a.txt: first; b.txt: second")
+	assert.ErrorIs(err, errFirst)
+
+	derr := new(storage.DeleteError)
+	assert.True(errors.As(err, &derr))
+	assert.Equal([]string{"a.txt", "b.txt"}, derr.Paths())
+}
+
+func TestDeleteMany(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	t.Run("delete one by one", func(t *testing.T) {
+		backend := mem.NewStorage()
+		store := struct{ storage.Storage }{backend}
+
+		for _, path := range []string{"a.txt", "b.txt", "c.txt"} {
+			assert.NoError(backend.Put(path, bytes.NewReader(batchTestData)))
+		}
+
+		assert.NoError(storage.DeleteMany(ctx, store, []string{"a.txt", "b.txt", "missing.txt"}))
+		assert.Equal([]string{"c.txt"}, walkPaths(t, backend, "/"))
+	})
+
+	t.Run("report every failed path", func(t *testing.T) {
+		store := struct{ storage.Storage }{&failingStorage{mem.NewStorage()}}
+
+		err := storage.DeleteMany(ctx, store, []string{"a.txt", "b.txt"})
+		assert.ErrorIs(err, errMirrorTestFailed)
+
+		derr := new(storage.DeleteError)
+		assert.True(errors.As(err, &derr))
+		assert.Equal([]string{"a.txt", "b.txt"}, derr.Paths())
+	})
+
+	t.Run("report paths inside the prefix", func(t *testing.T) {
+		store := storage.WithPrefix(struct{ storage.Storage }{&failingStorage{mem.NewStorage()}}, "jobs")
+
+		err := storage.DeleteMany(ctx, store, []string{"a.txt", "../b.txt"})
+		assert.ErrorIs(err, storage.ErrInvalidPath)
+
+		derr := new(storage.DeleteError)
+		assert.True(errors.As(err, &derr))
+		assert.Equal([]string{"../b.txt", "a.txt"}, derr.Paths())
+		assert.ErrorIs(derr.Errs["a.txt"], errMirrorTestFailed)
+	})
+}
+
+func TestDeleteAll(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	t.Run("walk and delete", func(t *testing.T) {
+		backend := mem.NewStorage()
+		store := struct{ storage.Storage }{backend}
+
+		for _, path := range []string{"jobs/1/a.txt", "jobs/1/b/c.txt", "jobs/2/a.txt"} {
+			assert.NoError(backend.Put(path, bytes.NewReader(batchTestData)))
+		}
+
+		assert.NoError(storage.DeleteAll(ctx, store, "jobs/1"))
+		assert.Equal([]string{"jobs/2/a.txt"}, walkPaths(t, backend, "/"))
+	})
+
+	t.Run("invalidate the cache", func(t *testing.T) {
+		backend := mem.NewStorage()
+		store := storage.WithCache(backend, mem.NewStorage(), storage.CachePolicy{TTL: time.Hour})
+		assert.NoError(store.Put("jobs/1/a.txt", bytes.NewReader(batchTestData)))
+
+		body, err := store.Get("jobs/1/a.txt")
+		assert.NoError(err)
+		assert.NoError(body.Close())
+
+		assert.NoError(storage.DeleteAll(ctx, store, "jobs"))
+		_, err = store.Get("jobs/1/a.txt")
+		assert.ErrorIs(err, storage.ErrNotExist)
+	})
+}
+
+func walkPaths(t *testing.T, store storage.Storage, path string) []string {
+	t.Helper()
+	paths := []string{}
+
+	if err := store.Walk(path, func(path string) { paths = append(paths, path) }); err != nil {
+		t.Fatalf("walk '%s': %v", path, err)
+	}
+
+	return paths
+}
@@ -0,0 +1,543 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachePolicy limits of the cache
+type CachePolicy struct {
+	// MaxBytes total size of the cached objects, least recently used objects are evicted first.
+	// Objects bigger than the limit are not cached, zero means no limit.
+	MaxBytes int64
+
+	// TTL how long cached object is served without revalidation,
+	// after that the ETag is checked with Stat. Zero revalidates on every call.
+	TTL time.Duration
+}
+
+// WithCache read-through cache for Get and Stat, objects are kept in the cache storage.
+// Put, Delete, Copy and Create through the wrapper invalidate the cached objects.
+// Objects are written to the cache while the body is read and cached only when it's read till the end,
+// one Get per path fills the cache at a time and the fill is dropped when the object is changed through the wrapper meanwhile.
+// The index of the cache is kept in memory, objects left in the cache storage from previous runs are overwritten.
+func WithCache(store Storage, cache Storage, policy CachePolicy) Storage {
+	return &cacheStorage{
+		store:   store,
+		cache:   cache,
+		policy:  policy,
+		lru:     list.New(),
+		entries: map[string]*list.Element{},
+		gens:    map[string]uint64{},
+	}
+}
+
+// cacheEntry object that is kept in the cache
+type cacheEntry struct {
+	path    string
+	size    int64
+	info    FileInfo
+	checked time.Time
+}
+
+// cacheStorage serves objects from the cache storage while they are fresh
+type cacheStorage struct {
+	store   Storage
+	cache   Storage
+	policy  CachePolicy
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+	gens    map[string]uint64
+	size    int64
+}
+
+// sameVersion check if the object did not change, backends without ETag are compared by size and modification time
+func sameVersion(a FileInfo, b FileInfo) bool {
+	if len(a.ETag()) > 0 || len(b.ETag()) > 0 {
+		return a.ETag() == b.ETag()
+	}
+
+	return a.Size() == b.Size() && a.ModTime().Equal(b.ModTime())
+}
+
+// lookup get fresh entry of the object, revalidates the entry when TTL is over
+func (c *cacheStorage) lookup(path string) (FileInfo, bool) {
+	c.mu.Lock()
+	elem, ok := c.entries[path]
+
+	if !ok {
+		c.mu.Unlock()
+		return nil, false
+	}
+
+	ent := elem.Value.(*cacheEntry)
+	c.lru.MoveToFront(elem)
+
+	if time.Since(ent.checked) < c.policy.TTL {
+		c.mu.Unlock()
+		return ent.info, true
+	}
+
+	cached := ent.info
+	c.mu.Unlock()
+
+	info, err := c.store.Stat(path)
+
+	if err != nil || !sameVersion(cached, info) {
+		c.remove(path)
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[path]; ok {
+		ent := elem.Value.(*cacheEntry)
+		ent.info = info
+		ent.checked = time.Now()
+	}
+
+	return info, true
+}
+
+// fill start writing the object to the cache, false if the object is being written already.
+// The generation of the path is kept until the fill is done, remove bumps it.
+func (c *cacheStorage) fill(path string) (uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.gens[path]; ok {
+		return 0, false
+	}
+
+	c.gens[path] = 0
+
+	return 0, true
+}
+
+// unfill drop the object that could not be written to the cache, the cache file is kept if the path is indexed
+func (c *cacheStorage) unfill(path string, written bool) {
+	c.mu.Lock()
+	_, indexed := c.entries[path]
+	c.mu.Unlock()
+
+	if written && !indexed {
+		_ = c.cache.Delete(path)
+	}
+
+	c.mu.Lock()
+	delete(c.gens, path)
+	c.mu.Unlock()
+}
+
+// add put the entry to the index and evict the least recently used ones,
+// false if the object was changed since the fill started
+func (c *cacheStorage) add(path string, gen uint64, size int64, info FileInfo) bool {
+	c.mu.Lock()
+
+	if cur, ok := c.gens[path]; !ok || cur != gen {
+		c.mu.Unlock()
+		return false
+	}
+
+	delete(c.gens, path)
+
+	if elem, ok := c.entries[path]; ok {
+		c.size -= elem.Value.(*cacheEntry).size
+		c.lru.Remove(elem)
+	}
+
+	c.entries[path] = c.lru.PushFront(&cacheEntry{
+		path:    path,
+		size:    size,
+		info:    info,
+		checked: time.Now(),
+	})
+	c.size += size
+	evicted := []string{}
+
+	for c.policy.MaxBytes > 0 && c.size > c.policy.MaxBytes {
+		ent := c.lru.Remove(c.lru.Back()).(*cacheEntry)
+		delete(c.entries, ent.path)
+		c.size -= ent.size
+		evicted = append(evicted, ent.path)
+	}
+
+	c.mu.Unlock()
+
+	for _, path := range evicted {
+		_ = c.cache.Delete(path)
+	}
+
+	return true
+}
+
+// remove drop the object from the cache and make the fill of the path stale
+func (c *cacheStorage) remove(path string) {
+	c.mu.Lock()
+	elem, ok := c.entries[path]
+
+	if gen, filling := c.gens[path]; filling {
+		c.gens[path] = gen + 1
+	}
+
+	if ok {
+		c.size -= elem.Value.(*cacheEntry).size
+		c.lru.Remove(elem)
+		delete(c.entries, path)
+	}
+
+	c.mu.Unlock()
+
+	if ok {
+		_ = c.cache.Delete(path)
+	}
+}
+
+// removeAll invalidate the cached objects at the path and under it
+func (c *cacheStorage) removeAll(prefix string) {
+	dir := strings.Trim(prefix, "/")
+	paths := []string{}
+
+	c.mu.Lock()
+
+	match := func(path string) bool {
+		key := strings.Trim(path, "/")
+		return len(dir) == 0 || key == dir || strings.HasPrefix(key, dir+"/")
+	}
+
+	for path := range c.entries {
+		if match(path) {
+			paths = append(paths, path)
+		}
+	}
+
+	for path := range c.gens {
+		if _, ok := c.entries[path]; !ok && match(path) {
+			paths = append(paths, path)
+		}
+	}
+
+	c.mu.Unlock()
+
+	for _, path := range paths {
+		c.remove(path)
+	}
+}
+
+// fetch get the object from the storage, the body is written to the cache while it is read
+func (c *cacheStorage) fetch(ctx context.Context, path string) (io.ReadCloser, error) {
+	gen, ok := c.fill(path)
+
+	if !ok {
+		return c.store.GetWithContext(ctx, path)
+	}
+
+	info, err := c.store.Stat(path)
+
+	if err != nil {
+		c.unfill(path, false)
+		return nil, err
+	}
+
+	body, err := c.store.GetWithContext(ctx, path)
+
+	if err != nil || (c.policy.MaxBytes > 0 && info.Size() > c.policy.MaxBytes) {
+		c.unfill(path, false)
+		return body, err
+	}
+
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+
+	go func() {
+		err := c.cache.PutWithContext(ctx, path, pr)
+
+		if err != nil {
+			_ = pr.CloseWithError(err)
+		} else {
+			_ = pr.Close()
+		}
+
+		done <- err
+	}()
+
+	return &cacheReader{
+		cache: c,
+		path:  path,
+		gen:   gen,
+		info:  info,
+		body:  body,
+		pw:    pw,
+		done:  done,
+	}, nil
+}
+
+// errCacheIncomplete body was closed before it was read till the end
+var errCacheIncomplete = errors.New("cache: body is not read till the end")
+
+// cacheReader writes the body to the cache while it is read,
+// the object is added to the cache only when the body is read till the end
+type cacheReader struct {
+	cache *cacheStorage
+	path  string
+	gen   uint64
+	info  FileInfo
+	body  io.ReadCloser
+	pw    *io.PipeWriter
+	done  chan error
+	size  int64
+}
+
+// Read get next part of the body and write it to the cache
+func (r *cacheReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+
+	if n > 0 && r.pw != nil {
+		if _, werr := r.pw.Write(p[:n]); werr != nil {
+			r.finish(werr)
+		}
+
+		r.size += int64(n)
+	}
+
+	if errors.Is(err, io.EOF) && r.pw != nil {
+		r.finish(nil)
+	}
+
+	return n, err
+}
+
+// Close stop caching of the body that is not read till the end and close it
+func (r *cacheReader) Close() error {
+	if r.pw != nil {
+		r.finish(errCacheIncomplete)
+	}
+
+	return r.body.Close()
+}
+
+// finish wait for the cache write, the object is added to the cache when the body is complete and did not change meanwhile
+func (r *cacheReader) finish(err error) {
+	if err == nil {
+		_ = r.pw.Close()
+	} else {
+		_ = r.pw.CloseWithError(err)
+	}
+
+	perr := <-r.done
+	r.pw = nil
+
+	if err == nil && perr == nil && r.cache.add(r.path, r.gen, r.size, r.info) {
+		return
+	}
+
+	r.cache.unfill(r.path, true)
+}
+
+// List get the contents of the path, is not cached
+func (c *cacheStorage) List(path string, options ...map[string]interface{}) ([]string, error) {
+	return c.store.List(path, options...)
+}
+
+// ListWithContext get the contents of the path, is not cached
+func (c *cacheStorage) ListWithContext(ctx context.Context, path string, options ...map[string]interface{}) ([]string, error) {
+	return c.store.ListWithContext(ctx, path, options...)
+}
+
+// ListIter paginated listing of the path, is not cached
+func (c *cacheStorage) ListIter(ctx context.Context, path string, options ...map[string]interface{}) Iterator {
+	lister, ok := c.store.(ListIterator)
+
+	if !ok {
+		return &errIterator{ErrNotSupported}
+	}
+
+	return lister.ListIter(ctx, path, options...)
+}
+
+// Walk recursively look for files in directory, is not cached
+func (c *cacheStorage) Walk(path string, callback func(path string)) error {
+	return c.store.Walk(path, callback)
+}
+
+// WalkWithContext recursively look for files in directory, is not cached
+func (c *cacheStorage) WalkWithContext(ctx context.Context, path string, callback func(path string)) error {
+	return c.store.WalkWithContext(ctx, path, callback)
+}
+
+// WalkFunc recursively look for files and directories, is not cached
+func (c *cacheStorage) WalkFunc(ctx context.Context, path string, callback func(entry *Entry) error) error {
+	walker, ok := c.store.(WalkerFunc)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	return walker.WalkFunc(ctx, path, callback)
+}
+
+// Copy copies an object and invalidates the destination
+func (c *cacheStorage) Copy(src string, dst string, options ...map[string]interface{}) error {
+	defer c.remove(dst)
+
+	return c.store.Copy(src, dst, options...)
+}
+
+// CopyWithContext copies an object and invalidates the destination
+func (c *cacheStorage) CopyWithContext(ctx context.Context, src string, dst string, options ...map[string]interface{}) error {
+	defer c.remove(dst)
+
+	return c.store.CopyWithContext(ctx, src, dst, options...)
+}
+
+// Move moves an object and invalidate both paths in the cache
+func (c *cacheStorage) Move(ctx context.Context, src string, dst string) error {
+	defer c.remove(src)
+	defer c.remove(dst)
+
+	return Move(ctx, c.store, src, dst)
+}
+
+// Create create new file or open current and truncate, invalidates the object
+func (c *cacheStorage) Create(path string) (io.ReadWriteCloser, error) {
+	defer c.remove(path)
+
+	return c.store.Create(path)
+}
+
+// CreateWithContext create new file or open current and truncate, invalidates the object
+func (c *cacheStorage) CreateWithContext(ctx context.Context, path string) (io.ReadWriteCloser, error) {
+	creator, ok := c.store.(CreatorWithContext)
+
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	defer c.remove(path)
+
+	return creator.CreateWithContext(ctx, path)
+}
+
+// Get get object from the cache or from the storage
+func (c *cacheStorage) Get(path string) (io.ReadCloser, error) {
+	return c.GetWithContext(context.Background(), path)
+}
+
+// GetWithContext get object from the cache or from the storage
+func (c *cacheStorage) GetWithContext(ctx context.Context, path string) (io.ReadCloser, error) {
+	if _, ok := c.lookup(path); ok {
+		body, err := c.cache.GetWithContext(ctx, path)
+
+		if err == nil {
+			return body, nil
+		}
+
+		c.remove(path)
+	}
+
+	return c.fetch(ctx, path)
+}
+
+// GetRange get part of the object from storage, is not cached
+func (c *cacheStorage) GetRange(ctx context.Context, path string, offset int64, length int64) (io.ReadCloser, error) {
+	getter, ok := c.store.(RangeGetter)
+
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return getter.GetRange(ctx, path, offset, length)
+}
+
+// Put object into storage and invalidate the cached one
+func (c *cacheStorage) Put(path string, body io.Reader) error {
+	defer c.remove(path)
+
+	return c.store.Put(path, body)
+}
+
+// PutWithContext object into storage and invalidate the cached one
+func (c *cacheStorage) PutWithContext(ctx context.Context, path string, body io.Reader) error {
+	defer c.remove(path)
+
+	return c.store.PutWithContext(ctx, path, body)
+}
+
+// PutWithOptions object into storage and invalidate the cached one
+func (c *cacheStorage) PutWithOptions(ctx context.Context, path string, body io.Reader, options *PutOptions) error {
+	putter, ok := c.store.(PutterWithOptions)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	defer c.remove(path)
+
+	return putter.PutWithOptions(ctx, path, body, options)
+}
+
+// SetMetadata replace user metadata of the object and invalidate the cached one
+func (c *cacheStorage) SetMetadata(ctx context.Context, path string, metadata map[string]string) error {
+	setter, ok := c.store.(MetadataSetter)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	defer c.remove(path)
+
+	return setter.SetMetadata(ctx, path, metadata)
+}
+
+// Link generate expiration link for storage
+func (c *cacheStorage) Link(path string, expire time.Duration) (string, error) {
+	return c.store.Link(path, expire)
+}
+
+// Delete remove object from storage and from the cache
+func (c *cacheStorage) Delete(path string) error {
+	defer c.remove(path)
+
+	return c.store.Delete(path)
+}
+
+// DeleteWithContext remove object from storage and from the cache
+func (c *cacheStorage) DeleteWithContext(ctx context.Context, path string) error {
+	defer c.remove(path)
+
+	return c.store.DeleteWithContext(ctx, path)
+}
+
+// DeleteMany deletes the objects from storage and from the cache
+func (c *cacheStorage) DeleteMany(ctx context.Context, paths []string) error {
+	defer func() {
+		for _, path := range paths {
+			c.remove(path)
+		}
+	}()
+
+	return DeleteMany(ctx, c.store, paths)
+}
+
+// DeleteAll deletes the object at the path and every object under it from storage and from the cache
+func (c *cacheStorage) DeleteAll(ctx context.Context, prefix string) error {
+	defer c.removeAll(prefix)
+
+	return DeleteAll(ctx, c.store, prefix)
+}
+
+// Stat get file information, cached together with the object
+func (c *cacheStorage) Stat(path string) (FileInfo, error) {
+	if info, ok := c.lookup(path); ok {
+		return info, nil
+	}
+
+	return c.store.Stat(path)
+}
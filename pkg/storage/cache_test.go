@@ -0,0 +1,289 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/dev-toolkit/lib/mem"
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+var cacheTestData = []byte("hello storage")
+
+// countingStorage counts the downloads
+type countingStorage struct {
+	*mem.Storage
+	gets int
+}
+
+func (s *countingStorage) GetWithContext(ctx context.Context, path string) (io.ReadCloser, error) {
+	s.gets++
+	return s.Storage.GetWithContext(ctx, path)
+}
+
+// streamingOrigin serves a big generated object and counts the bytes that were read from it
+type streamingOrigin struct {
+	storage.Mock
+	size int64
+	read int64
+}
+
+func (s *streamingOrigin) Stat(path string) (storage.FileInfo, error) {
+	return &streamingInfo{size: s.size}, nil
+}
+
+func (s *streamingOrigin) GetWithContext(ctx context.Context, path string) (io.ReadCloser, error) {
+	return io.NopCloser(&countingReader{io.LimitReader(zeroReader{}, s.size), &s.read}), nil
+}
+
+// streamingInfo file information of the generated object
+type streamingInfo struct {
+	storage.FileInfoMock
+	size int64
+}
+
+func (i *streamingInfo) Size() int64 {
+	return i.size
+}
+
+type countingReader struct {
+	r    io.Reader
+	read *int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	atomic.AddInt64(r.read, int64(n))
+	return n, err
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+
+	return len(p), nil
+}
+
+// streamingCache records how much of the origin was read when the cache write started
+type streamingCache struct {
+	*mem.Storage
+	origin *streamingOrigin
+	read   int64
+}
+
+func (s *streamingCache) PutWithContext(ctx context.Context, path string, body io.Reader) error {
+	first := make([]byte, 1)
+
+	if _, err := io.ReadFull(body, first); err != nil {
+		return err
+	}
+
+	s.read = atomic.LoadInt64(&s.origin.read)
+
+	return s.Storage.PutWithContext(ctx, path, io.MultiReader(bytes.NewReader(first), body))
+}
+
+func TestWithCache(t *testing.T) {
+	assert := assert.New(t)
+	size := int64(len(cacheTestData))
+
+	setup := func(policy storage.CachePolicy) (*countingStorage, *mem.Storage, storage.Storage) {
+		origin := &countingStorage{Storage: mem.NewStorage()}
+		cache := mem.NewStorage()
+
+		for _, path := range []string{"a.txt", "b.txt", "c.txt"} {
+			assert.NoError(origin.Put(path, bytes.NewReader(cacheTestData)))
+		}
+
+		return origin, cache, storage.WithCache(origin, cache, policy)
+	}
+
+	read := func(store storage.Storage, path string) []byte {
+		body, err := store.Get(path)
+		assert.NoError(err)
+
+		if err != nil {
+			return nil
+		}
+
+		defer body.Close()
+		data, err := io.ReadAll(body)
+		assert.NoError(err)
+
+		return data
+	}
+
+	cached := func(cache *mem.Storage, path string) bool {
+		_, err := cache.Stat(path)
+		return err == nil
+	}
+
+	t.Run("serve from the cache", func(t *testing.T) {
+		origin, cache, store := setup(storage.CachePolicy{TTL: time.Hour})
+
+		assert.Equal(cacheTestData, read(store, "a.txt"))
+		assert.Equal(cacheTestData, read(store, "a.txt"))
+		assert.Equal(1, origin.gets)
+		assert.True(cached(cache, "a.txt"))
+
+		info, err := store.Stat("a.txt")
+		assert.NoError(err)
+		assert.Equal(size, info.Size())
+	})
+
+	t.Run("serve stale object until ttl is over", func(t *testing.T) {
+		origin, _, store := setup(storage.CachePolicy{TTL: time.Hour})
+
+		assert.Equal(cacheTestData, read(store, "a.txt"))
+		assert.NoError(origin.Put("a.txt", bytes.NewReader([]byte("changed"))))
+		assert.Equal(cacheTestData, read(store, "a.txt"))
+	})
+
+	t.Run("revalidate with etag", func(t *testing.T) {
+		origin, _, store := setup(storage.CachePolicy{})
+
+		assert.Equal(cacheTestData, read(store, "a.txt"))
+		assert.Equal(cacheTestData, read(store, "a.txt"))
+		assert.Equal(1, origin.gets)
+
+		assert.NoError(origin.Put("a.txt", bytes.NewReader([]byte("changed"))))
+		assert.Equal([]byte("changed"), read(store, "a.txt"))
+		assert.Equal(2, origin.gets)
+	})
+
+	t.Run("invalidate on writes", func(t *testing.T) {
+		_, cache, store := setup(storage.CachePolicy{TTL: time.Hour})
+
+		read(store, "a.txt")
+		assert.NoError(store.Put("a.txt", bytes.NewReader([]byte("put"))))
+		assert.False(cached(cache, "a.txt"))
+		assert.Equal([]byte("put"), read(store, "a.txt"))
+
+		read(store, "b.txt")
+		assert.NoError(store.Copy("a.txt", "b.txt"))
+		assert.Equal([]byte("put"), read(store, "b.txt"))
+
+		read(store, "c.txt")
+		assert.NoError(store.Delete("c.txt"))
+		assert.False(cached(cache, "c.txt"))
+		_, err := store.Get("c.txt")
+		assert.ErrorIs(err, storage.ErrNotExist)
+	})
+
+	t.Run("evict least recently used", func(t *testing.T) {
+		_, cache, store := setup(storage.CachePolicy{TTL: time.Hour, MaxBytes: size * 2})
+
+		read(store, "a.txt")
+		read(store, "b.txt")
+		read(store, "a.txt")
+		read(store, "c.txt")
+
+		assert.True(cached(cache, "a.txt"))
+		assert.False(cached(cache, "b.txt"))
+		assert.True(cached(cache, "c.txt"))
+	})
+
+	t.Run("do not cache big objects", func(t *testing.T) {
+		origin, cache, store := setup(storage.CachePolicy{TTL: time.Hour, MaxBytes: size - 1})
+
+		assert.Equal(cacheTestData, read(store, "a.txt"))
+		assert.Equal(cacheTestData, read(store, "a.txt"))
+		assert.False(cached(cache, "a.txt"))
+		assert.Equal(2, origin.gets)
+	})
+
+	t.Run("stream big objects to the cache", func(t *testing.T) {
+		origin := &streamingOrigin{size: 16 << 20}
+		cache := &streamingCache{Storage: mem.NewStorage(), origin: origin}
+		store := storage.WithCache(origin, cache, storage.CachePolicy{TTL: time.Hour})
+
+		body, err := store.Get("big.bin")
+		assert.NoError(err)
+
+		n, err := io.Copy(io.Discard, body)
+		assert.NoError(err)
+		assert.NoError(body.Close())
+		assert.Equal(origin.size, n)
+		assert.Less(cache.read, origin.size)
+
+		info, err := cache.Stat("big.bin")
+		assert.NoError(err)
+		assert.Equal(origin.size, info.Size())
+	})
+
+	t.Run("do not cache partially read objects", func(t *testing.T) {
+		_, cache, store := setup(storage.CachePolicy{TTL: time.Hour})
+
+		body, err := store.Get("a.txt")
+		assert.NoError(err)
+
+		_, err = body.Read(make([]byte, 2))
+		assert.NoError(err)
+		assert.NoError(body.Close())
+		assert.False(cached(cache, "a.txt"))
+	})
+
+	t.Run("do not cache objects changed while they are read", func(t *testing.T) {
+		_, cache, store := setup(storage.CachePolicy{TTL: time.Hour})
+
+		body, err := store.Get("a.txt")
+		assert.NoError(err)
+
+		_, err = body.Read(make([]byte, 2))
+		assert.NoError(err)
+		assert.NoError(store.Put("a.txt", bytes.NewReader([]byte("put"))))
+
+		_, err = io.ReadAll(body)
+		assert.NoError(err)
+		assert.NoError(body.Close())
+		assert.False(cached(cache, "a.txt"))
+		assert.Equal([]byte("put"), read(store, "a.txt"))
+		assert.Equal([]byte("put"), read(store, "a.txt"))
+	})
+
+	t.Run("concurrent reads and writes", func(t *testing.T) {
+		store := storage.WithCache(mem.NewStorage(), mem.NewStorage(), storage.CachePolicy{TTL: time.Hour})
+		assert.NoError(store.Put("a.txt", bytes.NewReader([]byte("0"))))
+		wg := new(sync.WaitGroup)
+
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				for j := 0; j < 100; j++ {
+					if body, err := store.Get("a.txt"); err == nil {
+						_, _ = io.ReadAll(body)
+						_ = body.Close()
+					}
+				}
+			}()
+		}
+
+		for i := 1; i <= 100; i++ {
+			assert.NoError(store.Put("a.txt", bytes.NewReader([]byte(strconv.Itoa(i)))))
+		}
+
+		wg.Wait()
+		assert.Equal([]byte("100"), read(store, "a.txt"))
+	})
+
+	t.Run("missing objects", func(t *testing.T) {
+		_, cache, store := setup(storage.CachePolicy{TTL: time.Hour})
+
+		_, err := store.Get("missing.txt")
+		assert.ErrorIs(err, storage.ErrNotExist)
+		assert.False(cached(cache, "missing.txt"))
+	})
+}
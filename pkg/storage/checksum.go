@@ -0,0 +1,522 @@
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/textproto"
+	"time"
+)
+
+// ErrChecksumMismatch downloaded object does not match the checksum stored on upload
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ErrUnknownChecksum checksum algorithm is not supported
+var ErrUnknownChecksum = errors.New("unknown checksum algorithm")
+
+// ChecksumAlgorithm hash function of the object checksums
+type ChecksumAlgorithm string
+
+const (
+	// ChecksumMD5 md5 checksum
+	ChecksumMD5 ChecksumAlgorithm = "md5"
+
+	// ChecksumSHA256 sha-256 checksum
+	ChecksumSHA256 ChecksumAlgorithm = "sha256"
+
+	// ChecksumCRC32C crc-32 checksum with Castagnoli polynomial
+	ChecksumCRC32C ChecksumAlgorithm = "crc32c"
+)
+
+// Key get metadata key of the checksum in canonical header form, for example "Checksum-Sha256"
+func (a ChecksumAlgorithm) Key() string {
+	return textproto.CanonicalMIMEHeaderKey("checksum-" + string(a))
+}
+
+// New create the hash of the algorithm
+func (a ChecksumAlgorithm) New() (hash.Hash, error) {
+	switch a {
+	case ChecksumMD5:
+		return md5.New(), nil
+	case ChecksumSHA256:
+		return sha256.New(), nil
+	case ChecksumCRC32C:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli)), nil
+	}
+
+	return nil, fmt.Errorf("%w: '%s'", ErrUnknownChecksum, a)
+}
+
+// checksummer file information that keeps the checksum outside of the metadata
+type checksummer interface {
+	checksum(algorithm ChecksumAlgorithm) (string, bool)
+}
+
+// Checksum get hex encoded checksum that was stored with the object, false if the object has no such checksum
+func Checksum(info FileInfo, algorithm ChecksumAlgorithm) (string, bool) {
+	if info, ok := info.(checksummer); ok {
+		return info.checksum(algorithm)
+	}
+
+	if value, ok := info.Metadata()[algorithm.Key()]; ok && value != nil {
+		return *value, true
+	}
+
+	return "", false
+}
+
+// NewChecksumReader verify the body while it is read, the read that reaches EOF and Close
+// return ErrChecksumMismatch if the body does not match the hex encoded checksum.
+// Body that is closed before EOF is not verified.
+func NewChecksumReader(body io.ReadCloser, algorithm ChecksumAlgorithm, checksum string) (io.ReadCloser, error) {
+	hsh, err := algorithm.New()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &checksumReader{
+		body:      body,
+		hash:      hsh,
+		algorithm: algorithm,
+		checksum:  checksum,
+	}, nil
+}
+
+// WithChecksum compute checksums while the objects are uploaded with Put and Create and store them in the metadata
+// under algorithm Key, Get verifies them while the body is read, see NewChecksumReader.
+// Seekable bodies (io.ReadSeeker) are hashed before the upload and the checksum is uploaded together with the object
+// when the storage implements PutterWithOptions. Other bodies are hashed while they are uploaded and the checksum is saved
+// after the upload with MetadataSetter, so the upload costs a second write. When the checksum can't be saved
+// the object is kept without it and the error is returned. Objects without the checksum and GetRange are not verified. Stat hides the checksum from Metadata, use Checksum to get it.
+func WithChecksum(store Storage, algorithm ChecksumAlgorithm) Storage {
+	return &checksumStorage{
+		store:     store,
+		algorithm: algorithm,
+	}
+}
+
+// checksumStorage keeps checksums of the objects in the metadata
+type checksumStorage struct {
+	store     Storage
+	algorithm ChecksumAlgorithm
+}
+
+// metadata get copy of the user metadata with the checksum
+func (c *checksumStorage) metadata(metadata map[string]string, hsh hash.Hash) map[string]string {
+	meta := map[string]string{}
+
+	for name, value := range metadata {
+		meta[name] = value
+	}
+
+	meta[c.algorithm.Key()] = hex.EncodeToString(hsh.Sum(nil))
+
+	return meta
+}
+
+// save store the checksum together with the user metadata. The object is already uploaded at this point,
+// when the checksum can't be saved the object stays without it.
+func (c *checksumStorage) save(ctx context.Context, setter MetadataSetter, path string, hsh hash.Hash, metadata map[string]string) error {
+	if err := setter.SetMetadata(ctx, path, c.metadata(metadata, hsh)); err != nil {
+		return fmt.Errorf("save %s checksum of '%s', the object is stored without it: %w", c.algorithm, path, err)
+	}
+
+	return nil
+}
+
+// put upload the body with the checksum, seekable bodies are hashed first and uploaded with the checksum in one write
+func (c *checksumStorage) put(ctx context.Context, path string, body io.Reader, options *PutOptions) error {
+	hsh, err := c.algorithm.New()
+
+	if err != nil {
+		return err
+	}
+
+	putter, ok := c.store.(PutterWithOptions)
+
+	if seeker, seekable := body.(io.ReadSeeker); seekable && ok {
+		if err := hashSeeker(seeker, hsh); err != nil {
+			return err
+		}
+
+		opts := PutOptions{}
+
+		if options != nil {
+			opts = *options
+		}
+
+		opts.Metadata = c.metadata(opts.Metadata, hsh)
+
+		return putter.PutWithOptions(ctx, path, seeker, &opts)
+	}
+
+	setter, ok := c.store.(MetadataSetter)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	tee := io.TeeReader(body, hsh)
+
+	if options == nil {
+		err = c.store.PutWithContext(ctx, path, tee)
+	} else {
+		err = putter.PutWithOptions(ctx, path, tee, options)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if options == nil {
+		return c.save(ctx, setter, path, hsh, nil)
+	}
+
+	return c.save(ctx, setter, path, hsh, options.Metadata)
+}
+
+// hashSeeker hash the rest of the body and seek back to where it was
+func hashSeeker(seeker io.ReadSeeker, hsh hash.Hash) error {
+	start, err := seeker.Seek(0, io.SeekCurrent)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(hsh, seeker); err != nil {
+		return err
+	}
+
+	_, err = seeker.Seek(start, io.SeekStart)
+
+	return err
+}
+
+// List get the contents of the path
+func (c *checksumStorage) List(path string, options ...map[string]interface{}) ([]string, error) {
+	return c.store.List(path, options...)
+}
+
+// ListWithContext get the contents of the path
+func (c *checksumStorage) ListWithContext(ctx context.Context, path string, options ...map[string]interface{}) ([]string, error) {
+	return c.store.ListWithContext(ctx, path, options...)
+}
+
+// ListIter paginated listing of the path
+func (c *checksumStorage) ListIter(ctx context.Context, path string, options ...map[string]interface{}) Iterator {
+	lister, ok := c.store.(ListIterator)
+
+	if !ok {
+		return &errIterator{ErrNotSupported}
+	}
+
+	return lister.ListIter(ctx, path, options...)
+}
+
+// Walk recursively look for files in directory
+func (c *checksumStorage) Walk(path string, callback func(path string)) error {
+	return c.store.Walk(path, callback)
+}
+
+// WalkWithContext recursively look for files in directory
+func (c *checksumStorage) WalkWithContext(ctx context.Context, path string, callback func(path string)) error {
+	return c.store.WalkWithContext(ctx, path, callback)
+}
+
+// WalkFunc recursively look for files and directories, callback gets the entry details
+func (c *checksumStorage) WalkFunc(ctx context.Context, path string, callback func(entry *Entry) error) error {
+	walker, ok := c.store.(WalkerFunc)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	return walker.WalkFunc(ctx, path, callback)
+}
+
+// Copy copies an object, the checksum is copied with the metadata
+func (c *checksumStorage) Copy(src string, dst string, options ...map[string]interface{}) error {
+	return c.store.Copy(src, dst, options...)
+}
+
+// CopyWithContext copies an object, the checksum is copied with the metadata
+func (c *checksumStorage) CopyWithContext(ctx context.Context, src string, dst string, options ...map[string]interface{}) error {
+	return c.store.CopyWithContext(ctx, src, dst, options...)
+}
+
+// Move moves an object, the checksum is moved together with the metadata
+func (c *checksumStorage) Move(ctx context.Context, src string, dst string) error {
+	return Move(ctx, c.store, src, dst)
+}
+
+// Create create new file, the checksum is computed while it is written and saved on Close
+func (c *checksumStorage) Create(path string) (io.ReadWriteCloser, error) {
+	return c.CreateWithContext(context.Background(), path)
+}
+
+// CreateWithContext create new file, the checksum is computed while it is written and saved on Close
+func (c *checksumStorage) CreateWithContext(ctx context.Context, path string) (io.ReadWriteCloser, error) {
+	setter, ok := c.store.(MetadataSetter)
+
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	hsh, err := c.algorithm.New()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var file io.ReadWriteCloser
+
+	if creator, ok := c.store.(CreatorWithContext); ok {
+		file, err = creator.CreateWithContext(ctx, path)
+	} else {
+		file, err = c.store.Create(path)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &checksumFile{
+		ReadWriteCloser: file,
+		hash:            hsh,
+		save: func() error {
+			return c.save(ctx, setter, path, hsh, nil)
+		},
+	}, nil
+}
+
+// Get get object from storage, the body is verified while it is read
+func (c *checksumStorage) Get(path string) (io.ReadCloser, error) {
+	return c.GetWithContext(context.Background(), path)
+}
+
+// GetWithContext get object from storage, the body is verified while it is read
+func (c *checksumStorage) GetWithContext(ctx context.Context, path string) (io.ReadCloser, error) {
+	info, err := c.store.Stat(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.store.GetWithContext(ctx, path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	checksum, ok := Checksum(info, c.algorithm)
+
+	if !ok {
+		return body, nil
+	}
+
+	return NewChecksumReader(body, c.algorithm, checksum)
+}
+
+// GetRange get part of the object from storage, is not verified
+func (c *checksumStorage) GetRange(ctx context.Context, path string, offset int64, length int64) (io.ReadCloser, error) {
+	getter, ok := c.store.(RangeGetter)
+
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return getter.GetRange(ctx, path, offset, length)
+}
+
+// Put object into storage together with its checksum
+func (c *checksumStorage) Put(path string, body io.Reader) error {
+	return c.PutWithContext(context.Background(), path, body)
+}
+
+// PutWithContext object into storage together with its checksum
+func (c *checksumStorage) PutWithContext(ctx context.Context, path string, body io.Reader) error {
+	return c.put(ctx, path, body, nil)
+}
+
+// PutWithOptions object into storage together with its attributes and checksum
+func (c *checksumStorage) PutWithOptions(ctx context.Context, path string, body io.Reader, options *PutOptions) error {
+	if _, ok := c.store.(PutterWithOptions); !ok {
+		return ErrNotSupported
+	}
+
+	if options == nil {
+		options = new(PutOptions)
+	}
+
+	return c.put(ctx, path, body, options)
+}
+
+// SetMetadata replace user metadata of the object, the checksum stays
+func (c *checksumStorage) SetMetadata(ctx context.Context, path string, metadata map[string]string) error {
+	setter, ok := c.store.(MetadataSetter)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	info, err := c.store.Stat(path)
+
+	if err != nil {
+		return err
+	}
+
+	meta := map[string]string{}
+
+	for name, value := range metadata {
+		meta[name] = value
+	}
+
+	if checksum, ok := Checksum(info, c.algorithm); ok {
+		meta[c.algorithm.Key()] = checksum
+	}
+
+	return setter.SetMetadata(ctx, path, meta)
+}
+
+// Link generate expiration link for storage
+func (c *checksumStorage) Link(path string, expire time.Duration) (string, error) {
+	return c.store.Link(path, expire)
+}
+
+// Delete remove object from storage
+func (c *checksumStorage) Delete(path string) error {
+	return c.store.Delete(path)
+}
+
+// DeleteWithContext remove object from storage
+func (c *checksumStorage) DeleteWithContext(ctx context.Context, path string) error {
+	return c.store.DeleteWithContext(ctx, path)
+}
+
+// DeleteMany deletes the objects
+func (c *checksumStorage) DeleteMany(ctx context.Context, paths []string) error {
+	return DeleteMany(ctx, c.store, paths)
+}
+
+// DeleteAll deletes the object at the path and every object under it
+func (c *checksumStorage) DeleteAll(ctx context.Context, prefix string) error {
+	return DeleteAll(ctx, c.store, prefix)
+}
+
+// Stat get file information, the checksum is available through Checksum
+func (c *checksumStorage) Stat(path string) (FileInfo, error) {
+	info, err := c.store.Stat(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &checksumInfo{info, c.algorithm}, nil
+}
+
+// Retryable classify the error by the wrapped storage
+func (c *checksumStorage) Retryable(err error) bool {
+	if classifier, ok := c.store.(RetryClassifier); ok {
+		return classifier.Retryable(err)
+	}
+
+	return Retryable(err)
+}
+
+// checksumInfo file information with the checksum hidden from the metadata
+type checksumInfo struct {
+	FileInfo
+	algorithm ChecksumAlgorithm
+}
+
+// Metadata get user metadata without the checksum
+func (i *checksumInfo) Metadata() map[string]*string {
+	metadata := map[string]*string{}
+
+	for name, value := range i.FileInfo.Metadata() {
+		if name != i.algorithm.Key() {
+			metadata[name] = value
+		}
+	}
+
+	return metadata
+}
+
+func (i *checksumInfo) checksum(algorithm ChecksumAlgorithm) (string, bool) {
+	return Checksum(i.FileInfo, algorithm)
+}
+
+// checksumReader verifies the body when it's read till the end
+type checksumReader struct {
+	body      io.ReadCloser
+	hash      hash.Hash
+	algorithm ChecksumAlgorithm
+	checksum  string
+	err       error
+}
+
+// Read read the body and verify the checksum at EOF
+func (r *checksumReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	n, err := r.body.Read(p)
+	_, _ = r.hash.Write(p[:n])
+
+	if errors.Is(err, io.EOF) {
+		if sum := hex.EncodeToString(r.hash.Sum(nil)); sum != r.checksum {
+			r.err = fmt.Errorf("%w: %s is '%s' instead of '%s'", ErrChecksumMismatch, r.algorithm, sum, r.checksum)
+			return n, r.err
+		}
+	}
+
+	return n, err
+}
+
+// Close close the body, returns ErrChecksumMismatch if the body did not match
+func (r *checksumReader) Close() error {
+	if err := r.body.Close(); err != nil {
+		return err
+	}
+
+	return r.err
+}
+
+// checksumFile computes the checksum while the file is written
+type checksumFile struct {
+	io.ReadWriteCloser
+	hash   hash.Hash
+	save   func() error
+	closed bool
+}
+
+// Write write the data and add it to the checksum
+func (f *checksumFile) Write(p []byte) (int, error) {
+	n, err := f.ReadWriteCloser.Write(p)
+	_, _ = f.hash.Write(p[:n])
+
+	return n, err
+}
+
+// Close close the file and save the checksum
+func (f *checksumFile) Close() error {
+	if f.closed {
+		return nil
+	}
+
+	f.closed = true
+
+	if err := f.ReadWriteCloser.Close(); err != nil {
+		return err
+	}
+
+	return f.save()
+}
@@ -0,0 +1,203 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"io"
+	"testing"
+
+	"github.com/protsack-stephan/dev-toolkit/lib/mem"
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+var checksumTestData = []byte("hello checksum")
+
+var errChecksumTestFailed = errors.New("metadata is not saved")
+
+// metadataFailingStorage uploads the objects, but can't save the metadata
+type metadataFailingStorage struct {
+	*mem.Storage
+}
+
+func (s *metadataFailingStorage) SetMetadata(ctx context.Context, path string, metadata map[string]string) error {
+	return errChecksumTestFailed
+}
+
+func TestWithChecksum(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	md5Sum := md5.Sum(checksumTestData)
+	sha256Sum := sha256.Sum256(checksumTestData)
+	crc32cSum := make([]byte, 4)
+	crc := crc32.Checksum(checksumTestData, crc32.MakeTable(crc32.Castagnoli))
+	crc32cSum[0], crc32cSum[1], crc32cSum[2], crc32cSum[3] = byte(crc>>24), byte(crc>>16), byte(crc>>8), byte(crc)
+
+	read := func(store storage.Storage, path string) ([]byte, error, error) {
+		body, err := store.Get(path)
+
+		if err != nil {
+			return nil, err, nil
+		}
+
+		data, err := io.ReadAll(body)
+
+		return data, err, body.Close()
+	}
+
+	for algorithm, sum := range map[storage.ChecksumAlgorithm]string{
+		storage.ChecksumMD5:    hex.EncodeToString(md5Sum[:]),
+		storage.ChecksumSHA256: hex.EncodeToString(sha256Sum[:]),
+		storage.ChecksumCRC32C: hex.EncodeToString(crc32cSum),
+	} {
+		algorithm, sum := algorithm, sum
+
+		t.Run(string(algorithm), func(t *testing.T) {
+			raw := mem.NewStorage()
+			store := storage.WithChecksum(raw, algorithm)
+
+			assert.NoError(store.Put("a.txt", bytes.NewReader(checksumTestData)))
+
+			info, err := raw.Stat("a.txt")
+			assert.NoError(err)
+			assert.Equal(sum, *info.Metadata()[algorithm.Key()])
+
+			info, err = store.Stat("a.txt")
+			assert.NoError(err)
+			assert.Empty(info.Metadata())
+
+			checksum, ok := storage.Checksum(info, algorithm)
+			assert.True(ok)
+			assert.Equal(sum, checksum)
+
+			data, rerr, cerr := read(store, "a.txt")
+			assert.NoError(rerr)
+			assert.NoError(cerr)
+			assert.Equal(checksumTestData, data)
+		})
+	}
+
+	t.Run("metadata key", func(t *testing.T) {
+		assert.Equal("Checksum-Sha256", storage.ChecksumSHA256.Key())
+		assert.Equal("Checksum-Crc32c", storage.ChecksumCRC32C.Key())
+	})
+
+	t.Run("detect corrupted object", func(t *testing.T) {
+		raw := mem.NewStorage()
+		store := storage.WithChecksum(raw, storage.ChecksumSHA256)
+		assert.NoError(store.Put("a.txt", bytes.NewReader(checksumTestData)))
+
+		info, err := raw.Stat("a.txt")
+		assert.NoError(err)
+		assert.NoError(raw.PutWithOptions(ctx, "a.txt", bytes.NewReader([]byte("hello checksun")), &storage.PutOptions{
+			Metadata: map[string]string{"Checksum-Sha256": *info.Metadata()["Checksum-Sha256"]},
+		}))
+
+		_, rerr, cerr := read(store, "a.txt")
+		assert.ErrorIs(rerr, storage.ErrChecksumMismatch)
+		assert.ErrorIs(cerr, storage.ErrChecksumMismatch)
+	})
+
+	t.Run("create with checksum", func(t *testing.T) {
+		store := storage.WithChecksum(mem.NewStorage(), storage.ChecksumSHA256)
+
+		file, err := store.Create("a.txt")
+		assert.NoError(err)
+		_, err = file.Write(checksumTestData)
+		assert.NoError(err)
+		assert.NoError(file.Close())
+
+		info, err := store.Stat("a.txt")
+		assert.NoError(err)
+		checksum, ok := storage.Checksum(info, storage.ChecksumSHA256)
+		assert.True(ok)
+		assert.Equal(hex.EncodeToString(sha256Sum[:]), checksum)
+	})
+
+	t.Run("keep checksum with metadata", func(t *testing.T) {
+		store := storage.WithChecksum(mem.NewStorage(), storage.ChecksumSHA256)
+		putter := store.(storage.PutterWithOptions)
+
+		assert.NoError(putter.PutWithOptions(ctx, "a.txt", bytes.NewReader(checksumTestData), &storage.PutOptions{
+			Metadata: map[string]string{"Author": "test"},
+		}))
+		assert.NoError(store.(storage.MetadataSetter).SetMetadata(ctx, "a.txt", map[string]string{"Reviewer": "test"}))
+
+		info, err := store.Stat("a.txt")
+		assert.NoError(err)
+		assert.Len(info.Metadata(), 1)
+		assert.Contains(info.Metadata(), "Reviewer")
+
+		_, ok := storage.Checksum(info, storage.ChecksumSHA256)
+		assert.True(ok)
+	})
+
+	t.Run("objects without checksum", func(t *testing.T) {
+		raw := mem.NewStorage()
+		store := storage.WithChecksum(raw, storage.ChecksumSHA256)
+		assert.NoError(raw.Put("a.txt", bytes.NewReader(checksumTestData)))
+
+		data, rerr, cerr := read(store, "a.txt")
+		assert.NoError(rerr)
+		assert.NoError(cerr)
+		assert.Equal(checksumTestData, data)
+
+		info, err := store.Stat("a.txt")
+		assert.NoError(err)
+		_, ok := storage.Checksum(info, storage.ChecksumSHA256)
+		assert.False(ok)
+	})
+
+	t.Run("upload seekable bodies with checksum", func(t *testing.T) {
+		raw := &metadataFailingStorage{mem.NewStorage()}
+		store := storage.WithChecksum(raw, storage.ChecksumSHA256)
+
+		assert.NoError(store.Put("a.txt", bytes.NewReader(checksumTestData)))
+		assert.NoError(store.(storage.PutterWithOptions).PutWithOptions(ctx, "b.txt", bytes.NewReader(checksumTestData), &storage.PutOptions{Metadata: map[string]string{"Reviewer": "test"}}))
+
+		for _, path := range []string{"a.txt", "b.txt"} {
+			info, err := store.Stat(path)
+			assert.NoError(err)
+
+			checksum, ok := storage.Checksum(info, storage.ChecksumSHA256)
+			assert.True(ok)
+			assert.Equal(hex.EncodeToString(sha256Sum[:]), checksum)
+		}
+
+		info, err := store.Stat("b.txt")
+		assert.NoError(err)
+		assert.Equal("test", *info.Metadata()["Reviewer"])
+	})
+
+	t.Run("keep object when checksum is not saved", func(t *testing.T) {
+		raw := &metadataFailingStorage{mem.NewStorage()}
+		store := storage.WithChecksum(raw, storage.ChecksumSHA256)
+		body := func() io.Reader {
+			return io.MultiReader(bytes.NewReader(checksumTestData))
+		}
+
+		assert.NoError(raw.Put("a.txt", bytes.NewReader([]byte("previous"))))
+		assert.ErrorIs(store.Put("a.txt", body()), errChecksumTestFailed)
+		assert.ErrorIs(store.(storage.PutterWithOptions).PutWithOptions(ctx, "b.txt", body(), &storage.PutOptions{}), errChecksumTestFailed)
+
+		for _, path := range []string{"a.txt", "b.txt"} {
+			data, err, cerr := read(store, path)
+			assert.NoError(err)
+			assert.NoError(cerr)
+			assert.Equal(checksumTestData, data)
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		store := storage.WithChecksum(struct{ storage.Storage }{mem.NewStorage()}, storage.ChecksumSHA256)
+		assert.ErrorIs(store.Put("a.txt", bytes.NewReader(checksumTestData)), storage.ErrNotSupported)
+
+		store = storage.WithChecksum(mem.NewStorage(), storage.ChecksumAlgorithm("sha1"))
+		assert.ErrorIs(store.Put("a.txt", bytes.NewReader(checksumTestData)), storage.ErrUnknownChecksum)
+	})
+}
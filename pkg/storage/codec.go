@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec compresses the bodies, Encoding is the Content-Encoding value of the compressed objects
+type Codec interface {
+	Encoding() string
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+var (
+	// Gzip codec with default compression level
+	Gzip Codec = gzipCodec{}
+
+	// Zstd codec with default compression level
+	Zstd Codec = zstdCodec{}
+)
+
+// sizeTrailer codecs that append the uncompressed size to the end of the stream in the form the decoders skip
+type sizeTrailer interface {
+	trailer(size int64) []byte
+	trailerSize() int
+	trailerValue(tail []byte) (int64, bool)
+}
+
+// gzipCodec compress/gzip codec, the size is kept in extra field of the empty last member
+type gzipCodec struct{}
+
+// Encoding get gzip encoding
+func (gzipCodec) Encoding() string {
+	return "gzip"
+}
+
+// NewWriter create gzip writer
+func (gzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+// NewReader create gzip reader, reads all the members of the stream
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+func (gzipCodec) trailer(size int64) []byte {
+	extra := make([]byte, 12)
+	copy(extra, "DS")
+	binary.LittleEndian.PutUint16(extra[2:4], 8)
+	binary.LittleEndian.PutUint64(extra[4:], uint64(size))
+
+	buf := new(bytes.Buffer)
+	zw := gzip.NewWriter(buf)
+	zw.Extra = extra
+	_ = zw.Close()
+
+	return buf.Bytes()
+}
+
+func (c gzipCodec) trailerSize() int {
+	return len(c.trailer(0))
+}
+
+func (c gzipCodec) trailerValue(tail []byte) (int64, bool) {
+	if len(tail) != c.trailerSize() {
+		return 0, false
+	}
+
+	size := int64(binary.LittleEndian.Uint64(tail[16:24]))
+
+	return size, bytes.Equal(tail, c.trailer(size))
+}
+
+// zstdCodec zstd codec, the size is kept in the skippable frame at the end
+type zstdCodec struct{}
+
+// Encoding get zstd encoding
+func (zstdCodec) Encoding() string {
+	return "zstd"
+}
+
+// NewWriter create zstd writer
+func (zstdCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w)
+}
+
+// NewReader create zstd reader
+func (zstdCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &zstdReader{dec}, nil
+}
+
+func (zstdCodec) trailer(size int64) []byte {
+	buf := make([]byte, 20)
+	binary.LittleEndian.PutUint32(buf[0:4], 0x184D2A5E)
+	binary.LittleEndian.PutUint32(buf[4:8], 12)
+	copy(buf[8:12], "DTKS")
+	binary.LittleEndian.PutUint64(buf[12:], uint64(size))
+
+	return buf
+}
+
+func (zstdCodec) trailerSize() int {
+	return 20
+}
+
+func (c zstdCodec) trailerValue(tail []byte) (int64, bool) {
+	if len(tail) != c.trailerSize() {
+		return 0, false
+	}
+
+	size := int64(binary.LittleEndian.Uint64(tail[12:]))
+
+	return size, bytes.Equal(tail, c.trailer(size))
+}
+
+// zstdReader zstd decoder with Close that returns error
+type zstdReader struct {
+	*zstd.Decoder
+}
+
+// Close release the decoder
+func (r *zstdReader) Close() error {
+	r.Decoder.Close()
+	return nil
+}
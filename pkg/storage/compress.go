@@ -0,0 +1,555 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// compressedSignatures beginnings of the formats that are compressed already
+var compressedSignatures = [][]byte{
+	{0x1f, 0x8b},                       // gzip
+	{0x28, 0xb5, 0x2f, 0xfd},           // zstd
+	[]byte("BZh"),                      // bzip2
+	{0xfd, '7', 'z', 'X', 'Z', 0x00},   // xz
+	{0x04, 0x22, 0x4d, 0x18},           // lz4
+	[]byte("PK\x03\x04"),               // zip
+	{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}, // 7z
+	{0xff, 0xd8, 0xff},                 // jpeg
+	[]byte("\x89PNG\r\n\x1a\n"),        // png
+}
+
+// WithCompression compress the bodies of Put and Create with the codec and decompress them on Get.
+// The encoding is recorded as ContentEncoding of the object, so the storage has to implement PutterWithOptions,
+// otherwise the objects are stored as they are. Bodies that are compressed already or come with ContentEncoding
+// in the options are stored as they are too. Objects with the codec encoding are decompressed on Get and GetRange,
+// Stat reports them without ContentEncoding. Gzip and Zstd keep the uncompressed size at the end of the stream,
+// so Stat, ListIter and WalkFunc report it for storages that implement RangeGetter, it costs one range request per object.
+// Listings read the size only for the entries with the codec ContentEncoding, storages that don't report
+// the encoding in listings (s3) list compressed sizes, use Stat to get the uncompressed size.
+func WithCompression(store Storage, codec Codec) Storage {
+	return &compressStorage{
+		store: store,
+		codec: codec,
+	}
+}
+
+// compressStorage compresses objects before they get to the storage
+type compressStorage struct {
+	store Storage
+	codec Codec
+}
+
+// compressed check if the body starts with the signature of the compressed format
+func compressed(body *bufio.Reader) bool {
+	head, _ := body.Peek(8)
+
+	for _, sig := range compressedSignatures {
+		if bytes.HasPrefix(head, sig) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// size get uncompressed size from the end of the object, false if the object has no size trailer
+func (c *compressStorage) size(ctx context.Context, path string, size int64) (int64, bool) {
+	trailer, ok := c.codec.(sizeTrailer)
+
+	if !ok || size < int64(trailer.trailerSize()) {
+		return 0, false
+	}
+
+	getter, ok := c.store.(RangeGetter)
+
+	if !ok {
+		return 0, false
+	}
+
+	body, err := getter.GetRange(ctx, path, size-int64(trailer.trailerSize()), int64(trailer.trailerSize()))
+
+	if err != nil {
+		return 0, false
+	}
+
+	defer body.Close()
+	tail, err := io.ReadAll(body)
+
+	if err != nil {
+		return 0, false
+	}
+
+	return trailer.trailerValue(tail)
+}
+
+// plain get the entry of the decompressed object, the trailer is read only for the entries with the codec encoding
+func (c *compressStorage) plain(ctx context.Context, entry *Entry) *Entry {
+	if entry.IsDir || entry.ContentEncoding != c.codec.Encoding() {
+		return entry
+	}
+
+	plain := *entry
+	plain.ContentEncoding = ""
+
+	if size, ok := c.size(ctx, entry.Path, entry.Size); ok {
+		plain.Size = size
+	}
+
+	return &plain
+}
+
+// encoded check if the object was stored with the codec encoding
+func (c *compressStorage) encoded(info FileInfo) bool {
+	return Content(info).ContentEncoding() == c.codec.Encoding()
+}
+
+// compress get the reader of the compressed body, call stop when the reader is not used anymore
+func (c *compressStorage) compress(body io.Reader) (io.Reader, func()) {
+	pr, pw := io.Pipe()
+
+	go func() {
+		cw, err := newCompressWriter(pw, c.codec)
+
+		if err == nil {
+			if _, err = io.Copy(cw, body); err == nil {
+				err = cw.Close()
+			}
+		}
+
+		_ = pw.CloseWithError(err)
+	}()
+
+	return pr, func() {
+		_ = pr.CloseWithError(io.ErrClosedPipe)
+	}
+}
+
+// put compress the body and record the encoding, bodies that are encoded already are stored as they are
+func (c *compressStorage) put(ctx context.Context, putter PutterWithOptions, path string, body io.Reader, options *PutOptions) error {
+	src := bufio.NewReader(body)
+
+	if options != nil && len(options.ContentEncoding) > 0 {
+		return putter.PutWithOptions(ctx, path, src, options)
+	}
+
+	if compressed(src) {
+		if options == nil {
+			return c.store.PutWithContext(ctx, path, src)
+		}
+
+		return putter.PutWithOptions(ctx, path, src, options)
+	}
+
+	opts := PutOptions{}
+
+	if options != nil {
+		opts = *options
+	}
+
+	opts.ContentEncoding = c.codec.Encoding()
+	enc, stop := c.compress(src)
+	defer stop()
+
+	return putter.PutWithOptions(ctx, path, enc, &opts)
+}
+
+// List get the contents of the path
+func (c *compressStorage) List(path string, options ...map[string]interface{}) ([]string, error) {
+	return c.store.List(path, options...)
+}
+
+// ListWithContext get the contents of the path
+func (c *compressStorage) ListWithContext(ctx context.Context, path string, options ...map[string]interface{}) ([]string, error) {
+	return c.store.ListWithContext(ctx, path, options...)
+}
+
+// ListIter paginated listing of the path, entries with the codec encoding have uncompressed sizes
+func (c *compressStorage) ListIter(ctx context.Context, path string, options ...map[string]interface{}) Iterator {
+	lister, ok := c.store.(ListIterator)
+
+	if !ok {
+		return &errIterator{ErrNotSupported}
+	}
+
+	return &compressIterator{
+		Iterator: lister.ListIter(ctx, path, options...),
+		ctx:      ctx,
+		store:    c,
+	}
+}
+
+// Walk recursively look for files in directory
+func (c *compressStorage) Walk(path string, callback func(path string)) error {
+	return c.store.Walk(path, callback)
+}
+
+// WalkWithContext recursively look for files in directory
+func (c *compressStorage) WalkWithContext(ctx context.Context, path string, callback func(path string)) error {
+	return c.store.WalkWithContext(ctx, path, callback)
+}
+
+// WalkFunc recursively look for files and directories, files with the codec encoding have uncompressed sizes
+func (c *compressStorage) WalkFunc(ctx context.Context, path string, callback func(entry *Entry) error) error {
+	walker, ok := c.store.(WalkerFunc)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	return walker.WalkFunc(ctx, path, func(entry *Entry) error {
+		return callback(c.plain(ctx, entry))
+	})
+}
+
+// Copy copies compressed object together with its encoding
+func (c *compressStorage) Copy(src string, dst string, options ...map[string]interface{}) error {
+	return c.store.Copy(src, dst, options...)
+}
+
+// CopyWithContext copies compressed object together with its encoding
+func (c *compressStorage) CopyWithContext(ctx context.Context, src string, dst string, options ...map[string]interface{}) error {
+	return c.store.CopyWithContext(ctx, src, dst, options...)
+}
+
+// Move moves an object together with its encoding
+func (c *compressStorage) Move(ctx context.Context, src string, dst string) error {
+	return Move(ctx, c.store, src, dst)
+}
+
+// Create create new file that is compressed while it is written, reads fail with ErrWriteOnly
+func (c *compressStorage) Create(path string) (io.ReadWriteCloser, error) {
+	return c.CreateWithContext(context.Background(), path)
+}
+
+// CreateWithContext create new file that is compressed while it is written, reads fail with ErrWriteOnly.
+// The file is uploaded with PutWithOptions while it is written, storages without it get the file uncompressed.
+func (c *compressStorage) CreateWithContext(ctx context.Context, path string) (io.ReadWriteCloser, error) {
+	putter, ok := c.store.(PutterWithOptions)
+
+	if !ok {
+		if creator, ok := c.store.(CreatorWithContext); ok {
+			return creator.CreateWithContext(ctx, path)
+		}
+
+		return c.store.Create(path)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	cw, err := newCompressWriter(pw, c.codec)
+
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		err := putter.PutWithOptions(ctx, path, pr, &PutOptions{ContentEncoding: c.codec.Encoding()})
+		_ = pr.CloseWithError(io.ErrClosedPipe)
+		done <- err
+	}()
+
+	return &compressFile{
+		compressWriter: cw,
+		pipe:           pw,
+		done:           done,
+	}, nil
+}
+
+// Get get decompressed object from storage
+func (c *compressStorage) Get(path string) (io.ReadCloser, error) {
+	return c.GetWithContext(context.Background(), path)
+}
+
+// GetWithContext get decompressed object from storage
+func (c *compressStorage) GetWithContext(ctx context.Context, path string) (io.ReadCloser, error) {
+	info, err := c.store.Stat(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.encoded(info) {
+		return c.store.GetWithContext(ctx, path)
+	}
+
+	body, err := c.store.GetWithContext(WithRawContent(ctx), path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dec, err := c.codec.NewReader(body)
+
+	if err != nil {
+		_ = body.Close()
+		return nil, err
+	}
+
+	return &decompressReader{dec, body}, nil
+}
+
+// GetRange get part of the object, compressed objects are decompressed from the beginning up to the range
+func (c *compressStorage) GetRange(ctx context.Context, path string, offset int64, length int64) (io.ReadCloser, error) {
+	getter, ok := c.store.(RangeGetter)
+
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	if offset < 0 {
+		return nil, ErrInvalidRange
+	}
+
+	info, err := c.store.Stat(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.encoded(info) {
+		return getter.GetRange(ctx, path, offset, length)
+	}
+
+	body, err := c.GetWithContext(ctx, path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := io.CopyN(io.Discard, body, offset); err != nil && !errors.Is(err, io.EOF) {
+		_ = body.Close()
+		return nil, err
+	}
+
+	if length < 0 {
+		return body, nil
+	}
+
+	return &limitedReadCloser{io.LimitReader(body, length), body}, nil
+}
+
+// Put compressed object into storage
+func (c *compressStorage) Put(path string, body io.Reader) error {
+	return c.PutWithContext(context.Background(), path, body)
+}
+
+// PutWithContext compressed object into storage
+func (c *compressStorage) PutWithContext(ctx context.Context, path string, body io.Reader) error {
+	putter, ok := c.store.(PutterWithOptions)
+
+	if !ok {
+		return c.store.PutWithContext(ctx, path, body)
+	}
+
+	return c.put(ctx, putter, path, body, nil)
+}
+
+// PutWithOptions compressed object into storage together with its attributes, ContentEncoding in the options keeps the body as it is
+func (c *compressStorage) PutWithOptions(ctx context.Context, path string, body io.Reader, options *PutOptions) error {
+	putter, ok := c.store.(PutterWithOptions)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	return c.put(ctx, putter, path, body, options)
+}
+
+// SetMetadata replace user metadata of the object, the encoding stays
+func (c *compressStorage) SetMetadata(ctx context.Context, path string, metadata map[string]string) error {
+	setter, ok := c.store.(MetadataSetter)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	return setter.SetMetadata(ctx, path, metadata)
+}
+
+// Link generate expiration link for storage, the link serves the object with its ContentEncoding
+func (c *compressStorage) Link(path string, expire time.Duration) (string, error) {
+	return c.store.Link(path, expire)
+}
+
+// Delete remove object from storage
+func (c *compressStorage) Delete(path string) error {
+	return c.store.Delete(path)
+}
+
+// DeleteWithContext remove object from storage
+func (c *compressStorage) DeleteWithContext(ctx context.Context, path string) error {
+	return c.store.DeleteWithContext(ctx, path)
+}
+
+// DeleteMany deletes the objects
+func (c *compressStorage) DeleteMany(ctx context.Context, paths []string) error {
+	return DeleteMany(ctx, c.store, paths)
+}
+
+// DeleteAll deletes the object at the path and every object under it
+func (c *compressStorage) DeleteAll(ctx context.Context, prefix string) error {
+	return DeleteAll(ctx, c.store, prefix)
+}
+
+// Stat get file information, compressed objects are reported without ContentEncoding and with uncompressed size
+func (c *compressStorage) Stat(path string) (FileInfo, error) {
+	info, err := c.store.Stat(path)
+
+	if err != nil || !c.encoded(info) {
+		return info, err
+	}
+
+	size, ok := c.size(context.Background(), path, info.Size())
+
+	if !ok {
+		size = info.Size()
+	}
+
+	return &compressInfo{info, Content(info), size}, nil
+}
+
+// Retryable classify the error by the wrapped storage
+func (c *compressStorage) Retryable(err error) bool {
+	if classifier, ok := c.store.(RetryClassifier); ok {
+		return classifier.Retryable(err)
+	}
+
+	return Retryable(err)
+}
+
+// compressInfo file information of the decompressed object
+type compressInfo struct {
+	FileInfo
+	ContentInfo
+	size int64
+}
+
+// Size get uncompressed size
+func (i *compressInfo) Size() int64 {
+	return i.size
+}
+
+// ContentEncoding object is served decompressed
+func (i *compressInfo) ContentEncoding() string {
+	return ""
+}
+
+// compressWriter compresses the data and appends the size trailer on Close
+type compressWriter struct {
+	dst   io.Writer
+	enc   io.WriteCloser
+	codec Codec
+	size  int64
+}
+
+func newCompressWriter(dst io.Writer, codec Codec) (*compressWriter, error) {
+	enc, err := codec.NewWriter(dst)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &compressWriter{
+		dst:   dst,
+		enc:   enc,
+		codec: codec,
+	}, nil
+}
+
+// Write compress the data
+func (w *compressWriter) Write(p []byte) (int, error) {
+	n, err := w.enc.Write(p)
+	w.size += int64(n)
+
+	return n, err
+}
+
+// Close flush the compressed stream and write the size trailer
+func (w *compressWriter) Close() error {
+	if err := w.enc.Close(); err != nil {
+		return err
+	}
+
+	if trailer, ok := w.codec.(sizeTrailer); ok {
+		_, err := w.dst.Write(trailer.trailer(w.size))
+		return err
+	}
+
+	return nil
+}
+
+// compressFile file that is compressed and uploaded while it is written
+type compressFile struct {
+	*compressWriter
+	pipe   *io.PipeWriter
+	done   chan error
+	closed bool
+	err    error
+}
+
+// Read handle is write only, always returns ErrWriteOnly
+func (f *compressFile) Read(_ []byte) (int, error) {
+	return 0, ErrWriteOnly
+}
+
+// Close finish the stream and wait for the upload
+func (f *compressFile) Close() error {
+	if f.closed {
+		return f.err
+	}
+
+	f.closed = true
+	err := f.compressWriter.Close()
+	_ = f.pipe.CloseWithError(err)
+
+	if f.err = <-f.done; f.err == nil {
+		f.err = err
+	}
+
+	return f.err
+}
+
+// decompressReader closes both the decoder and the body
+type decompressReader struct {
+	io.ReadCloser
+	body io.Closer
+}
+
+// Close close the decoder and the body
+func (r *decompressReader) Close() error {
+	_ = r.ReadCloser.Close()
+	return r.body.Close()
+}
+
+// compressIterator replaces the sizes of the compressed files with uncompressed sizes
+type compressIterator struct {
+	Iterator
+	ctx   context.Context
+	store *compressStorage
+	entry *Entry
+}
+
+// Next advance to the next entry and read its uncompressed size
+func (it *compressIterator) Next() bool {
+	if !it.Iterator.Next() {
+		return false
+	}
+
+	it.entry = it.store.plain(it.ctx, it.Iterator.Entry())
+
+	return true
+}
+
+// Entry get current entry
+func (it *compressIterator) Entry() *Entry {
+	return it.entry
+}
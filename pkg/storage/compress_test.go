@@ -0,0 +1,221 @@
+package storage_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/protsack-stephan/dev-toolkit/lib/fs"
+	"github.com/protsack-stephan/dev-toolkit/lib/mem"
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+var compressTestData = []byte(strings.Repeat(`{"name":"storage","value":"compressed"}`+"\n", 5000))
+
+// rangeCountingStorage counts the range requests
+type rangeCountingStorage struct {
+	storage.Storage
+	ranges int
+}
+
+func (s *rangeCountingStorage) GetRange(ctx context.Context, path string, offset int64, length int64) (io.ReadCloser, error) {
+	s.ranges++
+	return s.Storage.(storage.RangeGetter).GetRange(ctx, path, offset, length)
+}
+
+func (s *rangeCountingStorage) PutWithOptions(ctx context.Context, path string, body io.Reader, options *storage.PutOptions) error {
+	return s.Storage.(storage.PutterWithOptions).PutWithOptions(ctx, path, body, options)
+}
+
+func (s *rangeCountingStorage) ListIter(ctx context.Context, path string, options ...map[string]interface{}) storage.Iterator {
+	return s.Storage.(storage.ListIterator).ListIter(ctx, path, options...)
+}
+
+func (s *rangeCountingStorage) WalkFunc(ctx context.Context, path string, callback func(entry *storage.Entry) error) error {
+	return s.Storage.(storage.WalkerFunc).WalkFunc(ctx, path, callback)
+}
+
+func TestWithCompression(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	size := int64(len(compressTestData))
+
+	read := func(store storage.Storage, path string) []byte {
+		body, err := store.Get(path)
+		assert.NoError(err)
+
+		if err != nil {
+			return nil
+		}
+
+		defer body.Close()
+		data, err := io.ReadAll(body)
+		assert.NoError(err)
+
+		return data
+	}
+
+	for _, codec := range []storage.Codec{storage.Gzip, storage.Zstd} {
+		t.Run(codec.Encoding(), func(t *testing.T) {
+			t.Run("compress on put", func(t *testing.T) {
+				raw := mem.NewStorage()
+				store := storage.WithCompression(raw, codec)
+
+				assert.NoError(store.Put("data.json", bytes.NewReader(compressTestData)))
+				assert.Less(len(read(raw, "data.json")), len(compressTestData)/10)
+				assert.Equal(compressTestData, read(store, "data.json"))
+
+				info, err := raw.Stat("data.json")
+				assert.NoError(err)
+				assert.Equal(codec.Encoding(), storage.Content(info).ContentEncoding())
+
+				info, err = store.Stat("data.json")
+				assert.NoError(err)
+				assert.Equal(size, info.Size())
+				assert.Empty(storage.Content(info).ContentEncoding())
+			})
+
+			t.Run("compress on create", func(t *testing.T) {
+				raw := mem.NewStorage()
+				store := storage.WithCompression(raw, codec)
+
+				file, err := store.Create("data.json")
+				assert.NoError(err)
+
+				for i := 0; i < len(compressTestData); i += 4096 {
+					end := i + 4096
+
+					if end > len(compressTestData) {
+						end = len(compressTestData)
+					}
+
+					_, err := file.Write(compressTestData[i:end])
+					assert.NoError(err)
+				}
+
+				_, err = file.Read(make([]byte, 1))
+				assert.ErrorIs(err, storage.ErrWriteOnly)
+				assert.NoError(file.Close())
+				assert.Equal(compressTestData, read(store, "data.json"))
+
+				info, err := raw.Stat("data.json")
+				assert.NoError(err)
+				assert.Equal(codec.Encoding(), storage.Content(info).ContentEncoding())
+			})
+
+			t.Run("get range", func(t *testing.T) {
+				store := storage.WithCompression(mem.NewStorage(), codec)
+				assert.NoError(store.Put("data.json", bytes.NewReader(compressTestData)))
+				getter := store.(storage.RangeGetter)
+
+				for _, rng := range [][2]int64{{0, 10}, {1000, 5000}, {size - 5, -1}, {size, 1}} {
+					body, err := getter.GetRange(ctx, "data.json", rng[0], rng[1])
+					assert.NoError(err)
+
+					data, err := io.ReadAll(body)
+					assert.NoError(err)
+					assert.NoError(body.Close())
+
+					end := size
+
+					if rng[1] >= 0 && rng[0]+rng[1] < end {
+						end = rng[0] + rng[1]
+					}
+
+					assert.Equal(compressTestData[rng[0]:end], data)
+				}
+			})
+
+			t.Run("listing sizes", func(t *testing.T) {
+				for _, raw := range []storage.Storage{mem.NewStorage(), fs.NewStorage(t.TempDir())} {
+					counter := &rangeCountingStorage{Storage: raw}
+					store := storage.WithCompression(counter, codec)
+					assert.NoError(store.Put("dir/data.json", bytes.NewReader(compressTestData)))
+					assert.NoError(raw.Put("dir/plain.json", bytes.NewReader(compressTestData[:100])))
+					sizes := map[string]int64{"dir/data.json": size, "dir/plain.json": 100}
+
+					it := store.(storage.ListIterator).ListIter(ctx, "dir/")
+					listed := 0
+
+					for it.Next() {
+						listed++
+						assert.Equal(sizes[it.Entry().Path], it.Entry().Size)
+						assert.Empty(it.Entry().ContentEncoding)
+					}
+
+					assert.NoError(it.Err())
+					assert.Equal(2, listed)
+					assert.Equal(1, counter.ranges)
+
+					entries := 0
+					assert.NoError(store.(storage.WalkerFunc).WalkFunc(ctx, "dir/", func(entry *storage.Entry) error {
+						if !entry.IsDir {
+							entries++
+							assert.Equal(sizes[entry.Path], entry.Size)
+						}
+
+						return nil
+					}))
+					assert.Equal(2, entries)
+					assert.Equal(2, counter.ranges)
+				}
+			})
+		})
+	}
+
+	t.Run("leave compressed bodies alone", func(t *testing.T) {
+		raw := mem.NewStorage()
+		store := storage.WithCompression(raw, storage.Zstd)
+
+		buf := new(bytes.Buffer)
+		zw := gzip.NewWriter(buf)
+		_, err := zw.Write(compressTestData)
+		assert.NoError(err)
+		assert.NoError(zw.Close())
+		gzipped := buf.Bytes()
+
+		assert.NoError(store.Put("data.json.gz", bytes.NewReader(gzipped)))
+		assert.Equal(gzipped, read(raw, "data.json.gz"))
+		assert.Equal(gzipped, read(store, "data.json.gz"))
+
+		info, err := store.Stat("data.json.gz")
+		assert.NoError(err)
+		assert.Empty(storage.Content(info).ContentEncoding())
+		assert.Equal(int64(len(gzipped)), info.Size())
+	})
+
+	t.Run("keep encoding from the options", func(t *testing.T) {
+		raw := mem.NewStorage()
+		store := storage.WithCompression(raw, storage.Gzip)
+		putter := store.(storage.PutterWithOptions)
+
+		assert.NoError(putter.PutWithOptions(ctx, "data.json", bytes.NewReader(compressTestData), &storage.PutOptions{ContentEncoding: "identity"}))
+		assert.Equal(compressTestData, read(raw, "data.json"))
+
+		info, err := store.Stat("data.json")
+		assert.NoError(err)
+		assert.Equal("identity", storage.Content(info).ContentEncoding())
+	})
+
+	t.Run("record encoding in fs sidecar", func(t *testing.T) {
+		raw := fs.NewStorage(t.TempDir())
+		store := storage.WithCompression(raw, storage.Gzip)
+
+		assert.NoError(store.Put("data.json", bytes.NewReader(compressTestData)))
+		assert.Equal(compressTestData, read(store, "data.json"))
+
+		info, err := raw.Stat("data.json")
+		assert.NoError(err)
+		assert.Equal("gzip", storage.Content(info).ContentEncoding())
+
+		zr, err := gzip.NewReader(bytes.NewReader(read(raw, "data.json")))
+		assert.NoError(err)
+		data, err := io.ReadAll(zr)
+		assert.NoError(err)
+		assert.Equal(compressTestData, data)
+	})
+}
@@ -0,0 +1,59 @@
+package storage_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/dev-toolkit/lib/fs"
+	"github.com/protsack-stephan/dev-toolkit/lib/mem"
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	for name, factory := range map[string]storagetest.Factory{
+		"mem with prefix": func(t *testing.T) storage.Storage {
+			return storage.WithPrefix(mem.NewStorage(), "scoped/dir")
+		},
+		"fs with prefix": func(t *testing.T) storage.Storage {
+			return storage.WithPrefix(fs.NewStorage(t.TempDir()), "scoped/dir")
+		},
+		"mem with retry": func(t *testing.T) storage.Storage {
+			return storage.WithRetry(mem.NewStorage(), storage.RetryPolicy{BaseDelay: time.Millisecond})
+		},
+		"mem with cache": func(t *testing.T) storage.Storage {
+			return storage.WithCache(mem.NewStorage(), mem.NewStorage(), storage.CachePolicy{TTL: time.Hour})
+		},
+		"fs with fs cache": func(t *testing.T) storage.Storage {
+			return storage.WithCache(fs.NewStorage(t.TempDir()), fs.NewStorage(t.TempDir()), storage.CachePolicy{})
+		},
+		"mem with mem mirror": func(t *testing.T) storage.Storage {
+			return storage.Mirror(mem.NewStorage(), mem.NewStorage())
+		},
+		"fs with fs mirror": func(t *testing.T) storage.Storage {
+			return storage.Mirror(fs.NewStorage(t.TempDir()), fs.NewStorage(t.TempDir()))
+		},
+		"mem with encryption": func(t *testing.T) storage.Storage {
+			return storage.WithEncryption(mem.NewStorage(), newTestKeys(t, 1))
+		},
+		"fs with encryption": func(t *testing.T) storage.Storage {
+			return storage.WithEncryption(fs.NewStorage(t.TempDir()), newTestKeys(t, 1))
+		},
+		"mem with gzip": func(t *testing.T) storage.Storage {
+			return storage.WithCompression(mem.NewStorage(), storage.Gzip)
+		},
+		"fs with zstd": func(t *testing.T) storage.Storage {
+			return storage.WithCompression(fs.NewStorage(t.TempDir()), storage.Zstd)
+		},
+		"mem with checksum": func(t *testing.T) storage.Storage {
+			return storage.WithChecksum(mem.NewStorage(), storage.ChecksumSHA256)
+		},
+		"fs with checksum": func(t *testing.T) storage.Storage {
+			return storage.WithChecksum(fs.NewStorage(t.TempDir()), storage.ChecksumCRC32C)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			storagetest.Run(t, factory)
+		})
+	}
+}
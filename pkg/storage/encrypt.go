@@ -0,0 +1,801 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrDecrypt object is not encrypted, is corrupted or truncated, or the key is wrong
+var ErrDecrypt = errors.New("can't decrypt the object")
+
+const (
+	encryptMagic      = "DTKE"
+	encryptVersion    = 1
+	encryptKeySize    = 32
+	encryptChunkSize  = 64 * 1024
+	encryptNonceSize  = 12
+	encryptTagSize    = 16
+	encryptHeaderSize = 23
+	encryptMaxWrapped = 1024
+)
+
+// WithEncryption encrypt the objects on the client with AES-256-GCM.
+// Every object gets random data key that is wrapped by the key provider and stored in the object header,
+// the body is sealed in 64KiB chunks so Get, GetRange and Create are streamed.
+// Get, GetRange, Stat, ListIter and WalkFunc report the plaintext, sizes in the listings cost one header read per object.
+// Copy copies the encrypted object as is. Link is refused with ErrNotSupported, because the link would serve ciphertext.
+func WithEncryption(store Storage, keys KeyProvider) Storage {
+	return &encryptStorage{
+		store: store,
+		keys:  keys,
+	}
+}
+
+// encryptHeader beginning of every encrypted object:
+// magic, version, chunk size, base nonce, wrapped data key length and the wrapped data key
+type encryptHeader struct {
+	chunkSize int64
+	nonce     []byte
+	wrapped   []byte
+}
+
+func (h *encryptHeader) size() int64 {
+	return encryptHeaderSize + int64(len(h.wrapped))
+}
+
+func (h *encryptHeader) marshal() []byte {
+	buf := make([]byte, encryptHeaderSize, h.size())
+	copy(buf, encryptMagic)
+	buf[4] = encryptVersion
+	binary.BigEndian.PutUint32(buf[5:9], uint32(h.chunkSize))
+	copy(buf[9:21], h.nonce)
+	binary.BigEndian.PutUint16(buf[21:23], uint16(len(h.wrapped)))
+
+	return append(buf, h.wrapped...)
+}
+
+// chunks number of sealed chunks in the object, there is at least one final chunk even for empty objects
+func (h *encryptHeader) chunks(size int64) int64 {
+	sealed := h.chunkSize + encryptTagSize
+	return (size - h.size() + sealed - 1) / sealed
+}
+
+// plainSize size of the plaintext of the object, every chunk has its authentication tag
+func (h *encryptHeader) plainSize(size int64) int64 {
+	if plain := size - h.size() - h.chunks(size)*encryptTagSize; plain > 0 {
+		return plain
+	}
+
+	return 0
+}
+
+// readHeader read and validate the header
+func readHeader(r io.Reader) (*encryptHeader, error) {
+	buf := make([]byte, encryptHeaderSize)
+
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrDecrypt
+		}
+
+		return nil, err
+	}
+
+	if string(buf[:4]) != encryptMagic || buf[4] != encryptVersion {
+		return nil, ErrDecrypt
+	}
+
+	hdr := &encryptHeader{
+		chunkSize: int64(binary.BigEndian.Uint32(buf[5:9])),
+		nonce:     buf[9:21],
+		wrapped:   make([]byte, binary.BigEndian.Uint16(buf[21:23])),
+	}
+
+	// only the chunk size of this version is accepted, so a forged header can't make the reader allocate huge chunks
+	if hdr.chunkSize != encryptChunkSize || len(hdr.wrapped) > encryptMaxWrapped {
+		return nil, ErrDecrypt
+	}
+
+	if _, err := io.ReadFull(r, hdr.wrapped); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrDecrypt
+		}
+
+		return nil, err
+	}
+
+	return hdr, nil
+}
+
+// chunkCipher seals and opens the chunks in order.
+// Nonce of the chunk is the base nonce xored with the counter, position and final flag are authenticated
+// so the chunks can't be reordered and the object can't be truncated.
+type chunkCipher struct {
+	aead    cipher.AEAD
+	nonce   []byte
+	counter uint64
+}
+
+func (c *chunkCipher) params(final bool) ([]byte, []byte) {
+	nonce := make([]byte, encryptNonceSize)
+	copy(nonce, c.nonce)
+	ad := make([]byte, 9)
+	binary.BigEndian.PutUint64(ad, c.counter)
+
+	for i := 0; i < 8; i++ {
+		nonce[encryptNonceSize-8+i] ^= ad[i]
+	}
+
+	if final {
+		ad[8] = 1
+	}
+
+	c.counter++
+
+	return nonce, ad
+}
+
+func (c *chunkCipher) seal(dst []byte, plain []byte, final bool) []byte {
+	nonce, ad := c.params(final)
+	return c.aead.Seal(dst, nonce, plain, ad)
+}
+
+func (c *chunkCipher) open(dst []byte, sealed []byte, final bool) ([]byte, error) {
+	nonce, ad := c.params(final)
+	plain, err := c.aead.Open(dst, nonce, sealed, ad)
+
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return plain, nil
+}
+
+// encryptStorage encrypts objects before they get to the storage
+type encryptStorage struct {
+	store Storage
+	keys  KeyProvider
+}
+
+// seal create the header and the cipher for the new object
+func (e *encryptStorage) seal(ctx context.Context) (*encryptHeader, *chunkCipher, error) {
+	key := make([]byte, encryptKeySize)
+	nonce := make([]byte, encryptNonceSize)
+
+	for _, buf := range [][]byte{key, nonce} {
+		if _, err := io.ReadFull(rand.Reader, buf); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	wrapped, err := e.keys.WrapKey(ctx, key)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(wrapped) > encryptMaxWrapped {
+		return nil, nil, fmt.Errorf("%w: wrapped key has %d bytes, limit is %d", ErrInvalidKey, len(wrapped), encryptMaxWrapped)
+	}
+
+	aead, err := newGCM(key)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hdr := &encryptHeader{
+		chunkSize: encryptChunkSize,
+		nonce:     nonce,
+		wrapped:   wrapped,
+	}
+
+	return hdr, &chunkCipher{aead: aead, nonce: nonce}, nil
+}
+
+// open unwrap the data key of the object and create the cipher starting from the chunk
+func (e *encryptStorage) open(ctx context.Context, hdr *encryptHeader, chunk int64) (*chunkCipher, error) {
+	key, err := e.keys.UnwrapKey(ctx, hdr.wrapped)
+
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newGCM(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &chunkCipher{aead: aead, nonce: hdr.nonce, counter: uint64(chunk)}, nil
+}
+
+// header read the header of the object, only the beginning of the object is downloaded if the storage supports ranges
+func (e *encryptStorage) header(ctx context.Context, path string) (*encryptHeader, error) {
+	var body io.ReadCloser
+	var err error
+
+	if getter, ok := e.store.(RangeGetter); ok {
+		body, err = getter.GetRange(ctx, path, 0, encryptHeaderSize+encryptMaxWrapped)
+	} else {
+		body, err = e.store.GetWithContext(ctx, path)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer body.Close()
+
+	return readHeader(body)
+}
+
+// size get the plaintext size of the object
+func (e *encryptStorage) size(ctx context.Context, path string, size int64) (int64, error) {
+	hdr, err := e.header(ctx, path)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return hdr.plainSize(size), nil
+}
+
+// encrypt get the reader of the encrypted body
+func (e *encryptStorage) encrypt(ctx context.Context, body io.Reader) (io.Reader, error) {
+	hdr, cph, err := e.seal(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &encryptReader{
+		src:    bufio.NewReader(body),
+		cipher: cph,
+		chunk:  make([]byte, hdr.chunkSize),
+		out:    hdr.marshal(),
+	}, nil
+}
+
+// List get the contents of the path
+func (e *encryptStorage) List(path string, options ...map[string]interface{}) ([]string, error) {
+	return e.store.List(path, options...)
+}
+
+// ListWithContext get the contents of the path
+func (e *encryptStorage) ListWithContext(ctx context.Context, path string, options ...map[string]interface{}) ([]string, error) {
+	return e.store.ListWithContext(ctx, path, options...)
+}
+
+// ListIter paginated listing of the path with plaintext sizes
+func (e *encryptStorage) ListIter(ctx context.Context, path string, options ...map[string]interface{}) Iterator {
+	lister, ok := e.store.(ListIterator)
+
+	if !ok {
+		return &errIterator{ErrNotSupported}
+	}
+
+	return &encryptIterator{
+		Iterator: lister.ListIter(ctx, path, options...),
+		ctx:      ctx,
+		store:    e,
+	}
+}
+
+// Walk recursively look for files in directory
+func (e *encryptStorage) Walk(path string, callback func(path string)) error {
+	return e.store.Walk(path, callback)
+}
+
+// WalkWithContext recursively look for files in directory
+func (e *encryptStorage) WalkWithContext(ctx context.Context, path string, callback func(path string)) error {
+	return e.store.WalkWithContext(ctx, path, callback)
+}
+
+// WalkFunc recursively look for files and directories, files have plaintext sizes
+func (e *encryptStorage) WalkFunc(ctx context.Context, path string, callback func(entry *Entry) error) error {
+	walker, ok := e.store.(WalkerFunc)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	return walker.WalkFunc(ctx, path, func(entry *Entry) error {
+		if entry.IsDir {
+			return callback(entry)
+		}
+
+		size, err := e.size(ctx, entry.Path, entry.Size)
+
+		if err != nil {
+			return err
+		}
+
+		plain := *entry
+		plain.Size = size
+		return callback(&plain)
+	})
+}
+
+// Copy copies encrypted object, data key stays the same
+func (e *encryptStorage) Copy(src string, dst string, options ...map[string]interface{}) error {
+	return e.store.Copy(src, dst, options...)
+}
+
+// CopyWithContext copies encrypted object, data key stays the same
+func (e *encryptStorage) CopyWithContext(ctx context.Context, src string, dst string, options ...map[string]interface{}) error {
+	return e.store.CopyWithContext(ctx, src, dst, options...)
+}
+
+// Move moves an object, the data key does not depend on the path so the object stays readable
+func (e *encryptStorage) Move(ctx context.Context, src string, dst string) error {
+	return Move(ctx, e.store, src, dst)
+}
+
+// Create create new file that is encrypted while it is written, reads fail with ErrWriteOnly
+func (e *encryptStorage) Create(path string) (io.ReadWriteCloser, error) {
+	return e.CreateWithContext(context.Background(), path)
+}
+
+// CreateWithContext create new file that is encrypted while it is written, reads fail with ErrWriteOnly
+func (e *encryptStorage) CreateWithContext(ctx context.Context, path string) (io.ReadWriteCloser, error) {
+	hdr, cph, err := e.seal(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var file io.ReadWriteCloser
+
+	if creator, ok := e.store.(CreatorWithContext); ok {
+		file, err = creator.CreateWithContext(ctx, path)
+	} else {
+		file, err = e.store.Create(path)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := file.Write(hdr.marshal()); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return &encryptWriter{
+		file:   file,
+		cipher: cph,
+		chunk:  make([]byte, 0, hdr.chunkSize),
+	}, nil
+}
+
+// Get get decrypted object from storage
+func (e *encryptStorage) Get(path string) (io.ReadCloser, error) {
+	return e.GetWithContext(context.Background(), path)
+}
+
+// GetWithContext get decrypted object from storage
+func (e *encryptStorage) GetWithContext(ctx context.Context, path string) (io.ReadCloser, error) {
+	body, err := e.store.GetWithContext(ctx, path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	src := bufio.NewReader(body)
+	hdr, err := readHeader(src)
+
+	if err != nil {
+		_ = body.Close()
+		return nil, err
+	}
+
+	cph, err := e.open(ctx, hdr, 0)
+
+	if err != nil {
+		_ = body.Close()
+		return nil, err
+	}
+
+	return newDecryptReader(src, body, cph, hdr, -1), nil
+}
+
+// GetRange get decrypted part of the object, only the chunks of the range are downloaded
+func (e *encryptStorage) GetRange(ctx context.Context, path string, offset int64, length int64) (io.ReadCloser, error) {
+	getter, ok := e.store.(RangeGetter)
+
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	if offset < 0 {
+		return nil, ErrInvalidRange
+	}
+
+	info, err := e.store.Stat(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	hdr, err := e.header(ctx, path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	size := hdr.plainSize(info.Size())
+	end := size
+
+	if length >= 0 && offset+length < end {
+		end = offset + length
+	}
+
+	if offset >= end {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+
+	first := offset / hdr.chunkSize
+	last := hdr.chunks(info.Size()) - 1
+	cph, err := e.open(ctx, hdr, first)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := hdr.chunkSize + encryptTagSize
+	count := (end-1)/hdr.chunkSize - first + 1
+	body, err := getter.GetRange(ctx, path, hdr.size()+first*sealed, count*sealed)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dec := newDecryptReader(bufio.NewReader(body), body, cph, hdr, last)
+
+	if _, err := io.CopyN(io.Discard, dec, offset-first*hdr.chunkSize); err != nil {
+		_ = dec.Close()
+		return nil, err
+	}
+
+	return &limitedReadCloser{io.LimitReader(dec, end-offset), dec}, nil
+}
+
+// Put encrypted object into storage
+func (e *encryptStorage) Put(path string, body io.Reader) error {
+	return e.PutWithContext(context.Background(), path, body)
+}
+
+// PutWithContext encrypted object into storage
+func (e *encryptStorage) PutWithContext(ctx context.Context, path string, body io.Reader) error {
+	enc, err := e.encrypt(ctx, body)
+
+	if err != nil {
+		return err
+	}
+
+	return e.store.PutWithContext(ctx, path, enc)
+}
+
+// PutWithOptions encrypted object into storage together with its attributes, attributes are not encrypted
+func (e *encryptStorage) PutWithOptions(ctx context.Context, path string, body io.Reader, options *PutOptions) error {
+	putter, ok := e.store.(PutterWithOptions)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	enc, err := e.encrypt(ctx, body)
+
+	if err != nil {
+		return err
+	}
+
+	return putter.PutWithOptions(ctx, path, enc, options)
+}
+
+// SetMetadata replace user metadata of the object, metadata is not encrypted
+func (e *encryptStorage) SetMetadata(ctx context.Context, path string, metadata map[string]string) error {
+	setter, ok := e.store.(MetadataSetter)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	return setter.SetMetadata(ctx, path, metadata)
+}
+
+// Link is refused with ErrNotSupported, the link would serve the ciphertext
+func (e *encryptStorage) Link(_ string, _ time.Duration) (string, error) {
+	return "", ErrNotSupported
+}
+
+// Delete remove object from storage
+func (e *encryptStorage) Delete(path string) error {
+	return e.store.Delete(path)
+}
+
+// DeleteWithContext remove object from storage
+func (e *encryptStorage) DeleteWithContext(ctx context.Context, path string) error {
+	return e.store.DeleteWithContext(ctx, path)
+}
+
+// DeleteMany deletes the objects
+func (e *encryptStorage) DeleteMany(ctx context.Context, paths []string) error {
+	return DeleteMany(ctx, e.store, paths)
+}
+
+// DeleteAll deletes the object at the path and every object under it
+func (e *encryptStorage) DeleteAll(ctx context.Context, prefix string) error {
+	return DeleteAll(ctx, e.store, prefix)
+}
+
+// Stat get file information with plaintext size
+func (e *encryptStorage) Stat(path string) (FileInfo, error) {
+	info, err := e.store.Stat(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := e.size(context.Background(), path, info.Size())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &encryptInfo{info, size}, nil
+}
+
+// Retryable classify the error by the wrapped storage
+func (e *encryptStorage) Retryable(err error) bool {
+	if classifier, ok := e.store.(RetryClassifier); ok {
+		return classifier.Retryable(err)
+	}
+
+	return Retryable(err)
+}
+
+// encryptInfo file information with plaintext size
+type encryptInfo struct {
+	FileInfo
+	size int64
+}
+
+// Size get plaintext size
+func (i *encryptInfo) Size() int64 {
+	return i.size
+}
+
+// encryptReader encrypts the body while it is read
+type encryptReader struct {
+	src    *bufio.Reader
+	cipher *chunkCipher
+	chunk  []byte
+	sealed []byte
+	out    []byte
+	done   bool
+}
+
+// Read get next part of the encrypted body
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		n, err := io.ReadFull(r.src, r.chunk)
+
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, err
+		}
+
+		final := n < len(r.chunk)
+
+		if !final {
+			if _, err := r.src.Peek(1); errors.Is(err, io.EOF) {
+				final = true
+			} else if err != nil {
+				return 0, err
+			}
+		}
+
+		r.sealed = r.cipher.seal(r.sealed[:0], r.chunk[:n], final)
+		r.out = r.sealed
+		r.done = final
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+
+	return n, nil
+}
+
+// encryptWriter encrypts the file while it is written, last chunk is sealed on Close
+type encryptWriter struct {
+	file   io.ReadWriteCloser
+	cipher *chunkCipher
+	chunk  []byte
+	sealed []byte
+	closed bool
+}
+
+func (w *encryptWriter) flush(final bool) error {
+	w.sealed = w.cipher.seal(w.sealed[:0], w.chunk, final)
+	w.chunk = w.chunk[:0]
+	_, err := w.file.Write(w.sealed)
+
+	return err
+}
+
+// Read handle is write only, always returns ErrWriteOnly
+func (w *encryptWriter) Read(_ []byte) (int, error) {
+	return 0, ErrWriteOnly
+}
+
+// Write encrypt and write the data, full chunks are written as soon as more data comes
+func (w *encryptWriter) Write(p []byte) (int, error) {
+	written := 0
+
+	for len(p) > 0 {
+		if len(w.chunk) == cap(w.chunk) {
+			if err := w.flush(false); err != nil {
+				return written, err
+			}
+		}
+
+		n := copy(w.chunk[len(w.chunk):cap(w.chunk)], p)
+		w.chunk = w.chunk[:len(w.chunk)+n]
+		p = p[n:]
+		written += n
+	}
+
+	return written, nil
+}
+
+// Close seal the last chunk and close the file
+func (w *encryptWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+
+	w.closed = true
+
+	if err := w.flush(true); err != nil {
+		_ = w.file.Close()
+		return err
+	}
+
+	return w.file.Close()
+}
+
+// decryptReader decrypts the chunks while they are read
+type decryptReader struct {
+	src    *bufio.Reader
+	body   io.Closer
+	cipher *chunkCipher
+	sealed []byte
+	plain  []byte
+	out    []byte
+	last   int64
+	done   bool
+}
+
+// newDecryptReader create reader of the chunks, last is the index of the final chunk, -1 finds it by the end of the body
+func newDecryptReader(src *bufio.Reader, body io.Closer, cph *chunkCipher, hdr *encryptHeader, last int64) *decryptReader {
+	return &decryptReader{
+		src:    src,
+		body:   body,
+		cipher: cph,
+		sealed: make([]byte, hdr.chunkSize+encryptTagSize),
+		last:   last,
+	}
+}
+
+// Read get next part of the plaintext
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		n, err := io.ReadFull(r.src, r.sealed)
+
+		if errors.Is(err, io.EOF) {
+			return 0, ErrDecrypt
+		}
+
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, err
+		}
+
+		final := n < len(r.sealed)
+
+		if r.last >= 0 {
+			final = int64(r.cipher.counter) == r.last
+		} else if !final {
+			if _, err := r.src.Peek(1); errors.Is(err, io.EOF) {
+				final = true
+			} else if err != nil {
+				return 0, err
+			}
+		}
+
+		if n < len(r.sealed) && !final {
+			return 0, ErrDecrypt
+		}
+
+		if r.plain, err = r.cipher.open(r.plain[:0], r.sealed[:n], final); err != nil {
+			return 0, err
+		}
+
+		r.out = r.plain
+		r.done = final
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+
+	return n, nil
+}
+
+// Close close the encrypted body
+func (r *decryptReader) Close() error {
+	return r.body.Close()
+}
+
+// limitedReadCloser reader limited to the range
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// encryptIterator replaces the sizes of the files with plaintext sizes
+type encryptIterator struct {
+	Iterator
+	ctx   context.Context
+	store *encryptStorage
+	entry *Entry
+	err   error
+}
+
+// Next advance to the next entry and read its plaintext size
+func (it *encryptIterator) Next() bool {
+	if it.err != nil || !it.Iterator.Next() {
+		return false
+	}
+
+	entry := *it.Iterator.Entry()
+
+	if !entry.IsDir {
+		if entry.Size, it.err = it.store.size(it.ctx, entry.Path, entry.Size); it.err != nil {
+			return false
+		}
+	}
+
+	it.entry = &entry
+
+	return true
+}
+
+// Entry get current entry
+func (it *encryptIterator) Entry() *Entry {
+	return it.entry
+}
+
+// Err get the error that stopped the iteration
+func (it *encryptIterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+
+	return it.Iterator.Err()
+}
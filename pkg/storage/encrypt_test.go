@@ -0,0 +1,187 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/dev-toolkit/lib/mem"
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestKeys(t *testing.T, seed byte) storage.KeyProvider {
+	keys, err := storage.NewLocalKeyProvider(bytes.Repeat([]byte{seed}, 32))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return keys
+}
+
+func TestWithEncryption(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	data := make([]byte, 150*1024)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	read := func(store storage.Storage, path string) ([]byte, error) {
+		body, err := store.Get(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		defer body.Close()
+
+		return io.ReadAll(body)
+	}
+
+	setup := func() (*mem.Storage, storage.Storage) {
+		raw := mem.NewStorage()
+		store := storage.WithEncryption(raw, newTestKeys(t, 1))
+		assert.NoError(store.Put("data.bin", bytes.NewReader(data)))
+
+		return raw, store
+	}
+
+	t.Run("encrypt at rest", func(t *testing.T) {
+		raw, store := setup()
+
+		encrypted, err := read(raw, "data.bin")
+		assert.NoError(err)
+		assert.False(bytes.Contains(encrypted, data[:64]))
+		assert.Greater(len(encrypted), len(data))
+
+		plain, err := read(store, "data.bin")
+		assert.NoError(err)
+		assert.Equal(data, plain)
+
+		info, err := store.Stat("data.bin")
+		assert.NoError(err)
+		assert.Equal(int64(len(data)), info.Size())
+	})
+
+	t.Run("sizes of the chunk boundaries", func(t *testing.T) {
+		store := storage.WithEncryption(mem.NewStorage(), newTestKeys(t, 1))
+
+		for _, size := range []int{0, 1, 64 * 1024, 64*1024 + 1, 128 * 1024} {
+			assert.NoError(store.Put("size.bin", bytes.NewReader(data[:size])))
+
+			plain, err := read(store, "size.bin")
+			assert.NoError(err)
+			assert.Equal(size, len(plain))
+			assert.Equal(data[:size], plain[:size])
+
+			info, err := store.Stat("size.bin")
+			assert.NoError(err)
+			assert.Equal(int64(size), info.Size())
+		}
+	})
+
+	t.Run("get range across the chunks", func(t *testing.T) {
+		_, store := setup()
+		getter := store.(storage.RangeGetter)
+
+		for _, rng := range [][2]int64{{0, 10}, {65530, 20}, {70000, 65536 + 100}, {140000, -1}, {int64(len(data)), 1}} {
+			body, err := getter.GetRange(ctx, "data.bin", rng[0], rng[1])
+			assert.NoError(err)
+
+			plain, err := io.ReadAll(body)
+			assert.NoError(err)
+			assert.NoError(body.Close())
+
+			end := int64(len(data))
+
+			if rng[1] >= 0 && rng[0]+rng[1] < end {
+				end = rng[0] + rng[1]
+			}
+
+			assert.Equal(data[rng[0]:end], plain)
+		}
+	})
+
+	t.Run("create is encrypted while written", func(t *testing.T) {
+		raw := mem.NewStorage()
+		store := storage.WithEncryption(raw, newTestKeys(t, 1))
+
+		file, err := store.Create("created.bin")
+		assert.NoError(err)
+
+		for i := 0; i < len(data); i += 1000 {
+			end := i + 1000
+
+			if end > len(data) {
+				end = len(data)
+			}
+
+			_, err := file.Write(data[i:end])
+			assert.NoError(err)
+		}
+
+		_, err = file.Read(make([]byte, 1))
+		assert.ErrorIs(err, storage.ErrWriteOnly)
+		assert.NoError(file.Close())
+
+		plain, err := read(store, "created.bin")
+		assert.NoError(err)
+		assert.Equal(data, plain)
+	})
+
+	t.Run("detect tampering", func(t *testing.T) {
+		raw, store := setup()
+		encrypted, err := read(raw, "data.bin")
+		assert.NoError(err)
+
+		tampered := append([]byte{}, encrypted...)
+		tampered[len(tampered)-100] ^= 1
+		assert.NoError(raw.Put("tampered.bin", bytes.NewReader(tampered)))
+		_, err = read(store, "tampered.bin")
+		assert.ErrorIs(err, storage.ErrDecrypt)
+
+		truncated := encrypted[:len(encrypted)-(len(data)-128*1024)-16]
+		assert.NoError(raw.Put("truncated.bin", bytes.NewReader(truncated)))
+		_, err = read(store, "truncated.bin")
+		assert.ErrorIs(err, storage.ErrDecrypt)
+
+		assert.NoError(raw.Put("plain.bin", bytes.NewReader(data)))
+		_, err = read(store, "plain.bin")
+		assert.ErrorIs(err, storage.ErrDecrypt)
+	})
+
+	t.Run("reject forged chunk size", func(t *testing.T) {
+		raw, store := setup()
+		encrypted, err := read(raw, "data.bin")
+		assert.NoError(err)
+
+		for _, size := range [][]byte{{0xff, 0xff, 0xff, 0xff}, {0, 0, 0, 0}, {0, 0, 0x80, 0}} {
+			forged := append([]byte{}, encrypted...)
+			copy(forged[5:9], size)
+			assert.NoError(raw.Put("forged.bin", bytes.NewReader(forged)))
+
+			_, err = read(store, "forged.bin")
+			assert.ErrorIs(err, storage.ErrDecrypt)
+
+			_, err = store.Stat("forged.bin")
+			assert.ErrorIs(err, storage.ErrDecrypt)
+		}
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		raw, _ := setup()
+
+		_, err := read(storage.WithEncryption(raw, newTestKeys(t, 2)), "data.bin")
+		assert.ErrorIs(err, storage.ErrDecrypt)
+	})
+
+	t.Run("refuse links", func(t *testing.T) {
+		_, store := setup()
+
+		_, err := store.Link("data.bin", time.Minute)
+		assert.ErrorIs(err, storage.ErrNotSupported)
+	})
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// Sentinel errors shared by all of the storage backends, check them with errors.Is.
+// They are the same values as in io/fs package, so file system errors match them as well.
+var (
+	// ErrNotExist object or path does not exist
+	ErrNotExist = fs.ErrNotExist
+
+	// ErrExist object or path already exists
+	ErrExist = fs.ErrExist
+
+	// ErrPermission not enough permissions to access the object
+	ErrPermission = fs.ErrPermission
+)
+
+// ErrInvalidRange range read with negative offset or seek before the start of the object
+var ErrInvalidRange = errors.New("invalid range")
+
+// ErrWriteOnly handle returned by the Creator can only be written to
+var ErrWriteOnly = errors.New("handle is write only")
+
+// SkipDir returned from the walk callback to skip the directory,
+// when returned for a file skips the rest of the files in the same directory
+var SkipDir = fs.SkipDir
+
+// NewError wrap backend error into storage error of a certain kind
+func NewError(op string, path string, kind error, err error) *Error {
+	return &Error{
+		Op:   op,
+		Path: path,
+		Kind: kind,
+		Err:  err,
+	}
+}
+
+// Error backend error classified by one of the sentinel errors,
+// the original backend error is available through errors.Unwrap and errors.As
+type Error struct {
+	Op   string
+	Path string
+	Kind error
+	Err  error
+}
+
+// Error get error message
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Op, e.Path, e.Err)
+}
+
+// Unwrap get the original backend error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is check if error is of the certain kind
+func (e *Error) Is(target error) bool {
+	return e.Kind != nil && e.Kind == target
+}
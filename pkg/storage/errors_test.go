@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errTestCause = errors.New("no such key")
+
+func TestError(t *testing.T) {
+	assert := assert.New(t)
+	err := NewError("get", "test.txt", ErrNotExist, errTestCause)
+
+	assert.Equal("get test.txt: no such key", err.Error())
+	assert.True(errors.Is(err, ErrNotExist))
+	assert.False(errors.Is(err, ErrPermission))
+	assert.True(errors.Is(err, errTestCause))
+	assert.Equal(errTestCause, errors.Unwrap(err))
+
+	var serr *Error
+	assert.True(errors.As(err, &serr))
+	assert.Equal("get", serr.Op)
+	assert.Equal("test.txt", serr.Path)
+
+	assert.False(errors.Is(NewError("get", "test.txt", nil, errTestCause), ErrNotExist))
+}
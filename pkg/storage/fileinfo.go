@@ -2,22 +2,48 @@ package storage
 
 import "time"
 
-// FileInfo list of file properties
+// FileInfo properties of the file that every backend reports
 type FileInfo interface {
+	// Name base name of the file
+	Name() string
+
+	// Size size of the file in bytes
 	Size() int64
-	AcceptRanges() string
+
+	// ModTime last modification time
+	ModTime() time.Time
+
+	// IsDir the path is a directory, object storages don't have directories
+	IsDir() bool
+
+	// ETag entity tag of the content, empty when the backend does not have one
+	ETag() string
+
+	// ContentType MIME type of the content
+	ContentType() string
+
+	// Metadata user metadata of the file
+	Metadata() map[string]*string
+
+	// Sys backend specific file information, use Content and Object to get the known extensions
+	Sys() interface{}
+}
+
+// ContentInfo content headers of the file that are set with PutOptions
+type ContentInfo interface {
 	CacheControl() string
 	ContentDisposition() string
 	ContentEncoding() string
 	ContentLanguage() string
-	ContentType() string
-	ETag() string
+}
+
+// ObjectInfo object storage (s3) specific properties of the file
+type ObjectInfo interface {
+	AcceptRanges() string
 	Expires() string
-	LastModified() time.Time
 	ActiveStatus() string
 	DeleteMarker() bool
 	Expiration() string
-	Metadata() map[string]*string
 	MissingMeta() int64
 	ObjectLockLegalHoldStatus() string
 	ObjectLockMode() string
@@ -34,3 +60,95 @@ type FileInfo interface {
 	VersionId() string
 	WebsiteRedirectLocation() string
 }
+
+// Content get content headers of the file, looks at the file information and then at Sys.
+// Empty headers are returned when the backend does not keep them.
+func Content(info FileInfo) ContentInfo {
+	if content, ok := info.(ContentInfo); ok {
+		return content
+	}
+
+	if content, ok := info.Sys().(ContentInfo); ok {
+		return content
+	}
+
+	return noContent{}
+}
+
+// Object get object storage properties of the file, false if the backend is not an object storage
+func Object(info FileInfo) (ObjectInfo, bool) {
+	if object, ok := info.(ObjectInfo); ok {
+		return object, true
+	}
+
+	object, ok := info.Sys().(ObjectInfo)
+
+	return object, ok
+}
+
+// LegacyFileInfo file information with all of the properties in one interface
+//
+// Deprecated: use FileInfo with Content and Object.
+type LegacyFileInfo interface {
+	FileInfo
+	ContentInfo
+	ObjectInfo
+	LastModified() time.Time
+}
+
+// Legacy get file information with all of the properties, missing properties have zero values
+//
+// Deprecated: use FileInfo with Content and Object.
+func Legacy(info FileInfo) LegacyFileInfo {
+	object, ok := Object(info)
+
+	if !ok {
+		object = noObject{}
+	}
+
+	return &legacyInfo{info, Content(info), object}
+}
+
+// legacyInfo file information with the extensions
+type legacyInfo struct {
+	FileInfo
+	ContentInfo
+	ObjectInfo
+}
+
+// LastModified get modification time
+func (i *legacyInfo) LastModified() time.Time {
+	return i.ModTime()
+}
+
+// noContent content headers of the backend that does not keep them
+type noContent struct{}
+
+func (noContent) CacheControl() string       { return "" }
+func (noContent) ContentDisposition() string { return "" }
+func (noContent) ContentEncoding() string    { return "" }
+func (noContent) ContentLanguage() string    { return "" }
+
+// noObject properties of the file that is not an object of the object storage
+type noObject struct{}
+
+func (noObject) AcceptRanges() string                 { return "" }
+func (noObject) Expires() string                      { return "" }
+func (noObject) ActiveStatus() string                 { return "" }
+func (noObject) DeleteMarker() bool                   { return false }
+func (noObject) Expiration() string                   { return "" }
+func (noObject) MissingMeta() int64                   { return 0 }
+func (noObject) ObjectLockLegalHoldStatus() string    { return "" }
+func (noObject) ObjectLockMode() string               { return "" }
+func (noObject) ObjectLockRetainUntilDate() time.Time { return time.Time{} }
+func (noObject) PartsCount() int64                    { return 0 }
+func (noObject) ReplicationStatus() string            { return "" }
+func (noObject) RequestCharged() string               { return "" }
+func (noObject) Restore() string                      { return "" }
+func (noObject) SSECustomerAlgorithm() string         { return "" }
+func (noObject) SSECustomerKeyMD5() string            { return "" }
+func (noObject) SSEKMSKeyId() string                  { return "" }
+func (noObject) ServerSideEncryption() string         { return "" }
+func (noObject) StorageClass() string                 { return "" }
+func (noObject) VersionId() string                    { return "" }
+func (noObject) WebsiteRedirectLocation() string      { return "" }
@@ -0,0 +1,71 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/protsack-stephan/dev-toolkit/lib/mem"
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+var fileInfoTestData = []byte("hello file info")
+
+func TestFileInfo(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	store := mem.NewStorage()
+	options := &storage.PutOptions{
+		ContentType:     "text/plain",
+		ContentEncoding: "identity",
+		CacheControl:    "no-cache",
+	}
+	assert.NoError(store.PutWithOptions(ctx, "dir/a.txt", bytes.NewReader(fileInfoTestData), options))
+
+	t.Run("core properties", func(t *testing.T) {
+		info, err := store.Stat("dir/a.txt")
+		assert.NoError(err)
+		assert.Equal("a.txt", info.Name())
+		assert.Equal(int64(len(fileInfoTestData)), info.Size())
+		assert.False(info.ModTime().IsZero())
+		assert.False(info.IsDir())
+		assert.NotEmpty(info.ETag())
+		assert.Equal("text/plain", info.ContentType())
+	})
+
+	t.Run("content headers", func(t *testing.T) {
+		info, err := store.Stat("dir/a.txt")
+		assert.NoError(err)
+		assert.Equal("no-cache", storage.Content(info).CacheControl())
+		assert.Equal("identity", storage.Content(info).ContentEncoding())
+
+		info, err = storage.WithChecksum(store, storage.ChecksumSHA256).Stat("dir/a.txt")
+		assert.NoError(err)
+		assert.Equal("no-cache", storage.Content(info).CacheControl())
+
+		info, err = storage.NewMock().Stat("dir/a.txt")
+		assert.NoError(err)
+		assert.Empty(storage.Content(info).CacheControl())
+	})
+
+	t.Run("object properties", func(t *testing.T) {
+		info, err := store.Stat("dir/a.txt")
+		assert.NoError(err)
+
+		_, ok := storage.Object(info)
+		assert.False(ok)
+	})
+
+	t.Run("legacy file info", func(t *testing.T) {
+		info, err := store.Stat("dir/a.txt")
+		assert.NoError(err)
+
+		legacy := storage.Legacy(info)
+		assert.Equal(info.ModTime(), legacy.LastModified())
+		assert.Equal(int64(len(fileInfoTestData)), legacy.Size())
+		assert.Equal("no-cache", legacy.CacheControl())
+		assert.Empty(legacy.StorageClass())
+		assert.Empty(legacy.VersionId())
+	})
+}
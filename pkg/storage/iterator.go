@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// Entry single object or directory in the storage listing.
+// ContentEncoding is set only by the storages that know it without extra requests (mem and fs, not s3).
+type Entry struct {
+	Path            string
+	Size            int64
+	LastModified    time.Time
+	ETag            string
+	ContentEncoding string
+	IsDir           bool
+}
+
+// Iterator goes through the listing page by page, check Err after Next returns false
+type Iterator interface {
+	// Next advance to the next entry, returns false when there are no entries left or on error
+	Next() bool
+
+	// Entry get current entry
+	Entry() *Entry
+
+	// Err get the error that stopped the iteration
+	Err() error
+
+	// Token get continuation token to resume the listing after the current entry
+	Token() string
+}
+
+// ListIterator paginated listing of the path.
+// Without the "delimiter" option lists all of the objects under the path recursively,
+// with the "delimiter" lists only direct children of the path and reports prefixes as directories.
+// Pass the "token" option to resume listing after the entry the token was taken from.
+type ListIterator interface {
+	ListIter(ctx context.Context, path string, options ...map[string]interface{}) Iterator
+}
+
+// errIterator iterator that fails right away
+type errIterator struct {
+	err error
+}
+
+// Next always returns false
+func (it *errIterator) Next() bool {
+	return false
+}
+
+// Entry always returns nil
+func (it *errIterator) Entry() *Entry {
+	return nil
+}
+
+// Err get the error
+func (it *errIterator) Err() error {
+	return it.err
+}
+
+// Token always returns empty token
+func (it *errIterator) Token() string {
+	return ""
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrInvalidKey encryption key has wrong size or format
+var ErrInvalidKey = errors.New("invalid encryption key")
+
+// keyWrapAAD binds the wrapped keys to their purpose
+var keyWrapAAD = []byte("dev-toolkit storage data key")
+
+// KeyProvider wraps the per-object data keys with the key encryption key, for example a local key or a KMS
+type KeyProvider interface {
+	// WrapKey encrypt the data key, the result is stored with the object
+	WrapKey(ctx context.Context, key []byte) ([]byte, error)
+
+	// UnwrapKey decrypt the data key that was wrapped by WrapKey
+	UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// NewLocalKeyProvider wrap the data keys with AES-256-GCM, key has to be 32 bytes
+func NewLocalKeyProvider(key []byte) (*LocalKeyProvider, error) {
+	aead, err := newGCM(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalKeyProvider{aead}, nil
+}
+
+// LoadKeyFile create local key provider from the key file.
+// The file contains 32 raw bytes or the key encoded with hex or base64, surrounding whitespace is ignored.
+func LoadKeyFile(path string) (*LocalKeyProvider, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == encryptKeySize {
+		return NewLocalKeyProvider(data)
+	}
+
+	text := string(bytes.TrimSpace(data))
+
+	if key, err := hex.DecodeString(text); err == nil {
+		return NewLocalKeyProvider(key)
+	}
+
+	if key, err := base64.StdEncoding.DecodeString(text); err == nil {
+		return NewLocalKeyProvider(key)
+	}
+
+	return nil, fmt.Errorf("%w: '%s' is not raw, hex or base64 key", ErrInvalidKey, path)
+}
+
+// LocalKeyProvider key provider with the key kept in memory
+type LocalKeyProvider struct {
+	aead cipher.AEAD
+}
+
+// WrapKey encrypt the data key with random nonce
+func (p *LocalKeyProvider) WrapKey(_ context.Context, key []byte) ([]byte, error) {
+	nonce := make([]byte, p.aead.NonceSize())
+
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return p.aead.Seal(nonce, nonce, key, keyWrapAAD), nil
+}
+
+// UnwrapKey decrypt the data key, returns ErrDecrypt if the key was wrapped with other key
+func (p *LocalKeyProvider) UnwrapKey(_ context.Context, wrapped []byte) ([]byte, error) {
+	size := p.aead.NonceSize()
+
+	if len(wrapped) < size {
+		return nil, ErrDecrypt
+	}
+
+	key, err := p.aead.Open(nil, wrapped[:size], wrapped[size:], keyWrapAAD)
+
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return key, nil
+}
+
+// newGCM create AES-256-GCM cipher
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != encryptKeySize {
+		return nil, fmt.Errorf("%w: key has %d bytes instead of %d", ErrInvalidKey, len(key), encryptKeySize)
+	}
+
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
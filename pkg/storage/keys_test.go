@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalKeyProvider(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	master := bytes.Repeat([]byte{1}, encryptKeySize)
+	dataKey := bytes.Repeat([]byte{2}, encryptKeySize)
+
+	t.Run("wrap and unwrap", func(t *testing.T) {
+		keys, err := NewLocalKeyProvider(master)
+		assert.NoError(err)
+
+		wrapped, err := keys.WrapKey(ctx, dataKey)
+		assert.NoError(err)
+		assert.NotContains(string(wrapped), string(dataKey))
+
+		key, err := keys.UnwrapKey(ctx, wrapped)
+		assert.NoError(err)
+		assert.Equal(dataKey, key)
+	})
+
+	t.Run("unwrap with other key", func(t *testing.T) {
+		keys, err := NewLocalKeyProvider(master)
+		assert.NoError(err)
+		other, err := NewLocalKeyProvider(dataKey)
+		assert.NoError(err)
+
+		wrapped, err := keys.WrapKey(ctx, dataKey)
+		assert.NoError(err)
+
+		_, err = other.UnwrapKey(ctx, wrapped)
+		assert.ErrorIs(err, ErrDecrypt)
+
+		_, err = other.UnwrapKey(ctx, []byte("short"))
+		assert.ErrorIs(err, ErrDecrypt)
+	})
+
+	t.Run("invalid key size", func(t *testing.T) {
+		_, err := NewLocalKeyProvider([]byte("short"))
+		assert.ErrorIs(err, ErrInvalidKey)
+	})
+
+	t.Run("load key file", func(t *testing.T) {
+		dir := t.TempDir()
+
+		for name, data := range map[string][]byte{
+			"raw":    master,
+			"hex":    []byte(hex.EncodeToString(master) + "\n"),
+			"base64": []byte(base64.StdEncoding.EncodeToString(master) + "\n"),
+		} {
+			path := filepath.Join(dir, name)
+			assert.NoError(os.WriteFile(path, data, 0600))
+
+			keys, err := LoadKeyFile(path)
+			assert.NoError(err, name)
+
+			if err != nil {
+				continue
+			}
+
+			wrapped, err := keys.WrapKey(ctx, dataKey)
+			assert.NoError(err)
+			key, err := keys.UnwrapKey(ctx, wrapped)
+			assert.NoError(err)
+			assert.Equal(dataKey, key)
+		}
+
+		path := filepath.Join(dir, "invalid")
+		assert.NoError(os.WriteFile(path, []byte("not a key"), 0600))
+		_, err := LoadKeyFile(path)
+		assert.ErrorIs(err, ErrInvalidKey)
+
+		_, err = LoadKeyFile(filepath.Join(dir, "missing"))
+		assert.ErrorIs(err, ErrNotExist)
+	})
+}
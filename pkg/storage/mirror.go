@@ -0,0 +1,530 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MirrorMode how the writes are replicated to the secondaries
+type MirrorMode int
+
+const (
+	// MirrorSync writes to every backend before returning, all of them must succeed
+	MirrorSync MirrorMode = iota
+
+	// MirrorAsync writes to the primary and replicates to the secondaries in background,
+	// every secondary gets the writes in the order they were made on the primary
+	MirrorAsync
+)
+
+// MirrorError status of every backend of the mirror operation.
+// Errs has the primary first and then the secondaries in order, nil for the backends that succeeded.
+type MirrorError struct {
+	Op   string
+	Path string
+	Errs []error
+}
+
+// Error get error message
+func (e *MirrorError) Error() string {
+	msgs := []string{}
+
+	for i, err := range e.Errs {
+		if err == nil {
+			continue
+		}
+
+		if i == 0 {
+			msgs = append(msgs, fmt.Sprintf("primary: %v", err))
+		} else {
+			msgs = append(msgs, fmt.Sprintf("secondary %d: %v", i, err))
+		}
+	}
+
+	return fmt.Sprintf("mirror %s %s: %s", e.Op, e.Path, strings.Join(msgs, "; "))
+}
+
+// Unwrap get the first of the errors, so errors.Is works for the primary error first
+func (e *MirrorError) Unwrap() error {
+	for _, err := range e.Errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newMirrorError create the error if any of the backends failed
+func newMirrorError(op string, path string, errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return &MirrorError{Op: op, Path: path, Errs: errs}
+		}
+	}
+
+	return nil
+}
+
+// Mirror storage that writes Put, Delete, Copy and Create to every backend and reads from the primary,
+// falling back to the secondaries in order on errors. Secondaries get the written objects from the primary.
+func Mirror(primary Storage, secondaries ...Storage) *MirrorStorage {
+	queues := make([]*mirrorQueue, len(secondaries))
+
+	for i := range queues {
+		queues[i] = new(mirrorQueue)
+	}
+
+	return &MirrorStorage{
+		Mode:        MirrorSync,
+		primary:     primary,
+		secondaries: secondaries,
+		queues:      queues,
+	}
+}
+
+// MirrorStorage mirrored writes with read fallback
+type MirrorStorage struct {
+	// Mode replication mode, MirrorSync by default
+	Mode MirrorMode
+
+	// OnError gets the failures of the background replication in MirrorAsync mode
+	OnError func(err *MirrorError)
+
+	primary     Storage
+	secondaries []Storage
+	queues      []*mirrorQueue
+	wg          sync.WaitGroup
+}
+
+// mirrorTask background replication of one write
+type mirrorTask struct {
+	op        string
+	path      string
+	secondary func(ctx context.Context, store Storage) error
+	mu        sync.Mutex
+	errs      []error
+	pending   int
+}
+
+// mirrorQueue writes that wait for the replication to one secondary, in the order they were made
+type mirrorQueue struct {
+	mu      sync.Mutex
+	tasks   []*mirrorTask
+	running bool
+}
+
+// Wait blocks until the background replication is done
+func (m *MirrorStorage) Wait() {
+	m.wg.Wait()
+}
+
+func (m *MirrorStorage) backends() []Storage {
+	return append([]Storage{m.primary}, m.secondaries...)
+}
+
+// write run the operation on the primary and replicate it to the secondaries
+func (m *MirrorStorage) write(ctx context.Context, op string, path string, primary func(ctx context.Context, store Storage) error, secondary func(ctx context.Context, store Storage) error) error {
+	if err := primary(ctx, m.primary); err != nil {
+		return newMirrorError(op, path, append([]error{err}, make([]error, len(m.secondaries))...))
+	}
+
+	if m.Mode == MirrorAsync {
+		m.enqueue(&mirrorTask{
+			op:        op,
+			path:      path,
+			secondary: secondary,
+			errs:      make([]error, len(m.secondaries)+1),
+			pending:   len(m.secondaries),
+		})
+
+		return nil
+	}
+
+	errs := make([]error, len(m.secondaries)+1)
+	wg := new(sync.WaitGroup)
+
+	for i, store := range m.secondaries {
+		wg.Add(1)
+
+		go func(i int, store Storage) {
+			defer wg.Done()
+			errs[i+1] = secondary(ctx, store)
+		}(i, store)
+	}
+
+	wg.Wait()
+
+	return newMirrorError(op, path, errs)
+}
+
+// enqueue replicate the write in background, every secondary applies the writes one by one in order,
+// so Put followed by Delete of the same path can't be reordered
+func (m *MirrorStorage) enqueue(task *mirrorTask) {
+	if len(m.secondaries) == 0 {
+		return
+	}
+
+	m.wg.Add(1)
+
+	for i, queue := range m.queues {
+		queue.mu.Lock()
+		queue.tasks = append(queue.tasks, task)
+		start := !queue.running
+		queue.running = true
+		queue.mu.Unlock()
+
+		if start {
+			go m.drain(i, queue)
+		}
+	}
+}
+
+// drain replicate the queued writes to the secondary until the queue is empty
+func (m *MirrorStorage) drain(i int, queue *mirrorQueue) {
+	for {
+		queue.mu.Lock()
+
+		if len(queue.tasks) == 0 {
+			queue.running = false
+			queue.mu.Unlock()
+			return
+		}
+
+		task := queue.tasks[0]
+		queue.tasks[0] = nil
+		queue.tasks = queue.tasks[1:]
+		queue.mu.Unlock()
+
+		m.done(task, i, task.secondary(context.Background(), m.secondaries[i]))
+	}
+}
+
+// done record the result of the secondary, reports the errors when every secondary is done with the task
+func (m *MirrorStorage) done(task *mirrorTask, i int, err error) {
+	task.mu.Lock()
+	task.errs[i+1] = err
+	task.pending--
+	last := task.pending == 0
+	task.mu.Unlock()
+
+	if !last {
+		return
+	}
+
+	defer m.wg.Done()
+
+	if err := newMirrorError(task.op, task.path, task.errs); err != nil && m.OnError != nil {
+		m.OnError(err.(*MirrorError))
+	}
+}
+
+// replicate copy the object from the primary to the secondary
+func (m *MirrorStorage) replicate(ctx context.Context, store Storage, path string, options *PutOptions) error {
+	body, err := m.primary.GetWithContext(ctx, path)
+
+	if err != nil {
+		return err
+	}
+
+	defer body.Close()
+
+	if putter, ok := store.(PutterWithOptions); ok && options != nil {
+		return putter.PutWithOptions(ctx, path, body, options)
+	}
+
+	return store.PutWithContext(ctx, path, body)
+}
+
+// read try the backends in order until one of them succeeds
+func (m *MirrorStorage) read(op string, path string, fn func(store Storage) error) error {
+	errs := []error{}
+
+	for _, store := range m.backends() {
+		err := fn(store)
+
+		if err == nil {
+			return nil
+		}
+
+		errs = append(errs, err)
+	}
+
+	return newMirrorError(op, path, errs)
+}
+
+// List get the contents of the path
+func (m *MirrorStorage) List(path string, options ...map[string]interface{}) ([]string, error) {
+	return m.ListWithContext(context.Background(), path, options...)
+}
+
+// ListWithContext get the contents of the path
+func (m *MirrorStorage) ListWithContext(ctx context.Context, path string, options ...map[string]interface{}) ([]string, error) {
+	var paths []string
+
+	err := m.read("list", path, func(store Storage) (err error) {
+		paths, err = store.ListWithContext(ctx, path, options...)
+		return err
+	})
+
+	return paths, err
+}
+
+// ListIter paginated listing of the primary
+func (m *MirrorStorage) ListIter(ctx context.Context, path string, options ...map[string]interface{}) Iterator {
+	lister, ok := m.primary.(ListIterator)
+
+	if !ok {
+		return &errIterator{ErrNotSupported}
+	}
+
+	return lister.ListIter(ctx, path, options...)
+}
+
+// Walk recursively look for files in directory, falls back until the callback is called for the first time
+func (m *MirrorStorage) Walk(path string, callback func(path string)) error {
+	return m.WalkWithContext(context.Background(), path, callback)
+}
+
+// WalkWithContext recursively look for files in directory, falls back until the callback is called for the first time
+func (m *MirrorStorage) WalkWithContext(ctx context.Context, path string, callback func(path string)) error {
+	called := false
+	var err error
+
+	_ = m.read("walk", path, func(store Storage) error {
+		err = store.WalkWithContext(ctx, path, func(path string) {
+			called = true
+			callback(path)
+		})
+
+		if called {
+			return nil
+		}
+
+		return err
+	})
+
+	return err
+}
+
+// WalkFunc recursively look for files and directories of the primary
+func (m *MirrorStorage) WalkFunc(ctx context.Context, path string, callback func(entry *Entry) error) error {
+	walker, ok := m.primary.(WalkerFunc)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	return walker.WalkFunc(ctx, path, callback)
+}
+
+// Copy copies an object in every backend
+func (m *MirrorStorage) Copy(src string, dst string, options ...map[string]interface{}) error {
+	return m.CopyWithContext(context.Background(), src, dst, options...)
+}
+
+// CopyWithContext copies an object in every backend
+func (m *MirrorStorage) CopyWithContext(ctx context.Context, src string, dst string, options ...map[string]interface{}) error {
+	cp := func(ctx context.Context, store Storage) error {
+		return store.CopyWithContext(ctx, src, dst, options...)
+	}
+
+	return m.write(ctx, "copy", dst, cp, cp)
+}
+
+// Move moves an object in every backend
+func (m *MirrorStorage) Move(ctx context.Context, src string, dst string) error {
+	move := func(ctx context.Context, store Storage) error {
+		return Move(ctx, store, src, dst)
+	}
+
+	return m.write(ctx, "move", src, move, move)
+}
+
+// Create create new file in the primary, the file is replicated to the secondaries on Close
+func (m *MirrorStorage) Create(path string) (io.ReadWriteCloser, error) {
+	return m.CreateWithContext(context.Background(), path)
+}
+
+// CreateWithContext create new file in the primary, the file is replicated to the secondaries on Close
+func (m *MirrorStorage) CreateWithContext(ctx context.Context, path string) (io.ReadWriteCloser, error) {
+	var file io.ReadWriteCloser
+	var err error
+
+	if creator, ok := m.primary.(CreatorWithContext); ok {
+		file, err = creator.CreateWithContext(ctx, path)
+	} else {
+		file, err = m.primary.Create(path)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &mirrorFile{
+		ReadWriteCloser: file,
+		close: func() error {
+			return m.write(ctx, "create", path, func(_ context.Context, _ Storage) error {
+				return file.Close()
+			}, func(ctx context.Context, store Storage) error {
+				return m.replicate(ctx, store, path, nil)
+			})
+		},
+	}, nil
+}
+
+// Get get object from the primary or from the first secondary that has it
+func (m *MirrorStorage) Get(path string) (io.ReadCloser, error) {
+	return m.GetWithContext(context.Background(), path)
+}
+
+// GetWithContext get object from the primary or from the first secondary that has it
+func (m *MirrorStorage) GetWithContext(ctx context.Context, path string) (io.ReadCloser, error) {
+	var body io.ReadCloser
+
+	err := m.read("get", path, func(store Storage) (err error) {
+		body, err = store.GetWithContext(ctx, path)
+		return err
+	})
+
+	return body, err
+}
+
+// GetRange get part of the object from the primary or from the first secondary that has it
+func (m *MirrorStorage) GetRange(ctx context.Context, path string, offset int64, length int64) (io.ReadCloser, error) {
+	var body io.ReadCloser
+
+	err := m.read("get", path, func(store Storage) (err error) {
+		getter, ok := store.(RangeGetter)
+
+		if !ok {
+			return ErrNotSupported
+		}
+
+		body, err = getter.GetRange(ctx, path, offset, length)
+		return err
+	})
+
+	return body, err
+}
+
+// Put object into every backend
+func (m *MirrorStorage) Put(path string, body io.Reader) error {
+	return m.PutWithContext(context.Background(), path, body)
+}
+
+// PutWithContext object into every backend
+func (m *MirrorStorage) PutWithContext(ctx context.Context, path string, body io.Reader) error {
+	return m.write(ctx, "put", path, func(ctx context.Context, store Storage) error {
+		return store.PutWithContext(ctx, path, body)
+	}, func(ctx context.Context, store Storage) error {
+		return m.replicate(ctx, store, path, nil)
+	})
+}
+
+// PutWithOptions object into every backend together with its attributes
+func (m *MirrorStorage) PutWithOptions(ctx context.Context, path string, body io.Reader, options *PutOptions) error {
+	return m.write(ctx, "put", path, func(ctx context.Context, store Storage) error {
+		putter, ok := store.(PutterWithOptions)
+
+		if !ok {
+			return ErrNotSupported
+		}
+
+		return putter.PutWithOptions(ctx, path, body, options)
+	}, func(ctx context.Context, store Storage) error {
+		return m.replicate(ctx, store, path, options)
+	})
+}
+
+// SetMetadata replace user metadata of the object in every backend
+func (m *MirrorStorage) SetMetadata(ctx context.Context, path string, metadata map[string]string) error {
+	set := func(ctx context.Context, store Storage) error {
+		setter, ok := store.(MetadataSetter)
+
+		if !ok {
+			return ErrNotSupported
+		}
+
+		return setter.SetMetadata(ctx, path, metadata)
+	}
+
+	return m.write(ctx, "metadata", path, set, set)
+}
+
+// Link generate expiration link for the primary or the first secondary that can do it
+func (m *MirrorStorage) Link(path string, expire time.Duration) (string, error) {
+	var link string
+
+	err := m.read("link", path, func(store Storage) (err error) {
+		link, err = store.Link(path, expire)
+		return err
+	})
+
+	return link, err
+}
+
+// Delete remove object from every backend
+func (m *MirrorStorage) Delete(path string) error {
+	return m.DeleteWithContext(context.Background(), path)
+}
+
+// DeleteWithContext remove object from every backend
+func (m *MirrorStorage) DeleteWithContext(ctx context.Context, path string) error {
+	del := func(ctx context.Context, store Storage) error {
+		return store.DeleteWithContext(ctx, path)
+	}
+
+	return m.write(ctx, "delete", path, del, del)
+}
+
+// DeleteMany deletes the objects from every backend
+func (m *MirrorStorage) DeleteMany(ctx context.Context, paths []string) error {
+	del := func(ctx context.Context, store Storage) error {
+		return DeleteMany(ctx, store, paths)
+	}
+
+	return m.write(ctx, "delete", fmt.Sprintf("%d paths", len(paths)), del, del)
+}
+
+// DeleteAll deletes the object at the path and every object under it from every backend
+func (m *MirrorStorage) DeleteAll(ctx context.Context, prefix string) error {
+	del := func(ctx context.Context, store Storage) error {
+		return DeleteAll(ctx, store, prefix)
+	}
+
+	return m.write(ctx, "delete", prefix, del, del)
+}
+
+// Stat get file information from the primary or from the first secondary that has it
+func (m *MirrorStorage) Stat(path string) (FileInfo, error) {
+	var info FileInfo
+
+	err := m.read("stat", path, func(store Storage) (err error) {
+		info, err = store.Stat(path)
+		return err
+	})
+
+	return info, err
+}
+
+// mirrorFile file that is replicated on Close
+type mirrorFile struct {
+	io.ReadWriteCloser
+	close func() error
+	once  sync.Once
+	err   error
+}
+
+// Close closes the file and replicates it to the secondaries
+func (f *mirrorFile) Close() error {
+	f.once.Do(func() {
+		f.err = f.close()
+	})
+
+	return f.err
+}
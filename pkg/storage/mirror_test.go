@@ -0,0 +1,199 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/dev-toolkit/lib/mem"
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+var mirrorTestData = []byte("hello mirror")
+
+var errMirrorTestFailed = errors.New("backend is down")
+
+// failingStorage rejects all writes
+type failingStorage struct {
+	*mem.Storage
+}
+
+func (s *failingStorage) PutWithContext(ctx context.Context, path string, body io.Reader) error {
+	return errMirrorTestFailed
+}
+
+func (s *failingStorage) DeleteWithContext(ctx context.Context, path string) error {
+	return errMirrorTestFailed
+}
+
+// slowStorage takes time to upload the objects
+type slowStorage struct {
+	*mem.Storage
+}
+
+func (s *slowStorage) PutWithContext(ctx context.Context, path string, body io.Reader) error {
+	data, err := io.ReadAll(body)
+
+	if err != nil {
+		return err
+	}
+
+	time.Sleep(time.Millisecond * 50)
+
+	return s.Storage.PutWithContext(ctx, path, bytes.NewReader(data))
+}
+
+func TestMirror(t *testing.T) {
+	assert := assert.New(t)
+
+	read := func(store storage.Storage, path string) []byte {
+		body, err := store.Get(path)
+
+		if err != nil {
+			return nil
+		}
+
+		defer body.Close()
+		data, err := io.ReadAll(body)
+		assert.NoError(err)
+
+		return data
+	}
+
+	t.Run("put to every backend", func(t *testing.T) {
+		primary, secondary := mem.NewStorage(), mem.NewStorage()
+		store := storage.Mirror(primary, secondary)
+
+		assert.NoError(store.Put("a.txt", bytes.NewReader(mirrorTestData)))
+		assert.Equal(mirrorTestData, read(primary, "a.txt"))
+		assert.Equal(mirrorTestData, read(secondary, "a.txt"))
+	})
+
+	t.Run("put with options to every backend", func(t *testing.T) {
+		primary, secondary := mem.NewStorage(), mem.NewStorage()
+		store := storage.Mirror(primary, secondary)
+
+		assert.NoError(store.PutWithOptions(context.Background(), "a.txt", bytes.NewReader(mirrorTestData), &storage.PutOptions{ContentType: "text/plain"}))
+
+		info, err := secondary.Stat("a.txt")
+		assert.NoError(err)
+		assert.Equal("text/plain", info.ContentType())
+	})
+
+	t.Run("create is replicated on close", func(t *testing.T) {
+		primary, secondary := mem.NewStorage(), mem.NewStorage()
+		store := storage.Mirror(primary, secondary)
+
+		file, err := store.Create("a.txt")
+		assert.NoError(err)
+		_, err = file.Write(mirrorTestData)
+		assert.NoError(err)
+		assert.NoError(file.Close())
+		assert.Equal(mirrorTestData, read(secondary, "a.txt"))
+	})
+
+	t.Run("copy and delete in every backend", func(t *testing.T) {
+		primary, secondary := mem.NewStorage(), mem.NewStorage()
+		store := storage.Mirror(primary, secondary)
+
+		assert.NoError(store.Put("a.txt", bytes.NewReader(mirrorTestData)))
+		assert.NoError(store.Copy("a.txt", "b.txt"))
+		assert.Equal(mirrorTestData, read(secondary, "b.txt"))
+
+		assert.NoError(store.Delete("a.txt"))
+		assert.Nil(read(primary, "a.txt"))
+		assert.Nil(read(secondary, "a.txt"))
+	})
+
+	t.Run("report failed secondary", func(t *testing.T) {
+		primary := mem.NewStorage()
+		store := storage.Mirror(primary, mem.NewStorage(), &failingStorage{mem.NewStorage()})
+
+		err := store.Put("a.txt", bytes.NewReader(mirrorTestData))
+		assert.ErrorIs(err, errMirrorTestFailed)
+
+		merr := new(storage.MirrorError)
+		assert.True(errors.As(err, &merr))
+		assert.Equal("put", merr.Op)
+		assert.Equal("a.txt", merr.Path)
+		assert.Len(merr.Errs, 3)
+		assert.NoError(merr.Errs[0])
+		assert.NoError(merr.Errs[1])
+		assert.ErrorIs(merr.Errs[2], errMirrorTestFailed)
+		assert.Equal(mirrorTestData, read(primary, "a.txt"))
+	})
+
+	t.Run("failed primary is not replicated", func(t *testing.T) {
+		secondary := mem.NewStorage()
+		store := storage.Mirror(&failingStorage{mem.NewStorage()}, secondary)
+
+		err := store.Put("a.txt", bytes.NewReader(mirrorTestData))
+		assert.ErrorIs(err, errMirrorTestFailed)
+		assert.Nil(read(secondary, "a.txt"))
+	})
+
+	t.Run("replicate in background", func(t *testing.T) {
+		secondary := mem.NewStorage()
+		store := storage.Mirror(mem.NewStorage(), secondary, &failingStorage{mem.NewStorage()})
+		store.Mode = storage.MirrorAsync
+
+		mu := new(sync.Mutex)
+		errs := []*storage.MirrorError{}
+		store.OnError = func(err *storage.MirrorError) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		}
+
+		assert.NoError(store.Put("a.txt", bytes.NewReader(mirrorTestData)))
+		store.Wait()
+
+		assert.Equal(mirrorTestData, read(secondary, "a.txt"))
+		assert.Len(errs, 1)
+		assert.ErrorIs(errs[0].Errs[2], errMirrorTestFailed)
+	})
+
+	t.Run("replicate in background in order", func(t *testing.T) {
+		secondary := &slowStorage{mem.NewStorage()}
+		store := storage.Mirror(mem.NewStorage(), secondary)
+		store.Mode = storage.MirrorAsync
+
+		assert.NoError(store.Put("a.txt", bytes.NewReader(mirrorTestData)))
+		time.Sleep(time.Millisecond * 10)
+		assert.NoError(store.Delete("a.txt"))
+		assert.NoError(store.Put("b.txt", bytes.NewReader(mirrorTestData)))
+		store.Wait()
+
+		_, err := secondary.Stat("a.txt")
+		assert.ErrorIs(err, storage.ErrNotExist)
+		assert.Equal(mirrorTestData, read(secondary, "b.txt"))
+	})
+
+	t.Run("read falls back to secondaries", func(t *testing.T) {
+		secondary := mem.NewStorage()
+		store := storage.Mirror(mem.NewStorage(), secondary)
+		assert.NoError(secondary.Put("a.txt", bytes.NewReader(mirrorTestData)))
+
+		assert.Equal(mirrorTestData, read(store, "a.txt"))
+
+		info, err := store.Stat("a.txt")
+		assert.NoError(err)
+		assert.Equal(int64(len(mirrorTestData)), info.Size())
+	})
+
+	t.Run("read fails on every backend", func(t *testing.T) {
+		store := storage.Mirror(mem.NewStorage(), mem.NewStorage())
+
+		_, err := store.Get("missing.txt")
+		assert.ErrorIs(err, storage.ErrNotExist)
+
+		merr := new(storage.MirrorError)
+		assert.True(errors.As(err, &merr))
+		assert.Len(merr.Errs, 2)
+	})
+}
@@ -25,6 +25,11 @@ func (Mock) ListWithContext(ctx context.Context, path string, options ...map[str
 	return []string{}, nil
 }
 
+// ListIter paginated listing of the path
+func (Mock) ListIter(ctx context.Context, path string, options ...map[string]interface{}) Iterator {
+	return new(IteratorMock)
+}
+
 // Walk recursively look for files in directory
 func (Mock) Walk(path string, callback func(path string)) error {
 	return nil
@@ -35,6 +40,11 @@ func (Mock) WalkWithContext(ctx context.Context, path string, callback func(path
 	return nil
 }
 
+// WalkFunc recursively look for files and directories
+func (Mock) WalkFunc(ctx context.Context, path string, callback func(entry *Entry) error) error {
+	return nil
+}
+
 // Copy copies an object from the a path in a bucket to another path in the same or different bucket.
 func (Mock) Copy(src string, dst string, options ...map[string]interface{}) error {
 	return nil
@@ -45,11 +55,21 @@ func (Mock) CopyWithContext(ctx context.Context, src string, dst string, options
 	return nil
 }
 
+// Move moves an object to another path
+func (Mock) Move(ctx context.Context, src string, dst string) error {
+	return nil
+}
+
 // Create for create object in storage
 func (Mock) Create(path string) (io.ReadWriteCloser, error) {
 	return nil, nil
 }
 
+// CreateWithContext for create object in storage
+func (Mock) CreateWithContext(ctx context.Context, path string) (io.ReadWriteCloser, error) {
+	return nil, nil
+}
+
 // Get get object from storage
 func (Mock) Get(path string) (io.ReadCloser, error) {
 	return io.NopCloser(bytes.NewReader([]byte{})), nil
@@ -60,6 +80,11 @@ func (Mock) GetWithContext(ctx context.Context, path string) (io.ReadCloser, err
 	return io.NopCloser(bytes.NewReader([]byte{})), nil
 }
 
+// GetRange get part of the object from storage
+func (Mock) GetRange(ctx context.Context, path string, offset int64, length int64) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader([]byte{})), nil
+}
+
 // Put object into storage
 func (Mock) Put(path string, body io.Reader) error {
 	return nil
@@ -70,6 +95,16 @@ func (Mock) PutWithContext(ctx context.Context, path string, body io.Reader) err
 	return nil
 }
 
+// PutWithOptions object into storage together with its attributes
+func (Mock) PutWithOptions(ctx context.Context, path string, body io.Reader, options *PutOptions) error {
+	return nil
+}
+
+// SetMetadata replace user metadata of the object
+func (Mock) SetMetadata(ctx context.Context, path string, metadata map[string]string) error {
+	return nil
+}
+
 // Link generate expiration link for storage
 func (Mock) Link(path string, expire time.Duration) (string, error) {
 	return "", nil
@@ -85,82 +120,75 @@ func (Mock) DeleteWithContext(ctx context.Context, path string) error {
 	return nil
 }
 
-// Stat get object info
-func (Mock) Stat(path string) (FileInfo, error) {
-	return new(FileInfoMock), nil
+// DeleteMany delete objects from storage
+func (Mock) DeleteMany(ctx context.Context, paths []string) error {
+	return nil
 }
 
-// FileInfoMock mock for file information
-type FileInfoMock struct{}
-
-// Size get file info size
-func (FileInfoMock) Size() int64 {
-	return 0
+// DeleteAll delete the object at the path and every object under it
+func (Mock) DeleteAll(ctx context.Context, prefix string) error {
+	return nil
 }
 
-// Size get file info accept-ranges
-func (FileInfoMock) AcceptRanges() string {
-	return "accept-ranges"
+// Stat get object info
+func (Mock) Stat(path string) (FileInfo, error) {
+	return new(FileInfoMock), nil
 }
 
-// Size get file info Cache-Control
-func (FileInfoMock) CacheControl() string {
-	return "Cache-Control"
-}
+// IteratorMock mock for listing iterator
+type IteratorMock struct{}
 
-// Size get file info Content-Disposition
-func (FileInfoMock) ContentDisposition() string {
-	return "Content-Disposition"
+// Next advance to the next entry
+func (IteratorMock) Next() bool {
+	return false
 }
 
-// Size get file info Content-Encoding
-func (FileInfoMock) ContentEncoding() string {
-	return "Content-Encoding"
+// Entry get current entry
+func (IteratorMock) Entry() *Entry {
+	return nil
 }
 
-// Size get file info Content-Language
-func (FileInfoMock) ContentLanguage() string {
-	return "Content-Language"
+// Err get iteration error
+func (IteratorMock) Err() error {
+	return nil
 }
 
-// Size get file info Content-Type
-func (FileInfoMock) ContentType() string {
-	return "Content-Type"
+// Token get continuation token
+func (IteratorMock) Token() string {
+	return ""
 }
 
-// Size get file info ETag
-func (FileInfoMock) ETag() string {
-	return "ETag"
-}
+// FileInfoMock mock for file information
+type FileInfoMock struct{}
 
-// Size get file info Expires
-func (FileInfoMock) Expires() string {
-	return "Expires"
+// Name get file info name
+func (FileInfoMock) Name() string {
+	return "name"
 }
 
-// Size get file info Last-Modified
-func (FileInfoMock) LastModified() time.Time {
-	return time.Now()
+// Size get file info size
+func (FileInfoMock) Size() int64 {
+	return 0
 }
 
-// ActiveStatus get ActiveStatus
-func (FileInfoMock) ActiveStatus() string {
-	return "activeStatus"
+// ModTime get file info Last-Modified
+func (FileInfoMock) ModTime() time.Time {
+	return time.Now()
 }
 
-// BucketKeyEnabled get BucketKeyEnabled
-func (FileInfoMock) BucketKeyEnabled() bool {
-	return true
+// IsDir get file info directory flag
+func (FileInfoMock) IsDir() bool {
+	return false
 }
 
-// DeleteMarker get DeleteMarker
-func (FileInfoMock) DeleteMarker() bool {
-	return true
+// ETag get file info ETag
+func (FileInfoMock) ETag() string {
+	return "ETag"
 }
 
-// Expiration get Expiration
-func (FileInfoMock) Expiration() string {
-	return "expiration"
+// ContentType get file info Content-Type
+func (FileInfoMock) ContentType() string {
+	return "Content-Type"
 }
 
 // Metadata get Metadata
@@ -168,77 +196,7 @@ func (FileInfoMock) Metadata() map[string]*string {
 	return map[string]*string{}
 }
 
-// MissingMeta get MissingMeta
-func (FileInfoMock) MissingMeta() int64 {
-	return 1
-}
-
-// ObjectLockLegalHoldStatus get ObjectLockLegalHoldStatus
-func (FileInfoMock) ObjectLockLegalHoldStatus() string {
-	return "objectLockLegalHoldStatus"
-}
-
-// ObjectLockMode get ObjectLockMode
-func (FileInfoMock) ObjectLockMode() string {
-	return "objectLockMode"
-}
-
-// ObjectLockRetainUntilDate get ObjectLockRetainUntilDate
-func (FileInfoMock) ObjectLockRetainUntilDate() time.Time {
-	return time.Now()
-}
-
-// PartsCount get PartsCount
-func (FileInfoMock) PartsCount() int64 {
-	return 1
-}
-
-// ReplicationStatus get ReplicationStatus
-func (FileInfoMock) ReplicationStatus() string {
-	return "replicationStatus"
-}
-
-// RequestCharged get RequestCharged
-func (FileInfoMock) RequestCharged() string {
-	return "requestCharged"
-}
-
-// Restore get Restore
-func (FileInfoMock) Restore() string {
-	return "restore"
-}
-
-// SSECustomerAlgorithm get SSECustomerAlgorithm
-func (FileInfoMock) SSECustomerAlgorithm() string {
-	return "sseCustomerAlgorithm"
-}
-
-// SSECustomerKeyMD5 get SSECustomerKeyMD5
-func (FileInfoMock) SSECustomerKeyMD5() string {
-	return "sseCustomerKeyMD5"
-}
-
-// SSEKMSKeyId get SSEKMSKeyId
-func (FileInfoMock) SSEKMSKeyId() string {
-	return "sseKMSKeyId"
-}
-
-// ServerSideEncryption get ServerSideEncryption
-func (FileInfoMock) ServerSideEncryption() string {
-	return "serverSideEncryption"
-}
-
-// StorageClass get StorageClass
-func (FileInfoMock) StorageClass() string {
-	return "storageClass"
-}
-
-// VersionId get VersionId
-func (FileInfoMock) VersionId() string {
-	return "versionId"
-}
-
-// WebsiteRedirectLocation get WebsiteRedirectLocation
-func (FileInfoMock) WebsiteRedirectLocation() string {
-	return "websiteRedirectLocation"
+// Sys get backend specific file info
+func (FileInfoMock) Sys() interface{} {
+	return nil
 }
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -15,4 +16,32 @@ func TestMock(t *testing.T) {
 	mock := NewMock()
 	assert.NotNil(mock)
 	assert.Nil(testStorage(mock))
+
+	var lister ListIterator = mock
+	iter := lister.ListIter(context.Background(), "/")
+	assert.False(iter.Next())
+	assert.NoError(iter.Err())
+
+	var walker WalkerFunc = mock
+	assert.NoError(walker.WalkFunc(context.Background(), "/", func(entry *Entry) error {
+		return nil
+	}))
+
+	var getter RangeGetter = mock
+	body, err := getter.GetRange(context.Background(), "/", 0, 10)
+	assert.NoError(err)
+	assert.NoError(body.Close())
+
+	var putter PutterWithOptions = mock
+	assert.NoError(putter.PutWithOptions(context.Background(), "/", body, &PutOptions{ContentType: "text/plain"}))
+
+	var setter MetadataSetter = mock
+	assert.NoError(setter.SetMetadata(context.Background(), "/", map[string]string{"Author": "mock"}))
+
+	var mover Mover = mock
+	assert.NoError(mover.Move(context.Background(), "/", "/"))
+
+	var deleter BatchDeleter = mock
+	assert.NoError(deleter.DeleteMany(context.Background(), []string{"/"}))
+	assert.NoError(deleter.DeleteAll(context.Background(), "/"))
 }
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// MoveError failed move, Copied reports that the destination is written but the source could not be removed,
+// so the object exists under both paths
+type MoveError struct {
+	Src    string
+	Dst    string
+	Copied bool
+	Err    error
+}
+
+// Error get error message
+func (e *MoveError) Error() string {
+	if e.Copied {
+		return fmt.Sprintf("move %s to %s: copied, but source is not removed: %v", e.Src, e.Dst, e.Err)
+	}
+
+	return fmt.Sprintf("move %s to %s: %v", e.Src, e.Dst, e.Err)
+}
+
+// Unwrap get the error that stopped the move
+func (e *MoveError) Unwrap() error {
+	return e.Err
+}
+
+// Move moves the object with Mover when storage implements it, otherwise copies the object and removes the source.
+// Moving the object onto itself does nothing.
+func Move(ctx context.Context, store Storage, src string, dst string) error {
+	if mover, ok := store.(Mover); ok {
+		return mover.Move(ctx, src, dst)
+	}
+
+	if src == dst {
+		if _, err := store.Stat(src); err != nil {
+			return &MoveError{Src: src, Dst: dst, Err: err}
+		}
+
+		return nil
+	}
+
+	if err := store.CopyWithContext(ctx, src, dst); err != nil {
+		return &MoveError{Src: src, Dst: dst, Err: err}
+	}
+
+	if err := store.DeleteWithContext(ctx, src); err != nil {
+		return &MoveError{Src: src, Dst: dst, Copied: true, Err: err}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/protsack-stephan/dev-toolkit/lib/mem"
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+var moveTestData = []byte("hello move")
+
+func TestMove(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	read := func(store storage.Storage, path string) []byte {
+		body, err := store.Get(path)
+
+		if err != nil {
+			return nil
+		}
+
+		defer body.Close()
+		data, _ := io.ReadAll(body)
+
+		return data
+	}
+
+	t.Run("move with copy and delete", func(t *testing.T) {
+		store := struct{ storage.Storage }{mem.NewStorage()}
+		assert.NoError(store.Put("a.txt", bytes.NewReader(moveTestData)))
+
+		assert.NoError(storage.Move(ctx, store, "a.txt", "b/a.txt"))
+		assert.Equal(moveTestData, read(store, "b/a.txt"))
+		assert.Nil(read(store, "a.txt"))
+	})
+
+	t.Run("move onto itself", func(t *testing.T) {
+		store := struct{ storage.Storage }{mem.NewStorage()}
+		assert.NoError(store.Put("a.txt", bytes.NewReader(moveTestData)))
+
+		assert.NoError(storage.Move(ctx, store, "a.txt", "a.txt"))
+		assert.Equal(moveTestData, read(store, "a.txt"))
+	})
+
+	t.Run("move missing object", func(t *testing.T) {
+		store := struct{ storage.Storage }{mem.NewStorage()}
+
+		err := storage.Move(ctx, store, "a.txt", "b.txt")
+		assert.ErrorIs(err, storage.ErrNotExist)
+
+		merr := new(storage.MoveError)
+		assert.True(errors.As(err, &merr))
+		assert.False(merr.Copied)
+		assert.Equal("a.txt", merr.Src)
+		assert.Equal("b.txt", merr.Dst)
+	})
+
+	t.Run("report object left under both paths", func(t *testing.T) {
+		backend := mem.NewStorage()
+		assert.NoError(backend.Put("a.txt", bytes.NewReader(moveTestData)))
+		store := struct{ storage.Storage }{&failingStorage{backend}}
+
+		err := storage.Move(ctx, store, "a.txt", "b.txt")
+		assert.ErrorIs(err, errMirrorTestFailed)
+		assert.Contains(err.Error(), "source is not removed")
+
+		merr := new(storage.MoveError)
+		assert.True(errors.As(err, &merr))
+		assert.True(merr.Copied)
+		assert.Equal(moveTestData, read(backend, "a.txt"))
+		assert.Equal(moveTestData, read(backend, "b.txt"))
+	})
+
+	t.Run("use the storage mover", func(t *testing.T) {
+		backend := mem.NewStorage()
+		assert.NoError(backend.Put("a.txt", bytes.NewReader(moveTestData)))
+		store := &failingStorage{backend}
+
+		assert.NoError(storage.Move(ctx, store, "a.txt", "b.txt"))
+		assert.Equal(moveTestData, read(backend, "b.txt"))
+		assert.Nil(read(backend, "a.txt"))
+	})
+}
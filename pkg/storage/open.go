@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownDriver no driver is registered for the URL scheme
+var ErrUnknownDriver = errors.New("unknown storage driver")
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]Driver{}
+)
+
+// Driver opens the storage for the URL. Returns the storage and the prefix inside of it
+// that the storage is not scoped to yet, Open scopes the storage to that prefix.
+type Driver interface {
+	Open(ctx context.Context, u *url.URL) (store Storage, prefix string, err error)
+}
+
+// DriverFunc function that can be used as a Driver
+type DriverFunc func(ctx context.Context, u *url.URL) (Storage, string, error)
+
+// Open calls the function
+func (f DriverFunc) Open(ctx context.Context, u *url.URL) (Storage, string, error) {
+	return f(ctx, u)
+}
+
+// Register makes the driver available for the URL scheme.
+// Panics if the driver is nil or the scheme is registered twice.
+func Register(scheme string, driver Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	if driver == nil {
+		panic("storage: Register driver is nil")
+	}
+
+	if _, ok := drivers[scheme]; ok {
+		panic("storage: Register called twice for scheme " + scheme)
+	}
+
+	drivers[scheme] = driver
+}
+
+// Drivers get sorted list of the registered schemes
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+
+	schemes := make([]string, 0, len(drivers))
+
+	for scheme := range drivers {
+		schemes = append(schemes, scheme)
+	}
+
+	sort.Strings(schemes)
+
+	return schemes
+}
+
+// Open opens the storage by URL, for example "s3://bucket/prefix?region=us-east-2", "file:///var/data" or "mem://".
+// The driver has to be registered first, usually by importing the backend package.
+func Open(ctx context.Context, rawURL string) (Storage, error) {
+	u, err := url.Parse(rawURL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	driversMu.RLock()
+	driver, ok := drivers[u.Scheme]
+	driversMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: '%s'", ErrUnknownDriver, u.Scheme)
+	}
+
+	store, prefix, err := driver.Open(ctx, u)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return WithPrefix(store, prefix), nil
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errOpenTest = errors.New("open failed")
+
+func TestOpen(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	mock := NewMock()
+	urls := []*url.URL{}
+
+	Register("test", DriverFunc(func(ctx context.Context, u *url.URL) (Storage, string, error) {
+		urls = append(urls, u)
+
+		if u.Host == "fail" {
+			return nil, "", errOpenTest
+		}
+
+		return mock, u.Path, nil
+	}))
+
+	t.Run("register drivers", func(t *testing.T) {
+		assert.Contains(Drivers(), "test")
+		assert.Panics(func() { Register("test", DriverFunc(nil)) })
+		assert.Panics(func() { Register("nil", nil) })
+	})
+
+	t.Run("open without prefix", func(t *testing.T) {
+		store, err := Open(ctx, "test://host?option=value")
+		assert.NoError(err)
+		assert.Equal(mock, store)
+		assert.Equal("host", urls[len(urls)-1].Host)
+		assert.Equal("value", urls[len(urls)-1].Query().Get("option"))
+	})
+
+	t.Run("open with prefix", func(t *testing.T) {
+		store, err := Open(ctx, "test://host/prefix/")
+		assert.NoError(err)
+		assert.Equal(&prefixStorage{store: mock, prefix: "prefix/"}, store)
+	})
+
+	t.Run("open unknown scheme", func(t *testing.T) {
+		_, err := Open(ctx, "unknown://host")
+		assert.ErrorIs(err, ErrUnknownDriver)
+	})
+
+	t.Run("open with driver error", func(t *testing.T) {
+		_, err := Open(ctx, "test://fail")
+		assert.ErrorIs(err, errOpenTest)
+	})
+
+	t.Run("open invalid url", func(t *testing.T) {
+		_, err := Open(ctx, "test://host/%zz")
+		assert.Error(err)
+	})
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// ErrInvalidOption option is unknown or its value has the wrong type
+var ErrInvalidOption = errors.New("invalid option")
+
+const (
+	optionDelimiter         = "delimiter"
+	optionToken             = "token"
+	optionBucket            = "bucket"
+	optionDestinationBucket = "dstBucket"
+	optionFileMode          = "mode"
+)
+
+// Option call option for List, Copy and ListIter.
+// It's the same type as the legacy map options, so both can be passed to the same call.
+type Option = map[string]interface{}
+
+// WithDelimiter list direct children of the path, directories are reported as prefixes
+func WithDelimiter(delimiter string) Option {
+	return Option{optionDelimiter: delimiter}
+}
+
+// WithToken continue the listing after the entry with the continuation token
+func WithToken(token string) Option {
+	return Option{optionToken: token}
+}
+
+// WithDestinationBucket copy the object into another bucket
+func WithDestinationBucket(bucket string) Option {
+	return Option{optionDestinationBucket: bucket}
+}
+
+// WithFileMode permissions of the files created by the call
+func WithFileMode(mode fs.FileMode) Option {
+	return Option{optionFileMode: mode}
+}
+
+// rawContentKey context key of WithRawContent
+type rawContentKey struct{}
+
+// WithRawContent ask GetWithContext to return the body as it is stored, without decoding its ContentEncoding.
+// Storages that decode the content by default (s3 http client decodes gzip) check it with RawContent.
+func WithRawContent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rawContentKey{}, true)
+}
+
+// RawContent check if the body has to be returned as it is stored, see WithRawContent
+func RawContent(ctx context.Context) bool {
+	raw, _ := ctx.Value(rawContentKey{}).(bool)
+	return raw
+}
+
+// Options parsed call options
+type Options struct {
+	Delimiter         string
+	Token             string
+	DestinationBucket string
+	FileMode          fs.FileMode
+}
+
+// ParseOptions merge the options into one struct, later options override the earlier ones.
+// Legacy keys "delimiter", "token", "bucket", "dstBucket" and "mode" (int or fs.FileMode) are accepted,
+// "bucket" and "dstBucket" both set the destination bucket and can't have different values.
+// Returns ErrInvalidOption if the key is unknown or the value has the wrong type.
+func ParseOptions(options ...map[string]interface{}) (*Options, error) {
+	opts := new(Options)
+	buckets := map[string]string{}
+
+	for _, opt := range options {
+		for key, val := range opt {
+			var ok bool
+
+			switch key {
+			case optionDelimiter:
+				opts.Delimiter, ok = val.(string)
+			case optionToken:
+				opts.Token, ok = val.(string)
+			case optionBucket, optionDestinationBucket:
+				opts.DestinationBucket, ok = val.(string)
+				buckets[key] = opts.DestinationBucket
+			case optionFileMode:
+				switch mode := val.(type) {
+				case fs.FileMode:
+					opts.FileMode, ok = mode, true
+				case int:
+					opts.FileMode, ok = fs.FileMode(mode), mode >= 0
+				}
+			default:
+				return nil, fmt.Errorf("%w: unknown option '%s'", ErrInvalidOption, key)
+			}
+
+			if !ok {
+				return nil, fmt.Errorf("%w: '%s' can't be %T", ErrInvalidOption, key, val)
+			}
+		}
+	}
+
+	if bucket, ok := buckets[optionBucket]; ok {
+		if dstBucket, ok := buckets[optionDestinationBucket]; ok && bucket != dstBucket {
+			return nil, fmt.Errorf("%w: '%s' is '%s' and '%s' is '%s'", ErrInvalidOption, optionBucket, bucket, optionDestinationBucket, dstBucket)
+		}
+	}
+
+	return opts, nil
+}
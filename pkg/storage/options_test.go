@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Run("typed options", func(t *testing.T) {
+		opts, err := ParseOptions(WithDelimiter("/"), WithToken("a.txt"), WithDestinationBucket("dst"), WithFileMode(0600))
+		assert.NoError(err)
+		assert.Equal(&Options{
+			Delimiter:         "/",
+			Token:             "a.txt",
+			DestinationBucket: "dst",
+			FileMode:          fs.FileMode(0600),
+		}, opts)
+	})
+
+	t.Run("legacy options", func(t *testing.T) {
+		opts, err := ParseOptions(map[string]interface{}{"delimiter": "/", "mode": 0600})
+		assert.NoError(err)
+		assert.Equal("/", opts.Delimiter)
+		assert.Equal(fs.FileMode(0600), opts.FileMode)
+
+		opts, err = ParseOptions(map[string]interface{}{"bucket": "dst"})
+		assert.NoError(err)
+		assert.Equal("dst", opts.DestinationBucket)
+
+		opts, err = ParseOptions(map[string]interface{}{"dstBucket": "dst"})
+		assert.NoError(err)
+		assert.Equal("dst", opts.DestinationBucket)
+
+		opts, err = ParseOptions(map[string]interface{}{"bucket": "dst"}, WithDestinationBucket("dst"))
+		assert.NoError(err)
+		assert.Equal("dst", opts.DestinationBucket)
+	})
+
+	t.Run("later options override", func(t *testing.T) {
+		opts, err := ParseOptions(WithDelimiter("/"), WithDelimiter("|"))
+		assert.NoError(err)
+		assert.Equal("|", opts.Delimiter)
+	})
+
+	t.Run("no options", func(t *testing.T) {
+		opts, err := ParseOptions()
+		assert.NoError(err)
+		assert.Equal(&Options{}, opts)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		for _, opt := range []map[string]interface{}{
+			{"delimiter": 1},
+			{"token": nil},
+			{"dstBucket": true},
+			{"mode": "0644"},
+			{"mode": -1},
+			{"delimeter": "/"},
+			{"unknown": 1},
+			{"bucket": "a", "dstBucket": "b"},
+		} {
+			_, err := ParseOptions(opt)
+			assert.ErrorIs(err, ErrInvalidOption)
+		}
+
+		_, err := ParseOptions(map[string]interface{}{"bucket": "a"}, WithDestinationBucket("b"))
+		assert.ErrorIs(err, ErrInvalidOption)
+	})
+}
@@ -0,0 +1,485 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+)
+
+// ErrNotSupported wrapped storage does not implement the optional interface
+var ErrNotSupported = errors.New("operation is not supported by the storage")
+
+// ErrInvalidPath path tries to leave the prefix with ".." element
+var ErrInvalidPath = errors.New("invalid path")
+
+// WithPrefix scope the storage to the "folder" inside of it. Paths are rewritten to be inside of the prefix,
+// prefix is stripped from List, Walk and ListIter results. Paths with ".." elements are rejected with ErrInvalidPath,
+// empty prefix returns the storage as is.
+func WithPrefix(store Storage, prefix string) Storage {
+	prefix = strings.Trim(prefix, "/")
+
+	if len(prefix) == 0 {
+		return store
+	}
+
+	p := &prefixStorage{
+		store:  store,
+		prefix: prefix + "/",
+	}
+
+	if hasParent(prefix) {
+		p.err = &fs.PathError{Op: "prefix", Path: prefix, Err: ErrInvalidPath}
+	}
+
+	return p
+}
+
+// prefixStorage rewrites the paths to be inside of the prefix and strips it from the results
+type prefixStorage struct {
+	store  Storage
+	prefix string
+	err    error
+}
+
+func (p *prefixStorage) path(op string, path string) (string, error) {
+	if p.err != nil {
+		return "", p.err
+	}
+
+	if hasParent(path) {
+		return "", &fs.PathError{Op: op, Path: path, Err: ErrInvalidPath}
+	}
+
+	return p.prefix + strings.TrimPrefix(path, "/"), nil
+}
+
+func (p *prefixStorage) strip(path string) string {
+	return strings.TrimPrefix(path, p.prefix)
+}
+
+func (p *prefixStorage) strips(paths []string) []string {
+	for i, path := range paths {
+		paths[i] = p.strip(path)
+	}
+
+	return paths
+}
+
+// hasParent check if any of the path elements is ".."
+func hasParent(path string) bool {
+	for _, elem := range strings.Split(path, "/") {
+		if elem == ".." {
+			return true
+		}
+	}
+
+	return false
+}
+
+// List reads the path content
+func (p *prefixStorage) List(path string, options ...map[string]interface{}) ([]string, error) {
+	loc, err := p.path("list", path)
+
+	if err != nil {
+		return []string{}, err
+	}
+
+	paths, err := p.store.List(loc, options...)
+
+	return p.strips(paths), err
+}
+
+// ListWithContext reads the path content
+func (p *prefixStorage) ListWithContext(ctx context.Context, path string, options ...map[string]interface{}) ([]string, error) {
+	loc, err := p.path("list", path)
+
+	if err != nil {
+		return []string{}, err
+	}
+
+	paths, err := p.store.ListWithContext(ctx, loc, options...)
+
+	return p.strips(paths), err
+}
+
+// ListIter paginated listing of the path, tokens are relative to the prefix
+func (p *prefixStorage) ListIter(ctx context.Context, path string, options ...map[string]interface{}) Iterator {
+	lister, ok := p.store.(ListIterator)
+
+	if !ok {
+		return &errIterator{ErrNotSupported}
+	}
+
+	loc, err := p.path("list", path)
+
+	if err != nil {
+		return &errIterator{err}
+	}
+
+	if opts, err := ParseOptions(options...); err == nil && len(opts.Token) > 0 {
+		options = append(options, WithToken(p.prefix+opts.Token))
+	}
+
+	return &prefixIterator{
+		Iterator: lister.ListIter(ctx, loc, options...),
+		prefix:   p.prefix,
+	}
+}
+
+// Walk recursively look for files in directory
+func (p *prefixStorage) Walk(path string, callback func(path string)) error {
+	loc, err := p.path("walk", path)
+
+	if err != nil {
+		return err
+	}
+
+	return p.store.Walk(loc, func(path string) {
+		callback(p.strip(path))
+	})
+}
+
+// WalkWithContext recursively look for files in directory
+func (p *prefixStorage) WalkWithContext(ctx context.Context, path string, callback func(path string)) error {
+	loc, err := p.path("walk", path)
+
+	if err != nil {
+		return err
+	}
+
+	return p.store.WalkWithContext(ctx, loc, func(path string) {
+		callback(p.strip(path))
+	})
+}
+
+// WalkFunc recursively look for files and directories, callback gets the entry details
+func (p *prefixStorage) WalkFunc(ctx context.Context, path string, callback func(entry *Entry) error) error {
+	walker, ok := p.store.(WalkerFunc)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	loc, err := p.path("walk", path)
+
+	if err != nil {
+		return err
+	}
+
+	return walker.WalkFunc(ctx, loc, func(entry *Entry) error {
+		scoped := *entry
+		scoped.Path = p.strip(entry.Path)
+		return callback(&scoped)
+	})
+}
+
+// Copy copies an object inside of the prefix
+func (p *prefixStorage) Copy(src string, dst string, options ...map[string]interface{}) error {
+	srcLoc, err := p.path("copy", src)
+
+	if err != nil {
+		return err
+	}
+
+	dstLoc, err := p.path("copy", dst)
+
+	if err != nil {
+		return err
+	}
+
+	return p.store.Copy(srcLoc, dstLoc, options...)
+}
+
+// CopyWithContext copies an object inside of the prefix
+func (p *prefixStorage) CopyWithContext(ctx context.Context, src string, dst string, options ...map[string]interface{}) error {
+	srcLoc, err := p.path("copy", src)
+
+	if err != nil {
+		return err
+	}
+
+	dstLoc, err := p.path("copy", dst)
+
+	if err != nil {
+		return err
+	}
+
+	return p.store.CopyWithContext(ctx, srcLoc, dstLoc, options...)
+}
+
+// Move moves an object inside the prefix
+func (p *prefixStorage) Move(ctx context.Context, src string, dst string) error {
+	srcLoc, err := p.path("move", src)
+
+	if err != nil {
+		return err
+	}
+
+	dstLoc, err := p.path("move", dst)
+
+	if err != nil {
+		return err
+	}
+
+	return Move(ctx, p.store, srcLoc, dstLoc)
+}
+
+// Create create new file or open current and truncate
+func (p *prefixStorage) Create(path string) (io.ReadWriteCloser, error) {
+	loc, err := p.path("create", path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p.store.Create(loc)
+}
+
+// CreateWithContext create new file or open current and truncate
+func (p *prefixStorage) CreateWithContext(ctx context.Context, path string) (io.ReadWriteCloser, error) {
+	creator, ok := p.store.(CreatorWithContext)
+
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	loc, err := p.path("create", path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return creator.CreateWithContext(ctx, loc)
+}
+
+// Get get object from storage
+func (p *prefixStorage) Get(path string) (io.ReadCloser, error) {
+	loc, err := p.path("get", path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p.store.Get(loc)
+}
+
+// GetWithContext get object from storage
+func (p *prefixStorage) GetWithContext(ctx context.Context, path string) (io.ReadCloser, error) {
+	loc, err := p.path("get", path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p.store.GetWithContext(ctx, loc)
+}
+
+// GetRange get part of the object from storage
+func (p *prefixStorage) GetRange(ctx context.Context, path string, offset int64, length int64) (io.ReadCloser, error) {
+	getter, ok := p.store.(RangeGetter)
+
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	loc, err := p.path("get", path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return getter.GetRange(ctx, loc, offset, length)
+}
+
+// Put object into storage
+func (p *prefixStorage) Put(path string, body io.Reader) error {
+	loc, err := p.path("put", path)
+
+	if err != nil {
+		return err
+	}
+
+	return p.store.Put(loc, body)
+}
+
+// PutWithContext object into storage
+func (p *prefixStorage) PutWithContext(ctx context.Context, path string, body io.Reader) error {
+	loc, err := p.path("put", path)
+
+	if err != nil {
+		return err
+	}
+
+	return p.store.PutWithContext(ctx, loc, body)
+}
+
+// PutWithOptions object into storage together with its attributes
+func (p *prefixStorage) PutWithOptions(ctx context.Context, path string, body io.Reader, options *PutOptions) error {
+	putter, ok := p.store.(PutterWithOptions)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	loc, err := p.path("put", path)
+
+	if err != nil {
+		return err
+	}
+
+	return putter.PutWithOptions(ctx, loc, body, options)
+}
+
+// SetMetadata replace user metadata of the object
+func (p *prefixStorage) SetMetadata(ctx context.Context, path string, metadata map[string]string) error {
+	setter, ok := p.store.(MetadataSetter)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	loc, err := p.path("metadata", path)
+
+	if err != nil {
+		return err
+	}
+
+	return setter.SetMetadata(ctx, loc, metadata)
+}
+
+// Link generate expiration link for storage
+func (p *prefixStorage) Link(path string, expire time.Duration) (string, error) {
+	loc, err := p.path("link", path)
+
+	if err != nil {
+		return "", err
+	}
+
+	return p.store.Link(loc, expire)
+}
+
+// Delete remove object from storage
+func (p *prefixStorage) Delete(path string) error {
+	loc, err := p.path("delete", path)
+
+	if err != nil {
+		return err
+	}
+
+	return p.store.Delete(loc)
+}
+
+// DeleteWithContext remove object from storage
+func (p *prefixStorage) DeleteWithContext(ctx context.Context, path string) error {
+	loc, err := p.path("delete", path)
+
+	if err != nil {
+		return err
+	}
+
+	return p.store.DeleteWithContext(ctx, loc)
+}
+
+// DeleteMany deletes the objects inside the prefix, the report has the paths as they were passed
+func (p *prefixStorage) DeleteMany(ctx context.Context, paths []string) error {
+	locs := make([]string, 0, len(paths))
+	origins := map[string]string{}
+	errs := map[string]error{}
+
+	for _, path := range paths {
+		loc, err := p.path("delete", path)
+
+		if err != nil {
+			errs[path] = err
+			continue
+		}
+
+		locs = append(locs, loc)
+		origins[loc] = path
+	}
+
+	derr := new(DeleteError)
+
+	if err := DeleteMany(ctx, p.store, locs); errors.As(err, &derr) {
+		for loc, err := range derr.Errs {
+			errs[origins[loc]] = err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	return NewDeleteError(errs)
+}
+
+// DeleteAll deletes the object at the path and every object under it inside the prefix
+func (p *prefixStorage) DeleteAll(ctx context.Context, prefix string) error {
+	loc, err := p.path("delete", prefix)
+
+	if err != nil {
+		return err
+	}
+
+	derr := new(DeleteError)
+
+	if err := DeleteAll(ctx, p.store, loc); errors.As(err, &derr) {
+		errs := map[string]error{}
+
+		for loc, err := range derr.Errs {
+			errs[p.strip(loc)] = err
+		}
+
+		return NewDeleteError(errs)
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Stat get file information
+func (p *prefixStorage) Stat(path string) (FileInfo, error) {
+	loc, err := p.path("stat", path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p.store.Stat(loc)
+}
+
+// Retryable classify the error by the wrapped storage
+func (p *prefixStorage) Retryable(err error) bool {
+	if classifier, ok := p.store.(RetryClassifier); ok {
+		return classifier.Retryable(err)
+	}
+
+	return Retryable(err)
+}
+
+// prefixIterator strips the prefix from the entries and tokens
+type prefixIterator struct {
+	Iterator
+	prefix string
+}
+
+// Entry get current entry with the path relative to the prefix
+func (it *prefixIterator) Entry() *Entry {
+	entry := it.Iterator.Entry()
+
+	if entry == nil {
+		return nil
+	}
+
+	scoped := *entry
+	scoped.Path = strings.TrimPrefix(entry.Path, it.prefix)
+
+	return &scoped
+}
+
+// Token get continuation token relative to the prefix
+func (it *prefixIterator) Token() string {
+	return strings.TrimPrefix(it.Iterator.Token(), it.prefix)
+}
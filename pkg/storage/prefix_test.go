@@ -0,0 +1,114 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/dev-toolkit/lib/mem"
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+var prefixTestData = []byte("hello storage")
+
+func TestWithPrefix(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	t.Run("paths are scoped to the prefix", func(t *testing.T) {
+		root := mem.NewStorage()
+		store := storage.WithPrefix(root, "/scoped/dir/")
+
+		assert.NoError(store.Put("a/b.txt", bytes.NewReader(prefixTestData)))
+		_, err := root.Stat("scoped/dir/a/b.txt")
+		assert.NoError(err)
+
+		paths := []string{}
+		assert.NoError(store.Walk("a", func(path string) {
+			paths = append(paths, path)
+		}))
+		assert.Equal([]string{"a/b.txt"}, paths)
+
+		names, err := store.List("/", storage.WithDelimiter("/"))
+		assert.NoError(err)
+		assert.Equal([]string{"a"}, names)
+
+		iter := store.(storage.ListIterator).ListIter(ctx, "/")
+		assert.True(iter.Next())
+		assert.Equal("a/b.txt", iter.Entry().Path)
+		assert.Equal("a/b.txt", iter.Token())
+	})
+
+	t.Run("copy inside of the prefix", func(t *testing.T) {
+		root := mem.NewStorage()
+		store := storage.WithPrefix(root, "scoped")
+
+		assert.NoError(store.Put("src.txt", bytes.NewReader(prefixTestData)))
+		assert.NoError(store.Copy("src.txt", "dst/dst.txt"))
+		_, err := root.Stat("scoped/dst/dst.txt")
+		assert.NoError(err)
+		_, err = root.Stat("dst/dst.txt")
+		assert.ErrorIs(err, storage.ErrNotExist)
+	})
+
+	t.Run("nested prefixes", func(t *testing.T) {
+		root := mem.NewStorage()
+		store := storage.WithPrefix(storage.WithPrefix(root, "a"), "b")
+
+		assert.NoError(store.Put("c.txt", bytes.NewReader(prefixTestData)))
+		_, err := root.Stat("a/b/c.txt")
+		assert.NoError(err)
+	})
+
+	t.Run("reject parent paths", func(t *testing.T) {
+		root := mem.NewStorage()
+		store := storage.WithPrefix(root, "scoped")
+		assert.NoError(root.Put("secret.txt", bytes.NewReader(prefixTestData)))
+
+		_, err := store.Get("../secret.txt")
+		assert.ErrorIs(err, storage.ErrInvalidPath)
+		_, err = store.Stat("a/../../secret.txt")
+		assert.ErrorIs(err, storage.ErrInvalidPath)
+		_, err = store.List("..")
+		assert.ErrorIs(err, storage.ErrInvalidPath)
+		_, err = store.Link("../secret.txt", time.Minute)
+		assert.ErrorIs(err, storage.ErrInvalidPath)
+		assert.ErrorIs(store.Walk("../", func(_ string) {}), storage.ErrInvalidPath)
+		assert.ErrorIs(store.Put("../secret.txt", bytes.NewReader(prefixTestData)), storage.ErrInvalidPath)
+		assert.ErrorIs(store.Delete("../secret.txt"), storage.ErrInvalidPath)
+		assert.ErrorIs(store.Copy("../secret.txt", "copy.txt"), storage.ErrInvalidPath)
+		assert.ErrorIs(store.Copy("src.txt", "../copy.txt"), storage.ErrInvalidPath)
+
+		iter := store.(storage.ListIterator).ListIter(ctx, "../")
+		assert.False(iter.Next())
+		assert.ErrorIs(iter.Err(), storage.ErrInvalidPath)
+
+		_, err = root.Stat("secret.txt")
+		assert.NoError(err)
+	})
+
+	t.Run("reject parent prefix", func(t *testing.T) {
+		store := storage.WithPrefix(mem.NewStorage(), "scoped/../..")
+
+		_, err := store.Get("a.txt")
+		assert.ErrorIs(err, storage.ErrInvalidPath)
+	})
+
+	t.Run("empty prefix", func(t *testing.T) {
+		root := mem.NewStorage()
+		assert.Equal(root, storage.WithPrefix(root, "/"))
+	})
+
+	t.Run("open with prefix", func(t *testing.T) {
+		root, err := storage.Open(ctx, "mem://prefix")
+		assert.NoError(err)
+		store, err := storage.Open(ctx, "mem://prefix/scoped/dir")
+		assert.NoError(err)
+
+		assert.NoError(store.Put("a.txt", bytes.NewReader(prefixTestData)))
+		_, err = root.Stat("scoped/dir/a.txt")
+		assert.NoError(err)
+	})
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"io"
+)
+
+// NewRangeReader create reader for the object that fetches only the requested parts of it,
+// size is the object size, for example from Stat
+func NewRangeReader(ctx context.Context, getter RangeGetter, path string, size int64) *RangeReader {
+	return &RangeReader{
+		ctx:    ctx,
+		getter: getter,
+		path:   path,
+		size:   size,
+	}
+}
+
+// RangeReader io.ReaderAt and io.ReadSeeker on top of range reads.
+// Sequential reads share one body until the next seek, ReadAt is safe for concurrent use.
+type RangeReader struct {
+	ctx    context.Context
+	getter RangeGetter
+	path   string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+// Size get size of the object
+func (r *RangeReader) Size() int64 {
+	return r.size
+}
+
+// ReadAt reads len(p) bytes of the object starting at offset
+func (r *RangeReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, ErrInvalidRange
+	}
+
+	if off >= r.size {
+		return 0, io.EOF
+	}
+
+	length := int64(len(p))
+
+	if off+length > r.size {
+		length = r.size - off
+	}
+
+	body, err := r.getter.GetRange(r.ctx, r.path, off, length)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer body.Close()
+	n, err := io.ReadFull(body, p[:length])
+
+	if err == nil && n < len(p) {
+		err = io.EOF
+	}
+
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+
+	return n, err
+}
+
+// Read reads the object from the current offset
+func (r *RangeReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	if r.body == nil {
+		body, err := r.getter.GetRange(r.ctx, r.path, r.offset, -1)
+
+		if err != nil {
+			return 0, err
+		}
+
+		r.body = body
+	}
+
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+
+	return n, err
+}
+
+// Seek sets the offset for the next Read
+func (r *RangeReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return r.offset, ErrInvalidRange
+	}
+
+	if offset < 0 {
+		return r.offset, ErrInvalidRange
+	}
+
+	if offset != r.offset {
+		if err := r.Close(); err != nil {
+			return r.offset, err
+		}
+
+		r.offset = offset
+	}
+
+	return r.offset, nil
+}
+
+// Close releases the body of the sequential read
+func (r *RangeReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+
+	err := r.body.Close()
+	r.body = nil
+
+	return err
+}
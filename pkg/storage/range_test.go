@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var rangeTestData = []byte("hello storage")
+
+type bytesGetter struct {
+	mu    sync.Mutex
+	data  []byte
+	calls int
+}
+
+func (g *bytesGetter) GetRange(_ context.Context, _ string, offset int64, length int64) (io.ReadCloser, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.calls++
+
+	if offset < 0 {
+		return nil, ErrInvalidRange
+	}
+
+	end := int64(len(g.data))
+
+	if offset > end {
+		offset = end
+	}
+
+	if length >= 0 && offset+length < end {
+		end = offset + length
+	}
+
+	return io.NopCloser(bytes.NewReader(g.data[offset:end])), nil
+}
+
+func TestRangeReader(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	size := int64(len(rangeTestData))
+
+	t.Run("read at offset", func(t *testing.T) {
+		reader := NewRangeReader(ctx, &bytesGetter{data: rangeTestData}, "range.txt", size)
+		buf := make([]byte, 4)
+
+		n, err := reader.ReadAt(buf, 6)
+		assert.NoError(err)
+		assert.Equal(4, n)
+		assert.Equal("stor", string(buf))
+
+		n, err = reader.ReadAt(buf, 10)
+		assert.ErrorIs(err, io.EOF)
+		assert.Equal(3, n)
+		assert.Equal("age", string(buf[:n]))
+
+		_, err = reader.ReadAt(buf, size)
+		assert.ErrorIs(err, io.EOF)
+
+		_, err = reader.ReadAt(buf, -1)
+		assert.ErrorIs(err, ErrInvalidRange)
+	})
+
+	t.Run("read sequentially", func(t *testing.T) {
+		getter := &bytesGetter{data: rangeTestData}
+		reader := NewRangeReader(ctx, getter, "range.txt", size)
+		defer reader.Close()
+
+		data, err := io.ReadAll(reader)
+		assert.NoError(err)
+		assert.Equal(rangeTestData, data)
+		assert.Equal(1, getter.calls)
+	})
+
+	t.Run("seek and read", func(t *testing.T) {
+		getter := &bytesGetter{data: rangeTestData}
+		reader := NewRangeReader(ctx, getter, "range.txt", size)
+		defer reader.Close()
+
+		offset, err := reader.Seek(-7, io.SeekEnd)
+		assert.NoError(err)
+		assert.Equal(int64(6), offset)
+
+		buf := make([]byte, 4)
+		_, err = io.ReadFull(reader, buf)
+		assert.NoError(err)
+		assert.Equal("stor", string(buf))
+
+		offset, err = reader.Seek(0, io.SeekCurrent)
+		assert.NoError(err)
+		assert.Equal(int64(10), offset)
+
+		_, err = reader.Seek(-4, io.SeekCurrent)
+		assert.NoError(err)
+		data, err := io.ReadAll(reader)
+		assert.NoError(err)
+		assert.Equal("storage", string(data))
+		assert.Equal(2, getter.calls)
+
+		_, err = reader.Seek(-1, io.SeekStart)
+		assert.ErrorIs(err, ErrInvalidRange)
+	})
+}
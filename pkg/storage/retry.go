@@ -0,0 +1,414 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"time"
+)
+
+// RetryClassifier tells which errors of the backend are transient and can be retried
+type RetryClassifier interface {
+	Retryable(err error) bool
+}
+
+// RetryPolicy how many times and how often to retry the operations.
+// Zero values are replaced with 3 attempts, 100ms base delay and 5s max delay.
+type RetryPolicy struct {
+	// MaxAttempts number of attempts including the first one
+	MaxAttempts int
+
+	// BaseDelay delay before the first retry, doubled for every next one
+	BaseDelay time.Duration
+
+	// MaxDelay upper limit of the delay
+	MaxDelay time.Duration
+
+	// Retryable overrides the error classification of the backend
+	Retryable func(err error) bool
+}
+
+// delay get random delay before the retry with exponential backoff and full jitter
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.MaxDelay
+
+	if attempt < 32 && p.BaseDelay<<attempt > 0 && p.BaseDelay<<attempt < p.MaxDelay {
+		delay = p.BaseDelay << attempt
+	}
+
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// Retryable default error classification, errors that report themselves as temporary or timeout are retried
+func Retryable(err error) bool {
+	var temp interface{ Temporary() bool }
+
+	if errors.As(err, &temp) && temp.Temporary() {
+		return true
+	}
+
+	var timeout interface{ Timeout() bool }
+
+	return errors.As(err, &timeout) && timeout.Timeout()
+}
+
+// WithRetry retry idempotent operations (Get, Stat, List, Walk, Delete, Copy) on transient errors.
+// Errors are classified by the policy, then by the storage if it implements RetryClassifier, then by Retryable.
+// Put is retried only when the body is an io.Seeker, Walk only until the callback is called for the first time.
+// Delays respect the context, retries stop when the deadline comes before the next attempt.
+func WithRetry(store Storage, policy RetryPolicy) Storage {
+	return &retryStorage{
+		store:  store,
+		policy: policy.withDefaults(),
+	}
+}
+
+// withDefaults replace zero values of the policy with the defaults
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 3
+	}
+
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = time.Millisecond * 100
+	}
+
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = time.Second * 5
+	}
+
+	return p
+}
+
+// retryable classify the error by the policy, then by the stores that implement RetryClassifier
+// (any of them can allow the retry), then by Retryable when none of the stores classifies errors
+func (p *RetryPolicy) retryable(err error, stores ...Storage) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+
+	classified := false
+
+	for _, store := range stores {
+		if classifier, ok := store.(RetryClassifier); ok {
+			if classifier.Retryable(err) {
+				return true
+			}
+
+			classified = true
+		}
+	}
+
+	return !classified && Retryable(err)
+}
+
+// retry call the function until it succeeds, the error is not retryable or attempts are over
+func (p *RetryPolicy) retry(ctx context.Context, fn func() error, stores ...Storage) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+
+		if err == nil || attempt+1 >= p.MaxAttempts || !p.retryable(err, stores...) {
+			return err
+		}
+
+		delay := p.delay(attempt)
+
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// retryStorage repeats failed operations of the storage
+type retryStorage struct {
+	store  Storage
+	policy RetryPolicy
+}
+
+func (r *retryStorage) retry(ctx context.Context, fn func() error) error {
+	return r.policy.retry(ctx, fn, r.store)
+}
+
+func (r *retryStorage) put(ctx context.Context, body io.Reader, fn func(body io.Reader) error) error {
+	seeker, ok := body.(io.Seeker)
+
+	if !ok {
+		return fn(body)
+	}
+
+	offset, err := seeker.Seek(0, io.SeekCurrent)
+
+	if err != nil {
+		return fn(body)
+	}
+
+	return r.retry(ctx, func() error {
+		if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+
+		return fn(body)
+	})
+}
+
+func (r *retryStorage) walk(ctx context.Context, fn func(called func()) error) error {
+	called := false
+	var err error
+
+	_ = r.retry(ctx, func() error {
+		if err = fn(func() { called = true }); called {
+			return nil
+		}
+
+		return err
+	})
+
+	return err
+}
+
+// List get the contents of the path
+func (r *retryStorage) List(path string, options ...map[string]interface{}) ([]string, error) {
+	return r.ListWithContext(context.Background(), path, options...)
+}
+
+// ListWithContext get the contents of the path
+func (r *retryStorage) ListWithContext(ctx context.Context, path string, options ...map[string]interface{}) ([]string, error) {
+	var paths []string
+
+	err := r.retry(ctx, func() (err error) {
+		paths, err = r.store.ListWithContext(ctx, path, options...)
+		return err
+	})
+
+	return paths, err
+}
+
+// ListIter paginated listing of the path, is not retried
+func (r *retryStorage) ListIter(ctx context.Context, path string, options ...map[string]interface{}) Iterator {
+	lister, ok := r.store.(ListIterator)
+
+	if !ok {
+		return &errIterator{ErrNotSupported}
+	}
+
+	return lister.ListIter(ctx, path, options...)
+}
+
+// Walk recursively look for files in directory
+func (r *retryStorage) Walk(path string, callback func(path string)) error {
+	return r.WalkWithContext(context.Background(), path, callback)
+}
+
+// WalkWithContext recursively look for files in directory
+func (r *retryStorage) WalkWithContext(ctx context.Context, path string, callback func(path string)) error {
+	return r.walk(ctx, func(called func()) error {
+		return r.store.WalkWithContext(ctx, path, func(path string) {
+			called()
+			callback(path)
+		})
+	})
+}
+
+// WalkFunc recursively look for files and directories, callback gets the entry details
+func (r *retryStorage) WalkFunc(ctx context.Context, path string, callback func(entry *Entry) error) error {
+	walker, ok := r.store.(WalkerFunc)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	return r.walk(ctx, func(called func()) error {
+		return walker.WalkFunc(ctx, path, func(entry *Entry) error {
+			called()
+			return callback(entry)
+		})
+	})
+}
+
+// Copy copies an object
+func (r *retryStorage) Copy(src string, dst string, options ...map[string]interface{}) error {
+	return r.CopyWithContext(context.Background(), src, dst, options...)
+}
+
+// CopyWithContext copies an object
+func (r *retryStorage) CopyWithContext(ctx context.Context, src string, dst string, options ...map[string]interface{}) error {
+	return r.retry(ctx, func() error {
+		return r.store.CopyWithContext(ctx, src, dst, options...)
+	})
+}
+
+// Move moves an object, once the object is copied only removing the source is retried
+func (r *retryStorage) Move(ctx context.Context, src string, dst string) error {
+	copied := false
+
+	return r.retry(ctx, func() error {
+		if copied {
+			if err := r.store.DeleteWithContext(ctx, src); err != nil {
+				return &MoveError{Src: src, Dst: dst, Copied: true, Err: err}
+			}
+
+			return nil
+		}
+
+		err := Move(ctx, r.store, src, dst)
+		merr := new(MoveError)
+		copied = errors.As(err, &merr) && merr.Copied
+
+		return err
+	})
+}
+
+// Create create new file or open current and truncate, is not retried
+func (r *retryStorage) Create(path string) (io.ReadWriteCloser, error) {
+	return r.store.Create(path)
+}
+
+// CreateWithContext create new file or open current and truncate, is not retried
+func (r *retryStorage) CreateWithContext(ctx context.Context, path string) (io.ReadWriteCloser, error) {
+	creator, ok := r.store.(CreatorWithContext)
+
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return creator.CreateWithContext(ctx, path)
+}
+
+// Get get object from storage, reading the body is not retried
+func (r *retryStorage) Get(path string) (io.ReadCloser, error) {
+	return r.GetWithContext(context.Background(), path)
+}
+
+// GetWithContext get object from storage, reading the body is not retried
+func (r *retryStorage) GetWithContext(ctx context.Context, path string) (io.ReadCloser, error) {
+	var body io.ReadCloser
+
+	err := r.retry(ctx, func() (err error) {
+		body, err = r.store.GetWithContext(ctx, path)
+		return err
+	})
+
+	return body, err
+}
+
+// GetRange get part of the object from storage, reading the body is not retried
+func (r *retryStorage) GetRange(ctx context.Context, path string, offset int64, length int64) (io.ReadCloser, error) {
+	getter, ok := r.store.(RangeGetter)
+
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	var body io.ReadCloser
+
+	err := r.retry(ctx, func() (err error) {
+		body, err = getter.GetRange(ctx, path, offset, length)
+		return err
+	})
+
+	return body, err
+}
+
+// Put object into storage, retried only if the body is an io.Seeker
+func (r *retryStorage) Put(path string, body io.Reader) error {
+	return r.PutWithContext(context.Background(), path, body)
+}
+
+// PutWithContext object into storage, retried only if the body is an io.Seeker
+func (r *retryStorage) PutWithContext(ctx context.Context, path string, body io.Reader) error {
+	return r.put(ctx, body, func(body io.Reader) error {
+		return r.store.PutWithContext(ctx, path, body)
+	})
+}
+
+// PutWithOptions object into storage together with its attributes, retried only if the body is an io.Seeker
+func (r *retryStorage) PutWithOptions(ctx context.Context, path string, body io.Reader, options *PutOptions) error {
+	putter, ok := r.store.(PutterWithOptions)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	return r.put(ctx, body, func(body io.Reader) error {
+		return putter.PutWithOptions(ctx, path, body, options)
+	})
+}
+
+// SetMetadata replace user metadata of the object
+func (r *retryStorage) SetMetadata(ctx context.Context, path string, metadata map[string]string) error {
+	setter, ok := r.store.(MetadataSetter)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	return r.retry(ctx, func() error {
+		return setter.SetMetadata(ctx, path, metadata)
+	})
+}
+
+// Link generate expiration link for storage
+func (r *retryStorage) Link(path string, expire time.Duration) (string, error) {
+	return r.store.Link(path, expire)
+}
+
+// Delete remove object from storage
+func (r *retryStorage) Delete(path string) error {
+	return r.DeleteWithContext(context.Background(), path)
+}
+
+// DeleteWithContext remove object from storage
+func (r *retryStorage) DeleteWithContext(ctx context.Context, path string) error {
+	return r.retry(ctx, func() error {
+		return r.store.DeleteWithContext(ctx, path)
+	})
+}
+
+// DeleteMany deletes the objects, only the paths that failed are retried
+func (r *retryStorage) DeleteMany(ctx context.Context, paths []string) error {
+	pending := paths
+
+	return r.retry(ctx, func() error {
+		err := DeleteMany(ctx, r.store, pending)
+		derr := new(DeleteError)
+
+		if errors.As(err, &derr) {
+			pending = derr.Paths()
+		}
+
+		return err
+	})
+}
+
+// DeleteAll deletes the object at the path and every object under it
+func (r *retryStorage) DeleteAll(ctx context.Context, prefix string) error {
+	return r.retry(ctx, func() error {
+		return DeleteAll(ctx, r.store, prefix)
+	})
+}
+
+// Stat get file information
+func (r *retryStorage) Stat(path string) (FileInfo, error) {
+	var info FileInfo
+
+	err := r.retry(context.Background(), func() (err error) {
+		info, err = r.store.Stat(path)
+		return err
+	})
+
+	return info, err
+}
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type retryTestError struct{}
+
+func (retryTestError) Error() string   { return "temporary failure" }
+func (retryTestError) Temporary() bool { return true }
+
+var errRetryTestFatal = errors.New("fatal failure")
+
+// flakyStorage fails the first calls with the error
+type flakyStorage struct {
+	Mock
+	failures int
+	err      error
+	calls    int
+	moves    int
+	bodies   []string
+}
+
+func (s *flakyStorage) fail() error {
+	s.calls++
+
+	if s.calls <= s.failures {
+		return s.err
+	}
+
+	return nil
+}
+
+func (s *flakyStorage) GetWithContext(_ context.Context, _ string) (io.ReadCloser, error) {
+	if err := s.fail(); err != nil {
+		return nil, err
+	}
+
+	return io.NopCloser(strings.NewReader("data")), nil
+}
+
+func (s *flakyStorage) Stat(_ string) (FileInfo, error) {
+	return nil, s.fail()
+}
+
+func (s *flakyStorage) PutWithContext(_ context.Context, _ string, body io.Reader) error {
+	data, _ := io.ReadAll(body)
+	s.bodies = append(s.bodies, string(data))
+
+	return s.fail()
+}
+
+func (s *flakyStorage) WalkWithContext(_ context.Context, _ string, callback func(path string)) error {
+	if s.calls > 0 {
+		callback("a.txt")
+	}
+
+	return s.fail()
+}
+
+func (s *flakyStorage) Move(_ context.Context, src string, dst string) error {
+	s.moves++
+
+	if err := s.fail(); err != nil {
+		return &MoveError{Src: src, Dst: dst, Copied: true, Err: err}
+	}
+
+	return nil
+}
+
+func (s *flakyStorage) DeleteWithContext(_ context.Context, _ string) error {
+	return s.fail()
+}
+
+func TestWithRetry(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+	t.Run("retry temporary errors", func(t *testing.T) {
+		flaky := &flakyStorage{failures: 2, err: retryTestError{}}
+		body, err := WithRetry(flaky, policy).GetWithContext(ctx, "a.txt")
+		assert.NoError(err)
+		assert.NotNil(body)
+		assert.Equal(3, flaky.calls)
+	})
+
+	t.Run("stop after max attempts", func(t *testing.T) {
+		flaky := &flakyStorage{failures: 5, err: retryTestError{}}
+		_, err := WithRetry(flaky, policy).Stat("a.txt")
+		assert.ErrorIs(err, retryTestError{})
+		assert.Equal(3, flaky.calls)
+	})
+
+	t.Run("do not retry permanent errors", func(t *testing.T) {
+		flaky := &flakyStorage{failures: 5, err: errRetryTestFatal}
+		_, err := WithRetry(flaky, policy).Stat("a.txt")
+		assert.ErrorIs(err, errRetryTestFatal)
+		assert.Equal(1, flaky.calls)
+	})
+
+	t.Run("classify with the policy", func(t *testing.T) {
+		flaky := &flakyStorage{failures: 1, err: errRetryTestFatal}
+		_, err := WithRetry(flaky, RetryPolicy{
+			BaseDelay: time.Millisecond,
+			Retryable: func(err error) bool { return errors.Is(err, errRetryTestFatal) },
+		}).Stat("a.txt")
+		assert.NoError(err)
+		assert.Equal(2, flaky.calls)
+	})
+
+	t.Run("do not retry canceled context", func(t *testing.T) {
+		flaky := &flakyStorage{failures: 5, err: context.Canceled}
+		_, err := WithRetry(flaky, policy).Stat("a.txt")
+		assert.ErrorIs(err, context.Canceled)
+		assert.Equal(1, flaky.calls)
+	})
+
+	t.Run("stop before the deadline", func(t *testing.T) {
+		flaky := &flakyStorage{failures: 5, err: retryTestError{}}
+		dctx, cancel := context.WithTimeout(ctx, time.Millisecond*50)
+		defer cancel()
+
+		store := WithRetry(flaky, RetryPolicy{MaxAttempts: 10, BaseDelay: time.Hour, MaxDelay: time.Hour})
+		start := time.Now()
+		_, err := store.GetWithContext(dctx, "a.txt")
+		assert.ErrorIs(err, retryTestError{})
+		assert.Less(time.Since(start), time.Second)
+	})
+
+	t.Run("retry put with seekable body", func(t *testing.T) {
+		flaky := &flakyStorage{failures: 2, err: retryTestError{}}
+		body := bytes.NewReader([]byte("hello"))
+		_, _ = body.Seek(1, io.SeekStart)
+
+		assert.NoError(WithRetry(flaky, policy).Put("a.txt", body))
+		assert.Equal([]string{"ello", "ello", "ello"}, flaky.bodies)
+	})
+
+	t.Run("do not retry put with stream body", func(t *testing.T) {
+		flaky := &flakyStorage{failures: 2, err: retryTestError{}}
+		body := io.MultiReader(strings.NewReader("hello"))
+
+		assert.ErrorIs(WithRetry(flaky, policy).Put("a.txt", body), retryTestError{})
+		assert.Equal(1, flaky.calls)
+	})
+
+	t.Run("retry walk until the first callback", func(t *testing.T) {
+		flaky := &flakyStorage{failures: 5, err: retryTestError{}}
+		paths := []string{}
+
+		err := WithRetry(flaky, policy).Walk("/", func(path string) {
+			paths = append(paths, path)
+		})
+		assert.ErrorIs(err, retryTestError{})
+		assert.Equal(2, flaky.calls)
+		assert.Equal([]string{"a.txt"}, paths)
+	})
+
+	t.Run("retry only source removal after the move copied the object", func(t *testing.T) {
+		flaky := &flakyStorage{failures: 2, err: retryTestError{}}
+
+		assert.NoError(WithRetry(flaky, policy).(Mover).Move(ctx, "a.txt", "b.txt"))
+		assert.Equal(1, flaky.moves)
+		assert.Equal(3, flaky.calls)
+	})
+
+	t.Run("retry only the paths that were not deleted", func(t *testing.T) {
+		flaky := &flakyStorage{failures: 1, err: retryTestError{}}
+
+		store := WithRetry(struct{ Storage }{flaky}, policy)
+
+		assert.NoError(store.(BatchDeleter).DeleteMany(ctx, []string{"a.txt", "b.txt"}))
+		assert.Equal(3, flaky.calls)
+	})
+}
@@ -25,12 +25,12 @@ type Storage interface {
 	Stater
 }
 
-// Lister get the contents of the path
+// Lister get the contents of the path, see ParseOptions for the supported options
 type Lister interface {
 	List(path string, options ...map[string]interface{}) ([]string, error)
 }
 
-// ListerWithContext get the contents of the path
+// ListerWithContext get the contents of the path, see ParseOptions for the supported options
 type ListerWithContext interface {
 	ListWithContext(ctx context.Context, path string, options ...map[string]interface{}) ([]string, error)
 }
@@ -45,6 +45,12 @@ type WalkerWithContext interface {
 	WalkWithContext(ctx context.Context, path string, callback func(path string)) error
 }
 
+// WalkerFunc recursively look for files and directories, callback gets the entry details.
+// Return an error from the callback to stop the walk or SkipDir to skip the directory.
+type WalkerFunc interface {
+	WalkFunc(ctx context.Context, path string, callback func(entry *Entry) error) error
+}
+
 // Copier copies an object from the a path in a bucket to another path in the same or different bucket.
 // 'src' and 'dst' are absolute paths of the file.
 type Copier interface {
@@ -57,11 +63,23 @@ type CopierWithContext interface {
 	CopyWithContext(ctx context.Context, src string, dst string, options ...map[string]interface{}) error
 }
 
+// Mover moves an object to another path inside the storage, the source is removed once the destination is written.
+// Failures are reported as *MoveError.
+type Mover interface {
+	Move(ctx context.Context, src string, dst string) error
+}
+
 // Creator create newfile or open current and truncate
 type Creator interface {
 	Create(path string) (io.ReadWriteCloser, error)
 }
 
+// CreatorWithContext create new file or open current and truncate,
+// canceling the context discards the data that is not committed yet
+type CreatorWithContext interface {
+	CreateWithContext(ctx context.Context, path string) (io.ReadWriteCloser, error)
+}
+
 // Getter get object from storage
 type Getter interface {
 	Get(path string) (io.ReadCloser, error)
@@ -72,6 +90,12 @@ type GetterWithContext interface {
 	GetWithContext(ctx context.Context, path string) (io.ReadCloser, error)
 }
 
+// RangeGetter get part of the object starting at offset, negative length reads till the end of the object.
+// Reading past the end of the object returns empty body.
+type RangeGetter interface {
+	GetRange(ctx context.Context, path string, offset int64, length int64) (io.ReadCloser, error)
+}
+
 // Putter move object to storage
 type Putter interface {
 	Put(path string, body io.Reader) error
@@ -82,6 +106,28 @@ type PutterWithContext interface {
 	PutWithContext(ctx context.Context, path string, body io.Reader) error
 }
 
+// PutOptions object attributes stored together with the object and returned back by Stat.
+// Metadata keys should be in canonical header form ("Content-Owner"), s3 returns them this way.
+type PutOptions struct {
+	ContentType        string
+	ContentEncoding    string
+	ContentDisposition string
+	ContentLanguage    string
+	CacheControl       string
+	Metadata           map[string]string
+}
+
+// PutterWithOptions moves object to storage together with its attributes,
+// nil options work same as PutWithContext
+type PutterWithOptions interface {
+	PutWithOptions(ctx context.Context, path string, body io.Reader, options *PutOptions) error
+}
+
+// MetadataSetter replaces user metadata of the stored object, content attributes and the body stay as they are
+type MetadataSetter interface {
+	SetMetadata(ctx context.Context, path string, metadata map[string]string) error
+}
+
 // Linker get dowload link with expiration
 type Linker interface {
 	Link(path string, expire time.Duration) (string, error)
@@ -97,6 +143,13 @@ type DeleterWithContext interface {
 	DeleteWithContext(ctx context.Context, path string) error
 }
 
+// BatchDeleter deletes many objects at once, every path is tried and failures are reported as *DeleteError.
+// DeleteAll removes the object at the path and every object under it, same as os.RemoveAll.
+type BatchDeleter interface {
+	DeleteMany(ctx context.Context, paths []string) error
+	DeleteAll(ctx context.Context, prefix string) error
+}
+
 // Stater get information about the file
 type Stater interface {
 	Stat(path string) (FileInfo, error)
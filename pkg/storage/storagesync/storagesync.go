@@ -0,0 +1,506 @@
+// Package storagesync makes the directory tree of one storage the same as the tree of another one,
+// the storages can be of any kind, for example local file system volume and s3 bucket.
+package storagesync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+)
+
+// DefaultConcurrency number of files transferred in parallel when Options.Concurrency is not set
+const DefaultConcurrency = 4
+
+// Compare how the files with the same size are compared
+type Compare int
+
+const (
+	// CompareAuto compare ETags when both sides have md5 ETags, otherwise compare modification times
+	CompareAuto Compare = iota
+
+	// CompareSize files with the same size are the same
+	CompareSize
+
+	// CompareETag files with different or missing ETags are changed
+	CompareETag
+
+	// CompareModTime files that are newer in the source are changed
+	CompareModTime
+)
+
+// Action what is done with the file
+type Action string
+
+const (
+	// ActionCreate file is missing in the destination
+	ActionCreate Action = "create"
+
+	// ActionUpdate file in the destination is different from the source
+	ActionUpdate Action = "update"
+
+	// ActionDelete file is missing in the source and Options.Delete is set
+	ActionDelete Action = "delete"
+)
+
+// Event progress of the sync, reported once the action is done or planned in a dry run
+type Event struct {
+	Action Action
+	Path   string
+	Size   int64
+	Err    error
+}
+
+// Options of the sync, nil options sync new and changed files with DefaultConcurrency
+type Options struct {
+	// Compare how the files with the same size are compared
+	Compare Compare
+
+	// Delete remove the destination files that are missing in the source
+	Delete bool
+
+	// DryRun only report what would be done
+	DryRun bool
+
+	// Include sync only the files that match one of the patterns, empty list includes all of the files.
+	// Patterns are matched with path.Match against the relative path and against the file name.
+	Include []string
+
+	// Exclude skip the files that match one of the patterns, excluded files are not deleted either
+	Exclude []string
+
+	// Concurrency number of files transferred in parallel
+	Concurrency int
+
+	// Progress called after every action, calls are not concurrent
+	Progress func(event *Event)
+}
+
+// Result number of files in each state, Bytes is the size of transferred files
+type Result struct {
+	Created int
+	Updated int
+	Deleted int
+	Skipped int
+	Bytes   int64
+}
+
+// Error files that could not be synced, the rest of the files are synced
+type Error struct {
+	Errs map[string]error
+}
+
+// Error get error message
+func (e *Error) Error() string {
+	msgs := []string{}
+
+	for _, path := range e.Paths() {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", path, e.Errs[path]))
+	}
+
+	return fmt.Sprintf("sync %d files failed: %s", len(msgs), strings.Join(msgs, "; "))
+}
+
+// Unwrap get the error of the first failed file in lexical order
+func (e *Error) Unwrap() error {
+	if paths := e.Paths(); len(paths) > 0 {
+		return e.Errs[paths[0]]
+	}
+
+	return nil
+}
+
+// Paths get relative paths of the files that failed in lexical order
+func (e *Error) Paths() []string {
+	paths := make([]string, 0, len(e.Errs))
+
+	for path := range e.Errs {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
+
+// file state of the file in the tree
+type file struct {
+	path    string
+	size    int64
+	modTime time.Time
+	eTag    string
+}
+
+// task action on the file with the relative path
+type task struct {
+	action Action
+	rel    string
+	src    *file
+}
+
+// Sync upload new and changed files from 'srcPath' of the source to 'dstPath' of the destination.
+// When the source and the destination are the same storage files are copied with Copy,
+// so s3 copies them on the server side in parts. Files that fail are reported with *Error.
+func Sync(ctx context.Context, src storage.Storage, srcPath string, dst storage.Storage, dstPath string, options *Options) (*Result, error) {
+	if options == nil {
+		options = new(Options)
+	}
+
+	for _, pattern := range append(append([]string{}, options.Include...), options.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("pattern '%s': %w", pattern, err)
+		}
+	}
+
+	srcFiles, err := scan(ctx, src, srcPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dstFiles, err := scan(ctx, dst, dstPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	res := new(Result)
+	tasks := []*task{}
+	deletes := []string{}
+
+	for _, rel := range sortedPaths(srcFiles) {
+		if !options.match(rel) {
+			continue
+		}
+
+		dstFile, ok := dstFiles[rel]
+
+		if ok {
+			statETags(src, srcFiles[rel], dst, dstFile, options.Compare)
+		}
+
+		switch {
+		case !ok:
+			tasks = append(tasks, &task{ActionCreate, rel, srcFiles[rel]})
+		case changed(srcFiles[rel], dstFile, options.Compare):
+			tasks = append(tasks, &task{ActionUpdate, rel, srcFiles[rel]})
+		default:
+			res.Skipped++
+		}
+	}
+
+	for _, rel := range sortedPaths(dstFiles) {
+		if _, ok := srcFiles[rel]; !ok && options.Delete && options.match(rel) {
+			deletes = append(deletes, rel)
+		}
+	}
+
+	r := &runner{
+		src:     src,
+		dst:     dst,
+		dstPath: dstPath,
+		same:    storage.SameStorage(src, dst),
+		options: options,
+		res:     res,
+		errs:    map[string]error{},
+	}
+
+	r.transfer(ctx, tasks)
+	r.delete(ctx, deletes, dstFiles)
+
+	if len(r.errs) > 0 {
+		return res, &Error{Errs: r.errs}
+	}
+
+	return res, nil
+}
+
+// runner executes the tasks and keeps the result
+type runner struct {
+	src     storage.Storage
+	dst     storage.Storage
+	dstPath string
+	same    bool
+	options *Options
+	mu      sync.Mutex
+	res     *Result
+	errs    map[string]error
+}
+
+// transfer upload the files with bounded parallelism
+func (r *runner) transfer(ctx context.Context, tasks []*task) {
+	concurrency := r.options.Concurrency
+
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	queue := make(chan *task)
+	wg := new(sync.WaitGroup)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for tsk := range queue {
+				err := ctx.Err()
+
+				if err == nil && !r.options.DryRun {
+					err = r.upload(ctx, tsk)
+				}
+
+				r.done(&Event{Action: tsk.action, Path: tsk.rel, Size: tsk.src.size, Err: err})
+			}
+		}()
+	}
+
+	for _, tsk := range tasks {
+		queue <- tsk
+	}
+
+	close(queue)
+	wg.Wait()
+}
+
+// upload copy the file to the destination
+func (r *runner) upload(ctx context.Context, tsk *task) error {
+	loc := join(r.dstPath, tsk.rel)
+
+	if r.same {
+		return r.dst.CopyWithContext(ctx, tsk.src.path, loc)
+	}
+
+	body, err := r.src.GetWithContext(ctx, tsk.src.path)
+
+	if err != nil {
+		return err
+	}
+
+	defer body.Close()
+
+	return r.dst.PutWithContext(ctx, loc, body)
+}
+
+// delete remove extraneous files from the destination with one batch
+func (r *runner) delete(ctx context.Context, rels []string, files map[string]*file) {
+	if len(rels) == 0 {
+		return
+	}
+
+	errs := map[string]error{}
+
+	if !r.options.DryRun {
+		paths := make([]string, 0, len(rels))
+
+		for _, rel := range rels {
+			paths = append(paths, files[rel].path)
+		}
+
+		err := storage.DeleteMany(ctx, r.dst, paths)
+		derr := new(storage.DeleteError)
+
+		switch {
+		case errors.As(err, &derr):
+			errs = derr.Errs
+		case err != nil:
+			for _, path := range paths {
+				errs[path] = err
+			}
+		}
+	}
+
+	for _, rel := range rels {
+		r.done(&Event{Action: ActionDelete, Path: rel, Size: files[rel].size, Err: errs[files[rel].path]})
+	}
+}
+
+// done count the event and report the progress
+func (r *runner) done(event *Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case event.Err != nil:
+		r.errs[event.Path] = event.Err
+	case event.Action == ActionCreate:
+		r.res.Created++
+		r.res.Bytes += event.Size
+	case event.Action == ActionUpdate:
+		r.res.Updated++
+		r.res.Bytes += event.Size
+	case event.Action == ActionDelete:
+		r.res.Deleted++
+	}
+
+	if r.options.Progress != nil {
+		r.options.Progress(event)
+	}
+}
+
+// match check if the relative path passes include and exclude patterns
+func (o *Options) match(rel string) bool {
+	matches := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, rel); ok {
+				return true
+			}
+
+			if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if len(o.Include) > 0 && !matches(o.Include) {
+		return false
+	}
+
+	return !matches(o.Exclude)
+}
+
+// scan collect the files of the tree by their paths relative to the root,
+// uses entries of WalkFunc when the storage has it, otherwise walks and stats every file
+func scan(ctx context.Context, store storage.Storage, root string) (map[string]*file, error) {
+	prefix := strings.Trim(root, "/")
+
+	if len(prefix) > 0 {
+		prefix += "/"
+	}
+
+	files := map[string]*file{}
+	add := func(fl *file) {
+		rel := strings.TrimPrefix(fl.path, "/")
+
+		if !strings.HasPrefix(rel, prefix) || len(rel) == len(prefix) {
+			return
+		}
+
+		files[rel[len(prefix):]] = fl
+	}
+
+	if walker, ok := store.(storage.WalkerFunc); ok {
+		err := walker.WalkFunc(ctx, root, func(entry *storage.Entry) error {
+			if !entry.IsDir {
+				add(&file{entry.Path, entry.Size, entry.LastModified, entry.ETag})
+			}
+
+			return nil
+		})
+
+		if !errors.Is(err, storage.ErrNotSupported) {
+			return files, err
+		}
+	}
+
+	paths := []string{}
+	err := store.WalkWithContext(ctx, root, func(path string) {
+		paths = append(paths, path)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range paths {
+		info, err := store.Stat(path)
+
+		if errors.Is(err, storage.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		add(&file{path, info.Size(), info.ModTime(), info.ETag()})
+	}
+
+	return files, nil
+}
+
+// statETags get ETags of the files with the same size from Stat when the compare needs them,
+// entries of some storages (fs) come without ETags
+func statETags(src storage.Storage, srcFile *file, dst storage.Storage, dstFile *file, compare Compare) {
+	if srcFile.size != dstFile.size || (compare != CompareAuto && compare != CompareETag) {
+		return
+	}
+
+	statETag(src, srcFile)
+	statETag(dst, dstFile)
+}
+
+// statETag get ETag of the file from Stat if the entry has none, the file stays without ETag on errors
+func statETag(store storage.Storage, fl *file) {
+	if len(fl.eTag) > 0 {
+		return
+	}
+
+	if info, err := store.Stat(fl.path); err == nil {
+		fl.eTag = info.ETag()
+	}
+}
+
+// changed check if the destination file is different from the source one
+func changed(src *file, dst *file, compare Compare) bool {
+	if src.size != dst.size {
+		return true
+	}
+
+	switch compare {
+	case CompareSize:
+		return false
+	case CompareETag:
+		return len(src.eTag) == 0 || strings.Trim(src.eTag, `"`) != strings.Trim(dst.eTag, `"`)
+	case CompareModTime:
+		return src.modTime.After(dst.modTime)
+	}
+
+	if srcSum, dstSum := md5ETag(src.eTag), md5ETag(dst.eTag); len(srcSum) > 0 && len(dstSum) > 0 {
+		return srcSum != dstSum
+	}
+
+	return src.modTime.After(dst.modTime)
+}
+
+// md5ETag get the md5 sum from the ETag, multipart and other ETags that are not md5 give empty string
+func md5ETag(eTag string) string {
+	sum := strings.ToLower(strings.Trim(eTag, `"`))
+
+	if len(sum) != 32 || strings.Trim(sum, "0123456789abcdef") != "" {
+		return ""
+	}
+
+	return sum
+}
+
+// join get the destination path of the relative path
+func join(root string, rel string) string {
+	if dir := strings.TrimSuffix(root, "/"); len(dir) > 0 {
+		return dir + "/" + rel
+	}
+
+	return rel
+}
+
+// sortedPaths get the relative paths in lexical order
+func sortedPaths(files map[string]*file) []string {
+	paths := make([]string, 0, len(files))
+
+	for path := range files {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
@@ -0,0 +1,236 @@
+package storagesync_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/protsack-stephan/dev-toolkit/lib/fs"
+	"github.com/protsack-stephan/dev-toolkit/lib/mem"
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage/storagesync"
+	"github.com/stretchr/testify/assert"
+)
+
+var syncTestData = []byte("hello sync")
+
+var errSyncTestFailed = errors.New("backend is down")
+
+// failingStorage rejects all uploads
+type failingStorage struct {
+	*mem.Storage
+}
+
+func (s *failingStorage) PutWithContext(ctx context.Context, path string, body io.Reader) error {
+	return errSyncTestFailed
+}
+
+// copyStorage counts the copies
+type copyStorage struct {
+	*mem.Storage
+	copies int
+}
+
+func (s *copyStorage) CopyWithContext(ctx context.Context, src string, dst string, options ...map[string]interface{}) error {
+	s.copies++
+	return s.Storage.CopyWithContext(ctx, src, dst, options...)
+}
+
+func put(t *testing.T, store storage.Storage, files map[string]string) {
+	t.Helper()
+
+	for path, data := range files {
+		if err := store.Put(path, bytes.NewReader([]byte(data))); err != nil {
+			t.Fatalf("put '%s': %v", path, err)
+		}
+	}
+}
+
+func read(store storage.Storage, path string) string {
+	body, err := store.Get(path)
+
+	if err != nil {
+		return ""
+	}
+
+	defer body.Close()
+	data, _ := io.ReadAll(body)
+
+	return string(data)
+}
+
+func TestSync(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	t.Run("upload new and changed files", func(t *testing.T) {
+		src, dst := mem.NewStorage(), mem.NewStorage()
+		put(t, src, map[string]string{"data/a.txt": "a", "data/b/c.txt": "c", "data/d.txt": "new d", "other/e.txt": "e"})
+		put(t, dst, map[string]string{"backup/d.txt": "d", "backup/f.txt": "f"})
+
+		res, err := storagesync.Sync(ctx, src, "data", dst, "backup", nil)
+		assert.NoError(err)
+		assert.Equal(&storagesync.Result{Created: 2, Updated: 1, Bytes: 7}, res)
+		assert.Equal("a", read(dst, "backup/a.txt"))
+		assert.Equal("c", read(dst, "backup/b/c.txt"))
+		assert.Equal("new d", read(dst, "backup/d.txt"))
+		assert.Equal("f", read(dst, "backup/f.txt"))
+		assert.Empty(read(dst, "backup/e.txt"))
+
+		res, err = storagesync.Sync(ctx, src, "data", dst, "backup", nil)
+		assert.NoError(err)
+		assert.Equal(&storagesync.Result{Skipped: 3}, res)
+	})
+
+	t.Run("delete extraneous files", func(t *testing.T) {
+		src, dst := mem.NewStorage(), mem.NewStorage()
+		put(t, src, map[string]string{"a.txt": "a"})
+		put(t, dst, map[string]string{"a.txt": "a", "b.txt": "b", "c.log": "c"})
+
+		res, err := storagesync.Sync(ctx, src, "/", dst, "/", &storagesync.Options{Delete: true, Exclude: []string{"*.log"}})
+		assert.NoError(err)
+		assert.Equal(&storagesync.Result{Deleted: 1, Skipped: 1}, res)
+		assert.Empty(read(dst, "b.txt"))
+		assert.Equal("c", read(dst, "c.log"))
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		src, dst := mem.NewStorage(), mem.NewStorage()
+		put(t, src, map[string]string{"a.txt": "a"})
+		put(t, dst, map[string]string{"b.txt": "b"})
+		events := []storagesync.Event{}
+
+		res, err := storagesync.Sync(ctx, src, "/", dst, "/", &storagesync.Options{
+			Delete: true,
+			DryRun: true,
+			Progress: func(event *storagesync.Event) {
+				events = append(events, *event)
+			},
+		})
+		assert.NoError(err)
+		assert.Equal(&storagesync.Result{Created: 1, Deleted: 1, Bytes: 1}, res)
+		assert.Equal([]storagesync.Event{
+			{Action: storagesync.ActionCreate, Path: "a.txt", Size: 1},
+			{Action: storagesync.ActionDelete, Path: "b.txt", Size: 1},
+		}, events)
+		assert.Empty(read(dst, "a.txt"))
+		assert.Equal("b", read(dst, "b.txt"))
+	})
+
+	t.Run("include patterns", func(t *testing.T) {
+		src, dst := mem.NewStorage(), mem.NewStorage()
+		put(t, src, map[string]string{"a.txt": "a", "b/c.txt": "c", "b/d.json": "d"})
+
+		res, err := storagesync.Sync(ctx, src, "/", dst, "/", &storagesync.Options{Include: []string{"*.txt"}})
+		assert.NoError(err)
+		assert.Equal(2, res.Created)
+		assert.Equal("c", read(dst, "b/c.txt"))
+		assert.Empty(read(dst, "b/d.json"))
+
+		_, err = storagesync.Sync(ctx, src, "/", dst, "/", &storagesync.Options{Exclude: []string{"["}})
+		assert.Error(err)
+	})
+
+	t.Run("compare with size", func(t *testing.T) {
+		src, dst := mem.NewStorage(), mem.NewStorage()
+		put(t, dst, map[string]string{"a.txt": "b"})
+		put(t, src, map[string]string{"a.txt": "a"})
+
+		res, err := storagesync.Sync(ctx, src, "/", dst, "/", &storagesync.Options{Compare: storagesync.CompareSize})
+		assert.NoError(err)
+		assert.Equal(1, res.Skipped)
+
+		res, err = storagesync.Sync(ctx, src, "/", dst, "/", &storagesync.Options{Compare: storagesync.CompareETag})
+		assert.NoError(err)
+		assert.Equal(1, res.Updated)
+		assert.Equal("a", read(dst, "a.txt"))
+	})
+
+	t.Run("sync file system volume", func(t *testing.T) {
+		vol := t.TempDir()
+		src, dst := fs.NewStorage(vol), mem.NewStorage()
+		put(t, src, map[string]string{"data/a.txt": "a", "data/b/c.txt": "c"})
+
+		res, err := storagesync.Sync(ctx, src, "/data", dst, "/", nil)
+		assert.NoError(err)
+		assert.Equal(2, res.Created)
+		assert.Equal("c", read(dst, "b/c.txt"))
+
+		back := fs.NewStorage(filepath.Join(vol, "back"))
+		res, err = storagesync.Sync(ctx, dst, "/", back, "/", nil)
+		assert.NoError(err)
+		assert.Equal(2, res.Created)
+		assert.Equal("a", read(back, "a.txt"))
+	})
+
+	t.Run("compare file system files by etag", func(t *testing.T) {
+		src, dst := fs.NewStorage(t.TempDir()), mem.NewStorage()
+		put(t, src, map[string]string{"a.txt": "a", "b/c.txt": "c", "d.txt": "d"})
+
+		res, err := storagesync.Sync(ctx, src, "/", dst, "/", &storagesync.Options{Compare: storagesync.CompareETag})
+		assert.NoError(err)
+		assert.Equal(3, res.Created)
+
+		res, err = storagesync.Sync(ctx, src, "/", dst, "/", &storagesync.Options{Compare: storagesync.CompareETag})
+		assert.NoError(err)
+		assert.Equal(&storagesync.Result{Skipped: 3}, res)
+
+		put(t, dst, map[string]string{"a.txt": "x"})
+		res, err = storagesync.Sync(ctx, src, "/", dst, "/", nil)
+		assert.NoError(err)
+		assert.Equal(&storagesync.Result{Updated: 1, Skipped: 2, Bytes: 1}, res)
+		assert.Equal("a", read(dst, "a.txt"))
+	})
+
+	t.Run("copy inside the same storage", func(t *testing.T) {
+		store := &copyStorage{Storage: mem.NewStorage()}
+		put(t, store, map[string]string{"data/a.txt": "a", "data/b.txt": "b"})
+
+		res, err := storagesync.Sync(ctx, store, "data", store, "backup", nil)
+		assert.NoError(err)
+		assert.Equal(2, res.Created)
+		assert.Equal(2, store.copies)
+		assert.Equal("b", read(store, "backup/b.txt"))
+	})
+
+	t.Run("report failed files", func(t *testing.T) {
+		src, dst := mem.NewStorage(), &failingStorage{mem.NewStorage()}
+		put(t, src, map[string]string{"a.txt": "a", "b.txt": "b"})
+		mu := new(sync.Mutex)
+		failed := 0
+
+		res, err := storagesync.Sync(ctx, src, "/", dst, "/", &storagesync.Options{
+			Concurrency: 2,
+			Progress: func(event *storagesync.Event) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				if event.Err != nil {
+					failed++
+				}
+			},
+		})
+		assert.ErrorIs(err, errSyncTestFailed)
+		assert.Equal(0, res.Created)
+		assert.Equal(2, failed)
+
+		serr := new(storagesync.Error)
+		assert.True(errors.As(err, &serr))
+		assert.Equal([]string{"a.txt", "b.txt"}, serr.Paths())
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		src, dst := mem.NewStorage(), mem.NewStorage()
+		put(t, src, map[string]string{"a.txt": string(syncTestData)})
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := storagesync.Sync(cctx, src, "/", dst, "/", nil)
+		assert.ErrorIs(err, context.Canceled)
+		assert.Empty(read(dst, "a.txt"))
+	})
+}
@@ -0,0 +1,761 @@
+// Package storagetest behavioral test suite for storage.Storage implementations.
+// Run the suite from the implementation tests to make sure it behaves the same way as built-in backends:
+//
+//	func TestConformance(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) storage.Storage {
+//			return NewStorage(t.TempDir())
+//		})
+//	}
+package storagetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"testing"
+
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// Factory creates new empty storage instance for a single test
+type Factory func(t *testing.T) storage.Storage
+
+var testData = []byte("hello storage")
+
+// Run runs all of the conformance checks, every check gets a fresh storage from the factory
+func Run(t *testing.T, factory Factory) {
+	t.Run("put and get", func(t *testing.T) {
+		testPutGet(t, factory(t))
+	})
+
+	t.Run("create", func(t *testing.T) {
+		testCreate(t, factory(t))
+	})
+
+	t.Run("nested paths", func(t *testing.T) {
+		testNestedPaths(t, factory(t))
+	})
+
+	t.Run("list", func(t *testing.T) {
+		testList(t, factory(t))
+	})
+
+	t.Run("list with delimiter", func(t *testing.T) {
+		testListDelimiter(t, factory(t))
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		testInvalidOptions(t, factory(t))
+	})
+
+	t.Run("walk", func(t *testing.T) {
+		testWalk(t, factory(t))
+	})
+
+	t.Run("walk missing prefix", func(t *testing.T) {
+		testWalkMissing(t, factory(t))
+	})
+
+	t.Run("walk with entries", func(t *testing.T) {
+		testWalkFunc(t, factory(t))
+	})
+
+	t.Run("list iterator", func(t *testing.T) {
+		testListIter(t, factory(t))
+	})
+
+	t.Run("put with options", func(t *testing.T) {
+		testPutWithOptions(t, factory(t))
+	})
+
+	t.Run("set metadata", func(t *testing.T) {
+		testSetMetadata(t, factory(t))
+	})
+
+	t.Run("get range", func(t *testing.T) {
+		testGetRange(t, factory(t))
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		testCopy(t, factory(t))
+	})
+
+	t.Run("move", func(t *testing.T) {
+		testMove(t, factory(t))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		testDelete(t, factory(t))
+	})
+
+	t.Run("delete missing", func(t *testing.T) {
+		testDeleteMissing(t, factory(t))
+	})
+
+	t.Run("delete many", func(t *testing.T) {
+		testDeleteMany(t, factory(t))
+	})
+
+	t.Run("delete all", func(t *testing.T) {
+		testDeleteAll(t, factory(t))
+	})
+
+	t.Run("stat", func(t *testing.T) {
+		testStat(t, factory(t))
+	})
+
+	t.Run("missing objects", func(t *testing.T) {
+		testMissing(t, factory(t))
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		testCanceledContext(t, factory(t))
+	})
+}
+
+func put(t *testing.T, store storage.Storage, path string, data []byte) {
+	t.Helper()
+
+	if err := store.Put(path, bytes.NewReader(data)); err != nil {
+		t.Fatalf("put '%s': %v", path, err)
+	}
+}
+
+func get(t *testing.T, store storage.Storage, path string) []byte {
+	t.Helper()
+
+	body, err := store.Get(path)
+
+	if err != nil {
+		t.Fatalf("get '%s': %v", path, err)
+	}
+
+	defer body.Close()
+	data, err := io.ReadAll(body)
+
+	if err != nil {
+		t.Fatalf("read '%s': %v", path, err)
+	}
+
+	return data
+}
+
+func walk(t *testing.T, store storage.Storage, path string) []string {
+	t.Helper()
+	paths := []string{}
+
+	if err := store.Walk(path, func(path string) { paths = append(paths, path) }); err != nil {
+		t.Fatalf("walk '%s': %v", path, err)
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
+
+func testPutGet(t *testing.T, store storage.Storage) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	put(t, store, "put.txt", testData)
+	assert.Equal(testData, get(t, store, "put.txt"))
+
+	assert.NoError(store.PutWithContext(ctx, "put.txt", bytes.NewReader([]byte("overwrite"))))
+	body, err := store.GetWithContext(ctx, "put.txt")
+	assert.NoError(err)
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	assert.NoError(err)
+	assert.Equal([]byte("overwrite"), data)
+
+	put(t, store, "empty.txt", []byte{})
+	assert.Empty(get(t, store, "empty.txt"))
+}
+
+func testCreate(t *testing.T, store storage.Storage) {
+	assert := assert.New(t)
+
+	file, err := store.Create("create/file.txt")
+	assert.NoError(err)
+
+	_, err = file.Write(testData)
+	assert.NoError(err)
+	assert.NoError(file.Close())
+	assert.Equal(testData, get(t, store, "create/file.txt"))
+
+	creator, ok := store.(storage.CreatorWithContext)
+
+	if !ok {
+		return
+	}
+
+	file, err = creator.CreateWithContext(context.Background(), "create/file.txt")
+	assert.NoError(err)
+	assert.NoError(file.Close())
+	assert.Empty(get(t, store, "create/file.txt"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = creator.CreateWithContext(ctx, "create/canceled.txt")
+	assert.Error(err)
+}
+
+func testNestedPaths(t *testing.T, store storage.Storage) {
+	assert := assert.New(t)
+
+	put(t, store, "nested/one/two/three.txt", testData)
+	assert.Equal(testData, get(t, store, "nested/one/two/three.txt"))
+	assert.Equal(testData, get(t, store, "/nested/one/two/three.txt"))
+}
+
+func testList(t *testing.T, store storage.Storage) {
+	assert := assert.New(t)
+
+	put(t, store, "list/a.txt", testData)
+	put(t, store, "list/b.txt", testData)
+	put(t, store, "list/sub/c.txt", testData)
+	put(t, store, "other.txt", testData)
+
+	content, err := store.List("list/")
+	assert.NoError(err)
+	assert.ElementsMatch([]string{"a.txt", "b.txt", "sub"}, content)
+
+	content, err = store.ListWithContext(context.Background(), "list/")
+	assert.NoError(err)
+	assert.ElementsMatch([]string{"a.txt", "b.txt", "sub"}, content)
+}
+
+func testListDelimiter(t *testing.T, store storage.Storage) {
+	assert := assert.New(t)
+
+	put(t, store, "list/a.txt", testData)
+	put(t, store, "list/sub/b.txt", testData)
+	put(t, store, "list/sub/deep/c.txt", testData)
+	put(t, store, "list/other/d.txt", testData)
+
+	content, err := store.List("list/", map[string]interface{}{"delimiter": "/"})
+	assert.NoError(err)
+	assert.ElementsMatch([]string{"other", "sub"}, content)
+}
+
+func testInvalidOptions(t *testing.T, store storage.Storage) {
+	assert := assert.New(t)
+	invalid := map[string]interface{}{"delimiter": 1}
+
+	put(t, store, "options/a.txt", testData)
+
+	_, err := store.List("options/", invalid)
+	assert.ErrorIs(err, storage.ErrInvalidOption)
+
+	_, err = store.ListWithContext(context.Background(), "options/", invalid)
+	assert.ErrorIs(err, storage.ErrInvalidOption)
+
+	assert.ErrorIs(store.Copy("options/a.txt", "options/b.txt", map[string]interface{}{"dstBucket": 1}), storage.ErrInvalidOption)
+
+	_, err = store.List("options/", map[string]interface{}{"delimeter": "/"})
+	assert.ErrorIs(err, storage.ErrInvalidOption)
+
+	if lister, ok := store.(storage.ListIterator); ok {
+		iter := lister.ListIter(context.Background(), "options/", invalid)
+		assert.False(iter.Next())
+		assert.ErrorIs(iter.Err(), storage.ErrInvalidOption)
+	}
+}
+
+func testWalk(t *testing.T, store storage.Storage) {
+	assert := assert.New(t)
+	expected := []string{
+		"walk/a.txt",
+		"walk/one/b.txt",
+		"walk/one/two/c.txt",
+		"walk/three/d.txt",
+	}
+
+	for _, path := range expected {
+		put(t, store, path, testData)
+	}
+
+	put(t, store, "other/e.txt", testData)
+	assert.Equal(expected, walk(t, store, "walk/"))
+
+	paths := []string{}
+	assert.NoError(store.WalkWithContext(context.Background(), "walk/one/", func(path string) {
+		paths = append(paths, path)
+	}))
+	sort.Strings(paths)
+	assert.Equal(expected[1:3], paths)
+}
+
+func testWalkFunc(t *testing.T, store storage.Storage) {
+	walker, ok := store.(storage.WalkerFunc)
+
+	if !ok {
+		t.Skip("storage.WalkerFunc is not implemented")
+	}
+
+	assert := assert.New(t)
+	ctx := context.Background()
+	errStop := errors.New("stop walk")
+
+	for _, path := range []string{"walk/a.txt", "walk/one/b.txt", "walk/one/two/c.txt", "walk/three/d.txt"} {
+		put(t, store, path, testData)
+	}
+
+	walkFunc := func(path string, skip string) ([]string, []string) {
+		files := []string{}
+		dirs := []string{}
+
+		assert.NoError(walker.WalkFunc(ctx, path, func(entry *storage.Entry) error {
+			if entry.IsDir {
+				dirs = append(dirs, entry.Path)
+			} else {
+				assert.Equal(int64(len(testData)), entry.Size)
+				files = append(files, entry.Path)
+			}
+
+			if entry.Path == skip {
+				return storage.SkipDir
+			}
+
+			return nil
+		}))
+
+		sort.Strings(files)
+		sort.Strings(dirs)
+
+		return files, dirs
+	}
+
+	files, dirs := walkFunc("walk/", "")
+	assert.Equal([]string{"walk/a.txt", "walk/one/b.txt", "walk/one/two/c.txt", "walk/three/d.txt"}, files)
+	assert.Equal([]string{"walk/one", "walk/one/two", "walk/three"}, dirs)
+
+	files, _ = walkFunc("walk/", "walk/one")
+	assert.Equal([]string{"walk/a.txt", "walk/three/d.txt"}, files)
+
+	files, _ = walkFunc("walk/", "walk/one/b.txt")
+	assert.Equal([]string{"walk/a.txt", "walk/one/b.txt", "walk/three/d.txt"}, files)
+
+	files, _ = walkFunc("missing/", "")
+	assert.Empty(files)
+
+	err := walker.WalkFunc(ctx, "walk/", func(entry *storage.Entry) error {
+		return errStop
+	})
+	assert.ErrorIs(err, errStop)
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Error(walker.WalkFunc(cctx, "walk/", func(entry *storage.Entry) error {
+		return nil
+	}))
+}
+
+func testWalkMissing(t *testing.T, store storage.Storage) {
+	assert.Empty(t, walk(t, store, "missing/"))
+}
+
+func collect(t *testing.T, iter storage.Iterator) []*storage.Entry {
+	t.Helper()
+	entries := []*storage.Entry{}
+
+	for iter.Next() {
+		entries = append(entries, iter.Entry())
+	}
+
+	if err := iter.Err(); err != nil {
+		t.Fatalf("iterate: %v", err)
+	}
+
+	return entries
+}
+
+func paths(entries []*storage.Entry) []string {
+	paths := []string{}
+
+	for _, entry := range entries {
+		paths = append(paths, entry.Path)
+	}
+
+	return paths
+}
+
+func testListIter(t *testing.T, store storage.Storage) {
+	lister, ok := store.(storage.ListIterator)
+
+	if !ok {
+		t.Skip("storage.ListIterator is not implemented")
+	}
+
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	put(t, store, "iter/a.txt", testData)
+	put(t, store, "iter/b/c.txt", testData)
+	put(t, store, "iter/b/d.txt", testData)
+	put(t, store, "iter/e.txt", testData)
+	put(t, store, "other.txt", testData)
+
+	entries := collect(t, lister.ListIter(ctx, "iter/"))
+	assert.Equal([]string{"iter/a.txt", "iter/b/c.txt", "iter/b/d.txt", "iter/e.txt"}, paths(entries))
+	assert.Equal(int64(len(testData)), entries[0].Size)
+	assert.False(entries[0].IsDir)
+
+	entries = collect(t, lister.ListIter(ctx, "iter/", storage.WithDelimiter("/")))
+	assert.Equal([]string{"iter/a.txt", "iter/b", "iter/e.txt"}, paths(entries))
+	assert.True(entries[1].IsDir)
+
+	iter := lister.ListIter(ctx, "iter/")
+	assert.True(iter.Next())
+	assert.True(iter.Next())
+	entries = collect(t, lister.ListIter(ctx, "iter/", storage.WithToken(iter.Token())))
+	assert.Equal([]string{"iter/b/d.txt", "iter/e.txt"}, paths(entries))
+
+	iter = lister.ListIter(ctx, "iter/", storage.WithDelimiter("/"))
+	assert.True(iter.Next())
+	assert.True(iter.Next())
+	entries = collect(t, lister.ListIter(ctx, "iter/", storage.WithDelimiter("/"), storage.WithToken(iter.Token())))
+	assert.Equal([]string{"iter/e.txt"}, paths(entries))
+
+	assert.Empty(collect(t, lister.ListIter(ctx, "missing/")))
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	iter = lister.ListIter(cctx, "iter/")
+	assert.False(iter.Next())
+	assert.Error(iter.Err())
+}
+
+func testPutWithOptions(t *testing.T, store storage.Storage) {
+	putter, ok := store.(storage.PutterWithOptions)
+
+	if !ok {
+		t.Skip("storage.PutterWithOptions is not implemented")
+	}
+
+	assert := assert.New(t)
+	ctx := context.Background()
+	options := &storage.PutOptions{
+		ContentType:        "application/x-storagetest",
+		ContentEncoding:    "identity",
+		ContentDisposition: "attachment",
+		ContentLanguage:    "en",
+		CacheControl:       "max-age=60",
+		Metadata:           map[string]string{"Author": "storagetest"},
+	}
+
+	assert.NoError(putter.PutWithOptions(ctx, "options.txt", bytes.NewReader(testData), options))
+	assert.Equal(testData, get(t, store, "options.txt"))
+
+	info, err := store.Stat("options.txt")
+	assert.NoError(err)
+	assert.Equal(int64(len(testData)), info.Size())
+	assert.Equal(options.ContentType, info.ContentType())
+	assert.Equal(options.ContentEncoding, storage.Content(info).ContentEncoding())
+	assert.Equal(options.ContentDisposition, storage.Content(info).ContentDisposition())
+	assert.Equal(options.ContentLanguage, storage.Content(info).ContentLanguage())
+	assert.Equal(options.CacheControl, storage.Content(info).CacheControl())
+
+	if assert.Contains(info.Metadata(), "Author") {
+		assert.Equal("storagetest", *info.Metadata()["Author"])
+	}
+
+	names, err := store.List("/")
+	assert.NoError(err)
+	assert.Equal([]string{"options.txt"}, names)
+	assert.Equal([]string{"options.txt"}, walk(t, store, "/"))
+
+	if lister, ok := store.(storage.ListIterator); ok {
+		assert.Equal([]string{"options.txt"}, paths(collect(t, lister.ListIter(ctx, "/"))))
+	}
+
+	assert.NoError(store.Copy("options.txt", "copy/options.txt"))
+	info, err = store.Stat("copy/options.txt")
+	assert.NoError(err)
+	assert.Equal(options.ContentType, info.ContentType())
+
+	put(t, store, "options.txt", testData)
+	info, err = store.Stat("options.txt")
+	assert.NoError(err)
+	assert.NotEqual(options.ContentType, info.ContentType())
+	assert.Empty(info.Metadata())
+}
+
+func testSetMetadata(t *testing.T, store storage.Storage) {
+	setter, ok := store.(storage.MetadataSetter)
+
+	if !ok {
+		t.Skip("storage.MetadataSetter is not implemented")
+	}
+
+	putter, ok := store.(storage.PutterWithOptions)
+
+	if !ok {
+		t.Skip("storage.PutterWithOptions is not implemented")
+	}
+
+	assert := assert.New(t)
+	ctx := context.Background()
+	options := &storage.PutOptions{
+		ContentType: "text/plain",
+		Metadata:    map[string]string{"Author": "storagetest"},
+	}
+
+	assert.NoError(putter.PutWithOptions(ctx, "meta/a.txt", bytes.NewReader(testData), options))
+	assert.NoError(setter.SetMetadata(ctx, "meta/a.txt", map[string]string{"Reviewer": "storagetest"}))
+	assert.Equal(testData, get(t, store, "meta/a.txt"))
+
+	info, err := store.Stat("meta/a.txt")
+	assert.NoError(err)
+	assert.Equal(options.ContentType, info.ContentType())
+	assert.NotContains(info.Metadata(), "Author")
+
+	if assert.Contains(info.Metadata(), "Reviewer") {
+		assert.Equal("storagetest", *info.Metadata()["Reviewer"])
+	}
+
+	assert.NoError(setter.SetMetadata(ctx, "meta/a.txt", nil))
+	info, err = store.Stat("meta/a.txt")
+	assert.NoError(err)
+	assert.Empty(info.Metadata())
+	assert.Equal(options.ContentType, info.ContentType())
+
+	err = setter.SetMetadata(ctx, "meta/missing.txt", map[string]string{"Author": "storagetest"})
+	assert.True(errors.Is(err, storage.ErrNotExist), "set metadata: %v", err)
+}
+
+func testGetRange(t *testing.T, store storage.Storage) {
+	getter, ok := store.(storage.RangeGetter)
+
+	if !ok {
+		t.Skip("storage.RangeGetter is not implemented")
+	}
+
+	assert := assert.New(t)
+	ctx := context.Background()
+	size := int64(len(testData))
+
+	put(t, store, "range/data.txt", testData)
+
+	read := func(offset int64, length int64) []byte {
+		body, err := getter.GetRange(ctx, "range/data.txt", offset, length)
+		assert.NoError(err)
+
+		if err != nil {
+			return nil
+		}
+
+		defer body.Close()
+		data, err := io.ReadAll(body)
+		assert.NoError(err)
+
+		return data
+	}
+
+	assert.Equal(testData[1:3], read(1, 2))
+	assert.Equal(testData[1:], read(1, -1))
+	assert.Equal(testData[1:], read(1, size))
+	assert.Empty(read(size, 1))
+	assert.Empty(read(0, 0))
+
+	_, err := getter.GetRange(ctx, "range/data.txt", -1, 1)
+	assert.ErrorIs(err, storage.ErrInvalidRange)
+
+	_, err = getter.GetRange(ctx, "range/missing.txt", 0, 1)
+	assert.True(errors.Is(err, storage.ErrNotExist), "get range: %v", err)
+
+	reader := storage.NewRangeReader(ctx, getter, "range/data.txt", size)
+	defer reader.Close()
+
+	buf := make([]byte, 2)
+	n, err := reader.ReadAt(buf, size-1)
+	assert.Equal(1, n)
+	assert.ErrorIs(err, io.EOF)
+
+	_, err = reader.Seek(2, io.SeekStart)
+	assert.NoError(err)
+	data, err := io.ReadAll(reader)
+	assert.NoError(err)
+	assert.Equal(testData[2:], data)
+}
+
+func testCopy(t *testing.T, store storage.Storage) {
+	assert := assert.New(t)
+
+	put(t, store, "copy/src.txt", testData)
+	assert.NoError(store.Copy("copy/src.txt", "copy/dst.txt"))
+	assert.Equal(testData, get(t, store, "copy/dst.txt"))
+	assert.Equal(testData, get(t, store, "copy/src.txt"))
+
+	assert.NoError(store.CopyWithContext(context.Background(), "copy/src.txt", "copy/nested/dst.txt"))
+	assert.Equal(testData, get(t, store, "copy/nested/dst.txt"))
+}
+
+func testMove(t *testing.T, store storage.Storage) {
+	mover, ok := store.(storage.Mover)
+
+	if !ok {
+		t.Skip("storage.Mover is not implemented")
+	}
+
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	if putter, ok := store.(storage.PutterWithOptions); ok {
+		options := &storage.PutOptions{Metadata: map[string]string{"Author": "storagetest"}}
+		assert.NoError(putter.PutWithOptions(ctx, "move/src.txt", bytes.NewReader(testData), options))
+	} else {
+		put(t, store, "move/src.txt", testData)
+	}
+
+	put(t, store, "move/nested/dst.txt", []byte("old"))
+	assert.NoError(mover.Move(ctx, "move/src.txt", "move/nested/dst.txt"))
+	assert.Equal(testData, get(t, store, "move/nested/dst.txt"))
+
+	_, err := store.Stat("move/src.txt")
+	assert.True(errors.Is(err, storage.ErrNotExist), "stat source: %v", err)
+
+	if _, ok := store.(storage.PutterWithOptions); ok {
+		info, err := store.Stat("move/nested/dst.txt")
+		assert.NoError(err)
+
+		if assert.Contains(info.Metadata(), "Author") {
+			assert.Equal("storagetest", *info.Metadata()["Author"])
+		}
+	}
+
+	assert.NoError(mover.Move(ctx, "move/nested/dst.txt", "move/nested/dst.txt"))
+	assert.Equal(testData, get(t, store, "move/nested/dst.txt"))
+
+	err = mover.Move(ctx, "move/missing.txt", "move/dst.txt")
+	assert.True(errors.Is(err, storage.ErrNotExist), "move missing: %v", err)
+
+	merr := new(storage.MoveError)
+
+	if assert.True(errors.As(err, &merr)) {
+		assert.False(merr.Copied)
+	}
+
+	_, err = store.Stat("move/dst.txt")
+	assert.Error(err)
+}
+
+func testDelete(t *testing.T, store storage.Storage) {
+	assert := assert.New(t)
+
+	put(t, store, "delete/a.txt", testData)
+	put(t, store, "delete/b.txt", testData)
+	assert.NoError(store.Delete("delete/a.txt"))
+	assert.NoError(store.DeleteWithContext(context.Background(), "delete/b.txt"))
+
+	_, err := store.Stat("delete/a.txt")
+	assert.Error(err)
+	_, err = store.Stat("delete/b.txt")
+	assert.Error(err)
+}
+
+func testDeleteMany(t *testing.T, store storage.Storage) {
+	deleter, ok := store.(storage.BatchDeleter)
+
+	if !ok {
+		t.Skip("storage.BatchDeleter is not implemented")
+	}
+
+	assert := assert.New(t)
+
+	for _, path := range []string{"many/a.txt", "many/b.txt", "many/c/d.txt", "many/e.txt"} {
+		put(t, store, path, testData)
+	}
+
+	assert.NoError(deleter.DeleteMany(context.Background(), []string{"many/a.txt", "many/c/d.txt", "many/e.txt", "many/missing.txt"}))
+	assert.Equal([]string{"many/b.txt"}, walk(t, store, "many"))
+	assert.NoError(deleter.DeleteMany(context.Background(), []string{}))
+}
+
+func testDeleteAll(t *testing.T, store storage.Storage) {
+	deleter, ok := store.(storage.BatchDeleter)
+
+	if !ok {
+		t.Skip("storage.BatchDeleter is not implemented")
+	}
+
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	for _, path := range []string{"all/a.txt", "all/b/c.txt", "all/b/d/e.txt", "all.txt", "allx/f.txt"} {
+		put(t, store, path, testData)
+	}
+
+	assert.NoError(deleter.DeleteAll(ctx, "all"))
+	assert.Empty(walk(t, store, "all"))
+	assert.Equal(testData, get(t, store, "all.txt"))
+	assert.Equal(testData, get(t, store, "allx/f.txt"))
+
+	assert.NoError(deleter.DeleteAll(ctx, "all.txt"))
+	_, err := store.Stat("all.txt")
+	assert.True(errors.Is(err, storage.ErrNotExist), "stat deleted: %v", err)
+
+	assert.NoError(deleter.DeleteAll(ctx, "missing"))
+	assert.Equal(testData, get(t, store, "allx/f.txt"))
+}
+
+func testDeleteMissing(t *testing.T, store storage.Storage) {
+	assert := assert.New(t)
+
+	assert.NoError(store.Delete("missing.txt"))
+	assert.NoError(store.DeleteWithContext(context.Background(), "missing/missing.txt"))
+}
+
+func testStat(t *testing.T, store storage.Storage) {
+	assert := assert.New(t)
+
+	put(t, store, "stat/data.txt", testData)
+	put(t, store, "stat/empty.txt", []byte{})
+
+	info, err := store.Stat("stat/data.txt")
+	assert.NoError(err)
+	assert.Equal(int64(len(testData)), info.Size())
+	assert.False(info.ModTime().IsZero())
+
+	info, err = store.Stat("stat/empty.txt")
+	assert.NoError(err)
+	assert.Zero(info.Size())
+}
+
+func testMissing(t *testing.T, store storage.Storage) {
+	assert := assert.New(t)
+
+	_, err := store.Get("missing.txt")
+	assert.True(errors.Is(err, storage.ErrNotExist), "get: %v", err)
+
+	_, err = store.GetWithContext(context.Background(), "missing/missing.txt")
+	assert.True(errors.Is(err, storage.ErrNotExist), "get with context: %v", err)
+
+	_, err = store.Stat("missing.txt")
+	assert.True(errors.Is(err, storage.ErrNotExist), "stat: %v", err)
+
+	err = store.Copy("missing.txt", "copy.txt")
+	assert.True(errors.Is(err, storage.ErrNotExist), "copy: %v", err)
+}
+
+func testCanceledContext(t *testing.T, store storage.Storage) {
+	assert := assert.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	put(t, store, "ctx/a.txt", testData)
+
+	assert.Error(store.PutWithContext(ctx, "ctx/b.txt", bytes.NewReader(testData)))
+	_, err := store.GetWithContext(ctx, "ctx/a.txt")
+	assert.Error(err)
+	_, err = store.ListWithContext(ctx, "ctx/")
+	assert.Error(err)
+	assert.Error(store.WalkWithContext(ctx, "ctx/", func(_ string) {}))
+	assert.Error(store.CopyWithContext(ctx, "ctx/a.txt", "ctx/c.txt"))
+	assert.Error(store.DeleteWithContext(ctx, "ctx/a.txt"))
+	assert.Equal(testData, get(t, store, "ctx/a.txt"))
+}
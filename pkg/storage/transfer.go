@@ -0,0 +1,316 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/protsack-stephan/dev-toolkit/internal/atomicfile"
+)
+
+// transferProgressStep number of bytes between the progress reports of the job
+const transferProgressStep = 1 << 20
+
+// TransferKind what the transfer job does
+type TransferKind int
+
+const (
+	// TransferCopy copy the object from the source storage to the destination storage
+	TransferCopy TransferKind = iota
+
+	// TransferUpload upload the local file to the destination storage
+	TransferUpload
+
+	// TransferDownload download the object from the source storage to the local file
+	TransferDownload
+)
+
+// String get name of the kind
+func (k TransferKind) String() string {
+	switch k {
+	case TransferCopy:
+		return "copy"
+	case TransferUpload:
+		return "upload"
+	case TransferDownload:
+		return "download"
+	}
+
+	return fmt.Sprintf("TransferKind(%d)", int(k))
+}
+
+// TransferJob single object transfer, create it with CopyJob, UploadJob or DownloadJob
+type TransferJob struct {
+	Kind    TransferKind
+	Src     Storage
+	SrcPath string
+	Dst     Storage
+	DstPath string
+}
+
+// CopyJob copy the object between the storages, objects inside of the same storage are copied with CopyWithContext
+func CopyJob(src Storage, srcPath string, dst Storage, dstPath string) *TransferJob {
+	return &TransferJob{Kind: TransferCopy, Src: src, SrcPath: srcPath, Dst: dst, DstPath: dstPath}
+}
+
+// UploadJob upload the local file to the storage
+func UploadJob(file string, dst Storage, dstPath string) *TransferJob {
+	return &TransferJob{Kind: TransferUpload, SrcPath: file, Dst: dst, DstPath: dstPath}
+}
+
+// DownloadJob download the object to the local file, the file is replaced only when the download is complete and flushed to disk
+func DownloadJob(src Storage, srcPath string, file string) *TransferJob {
+	return &TransferJob{Kind: TransferDownload, Src: src, SrcPath: srcPath, DstPath: file}
+}
+
+// TransferProgress state of the job, Size is -1 when the size is not known yet.
+// Bytes start over from zero when the job is retried.
+type TransferProgress struct {
+	Job     *TransferJob
+	Attempt int
+	Bytes   int64
+	Size    int64
+	Done    bool
+	Err     error
+}
+
+// TransferFailure job that failed with the error
+type TransferFailure struct {
+	Job *TransferJob
+	Err error
+}
+
+// TransferSummary result of the transfer, Bytes is the size of the succeeded jobs
+type TransferSummary struct {
+	Succeeded []*TransferJob
+	Failed    []*TransferFailure
+	Bytes     int64
+	Duration  time.Duration
+}
+
+// Err get error of the first failed job, nil when all of the jobs succeeded
+func (s *TransferSummary) Err() error {
+	if len(s.Failed) == 0 {
+		return nil
+	}
+
+	first := s.Failed[0]
+
+	return fmt.Errorf("%d of %d transfers failed, %s %s: %w", len(s.Failed), len(s.Failed)+len(s.Succeeded), first.Job.Kind, first.Job.SrcPath, first.Err)
+}
+
+// NewTransferManager create transfer manager that runs up to 'concurrency' jobs in parallel, 4 by default
+func NewTransferManager(concurrency int) *TransferManager {
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+
+	return &TransferManager{
+		Concurrency: concurrency,
+	}
+}
+
+// TransferManager runs the transfer jobs with a pool of workers
+type TransferManager struct {
+	// Concurrency number of jobs that run in parallel
+	Concurrency int
+
+	// Retry policy of the single job, the job starts over on transient errors of the storages
+	Retry RetryPolicy
+
+	// Progress called after every MiB of the job and once the job is done, calls are not concurrent.
+	// Workers wait for the callback to return, so it should be fast.
+	Progress func(progress *TransferProgress)
+
+	mu         sync.Mutex
+	progressMu sync.Mutex
+}
+
+// Run runs the jobs from the queue until it's closed, jobs that are left when the context is canceled fail with the context error
+func (m *TransferManager) Run(ctx context.Context, jobs <-chan *TransferJob) *TransferSummary {
+	start := time.Now()
+	summary := new(TransferSummary)
+	policy := m.Retry.withDefaults()
+	wg := new(sync.WaitGroup)
+	concurrency := m.Concurrency
+
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for job := range jobs {
+				size, err := m.run(ctx, &policy, job)
+
+				m.mu.Lock()
+
+				if err != nil {
+					summary.Failed = append(summary.Failed, &TransferFailure{job, err})
+				} else {
+					summary.Succeeded = append(summary.Succeeded, job)
+					summary.Bytes += size
+				}
+
+				m.mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	summary.Duration = time.Since(start)
+
+	return summary
+}
+
+// RunJobs runs the list of jobs, see Run
+func (m *TransferManager) RunJobs(ctx context.Context, jobs ...*TransferJob) *TransferSummary {
+	queue := make(chan *TransferJob, len(jobs))
+
+	for _, job := range jobs {
+		queue <- job
+	}
+
+	close(queue)
+
+	return m.Run(ctx, queue)
+}
+
+// run runs the job with retries and reports when it's done
+func (m *TransferManager) run(ctx context.Context, policy *RetryPolicy, job *TransferJob) (int64, error) {
+	progress := &TransferProgress{Job: job, Size: -1}
+
+	err := ctx.Err()
+
+	if err == nil {
+		err = policy.retry(ctx, func() error {
+			progress.Attempt++
+			progress.Bytes = 0
+
+			return m.transfer(ctx, job, progress)
+		}, job.Src, job.Dst)
+	}
+
+	progress.Done = true
+	progress.Err = err
+	m.report(progress)
+
+	return progress.Bytes, err
+}
+
+// transfer make one attempt of the job
+func (m *TransferManager) transfer(ctx context.Context, job *TransferJob, progress *TransferProgress) error {
+	switch job.Kind {
+	case TransferCopy:
+		if SameStorage(job.Src, job.Dst) {
+			info, err := job.Src.Stat(job.SrcPath)
+
+			if err != nil {
+				return err
+			}
+
+			if err := job.Dst.CopyWithContext(ctx, job.SrcPath, job.DstPath); err != nil {
+				return err
+			}
+
+			progress.Size, progress.Bytes = info.Size(), info.Size()
+			m.report(progress)
+
+			return nil
+		}
+
+		if info, err := job.Src.Stat(job.SrcPath); err == nil {
+			progress.Size = info.Size()
+		}
+
+		body, err := job.Src.GetWithContext(ctx, job.SrcPath)
+
+		if err != nil {
+			return err
+		}
+
+		defer body.Close()
+
+		return job.Dst.PutWithContext(ctx, job.DstPath, &progressReader{r: body, manager: m, progress: progress})
+	case TransferUpload:
+		file, err := os.Open(job.SrcPath)
+
+		if err != nil {
+			return err
+		}
+
+		defer file.Close()
+
+		if info, err := file.Stat(); err == nil {
+			progress.Size = info.Size()
+		}
+
+		return job.Dst.PutWithContext(ctx, job.DstPath, &progressReader{r: file, manager: m, progress: progress})
+	case TransferDownload:
+		if info, err := job.Src.Stat(job.SrcPath); err == nil {
+			progress.Size = info.Size()
+		}
+
+		body, err := job.Src.GetWithContext(ctx, job.SrcPath)
+
+		if err != nil {
+			return err
+		}
+
+		defer body.Close()
+
+		return atomicfile.Write(ctx, job.DstPath, &progressReader{r: body, manager: m, progress: progress}, atomicfile.FileMode, atomicfile.DirMode)
+	}
+
+	return fmt.Errorf("unknown transfer kind %d", int(job.Kind))
+}
+
+// report call the progress callback
+func (m *TransferManager) report(progress *TransferProgress) {
+	if m.Progress == nil {
+		return
+	}
+
+	m.progressMu.Lock()
+	defer m.progressMu.Unlock()
+
+	report := *progress
+	m.Progress(&report)
+}
+
+// progressReader reports the progress after every transferProgressStep bytes
+type progressReader struct {
+	r        io.Reader
+	manager  *TransferManager
+	progress *TransferProgress
+	reported int64
+}
+
+// Read reads from underlying reader and reports the bytes
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.progress.Bytes += int64(n)
+
+	if r.progress.Bytes-r.reported >= transferProgressStep {
+		r.reported = r.progress.Bytes
+		r.manager.report(r.progress)
+	}
+
+	return n, err
+}
+
+// SameStorage check if both storages are the same value, so objects can be copied inside of it.
+// Nil storages and values of the types that are not comparable are never the same.
+func SameStorage(a Storage, b Storage) bool {
+	typ := reflect.TypeOf(a)
+
+	return typ != nil && typ == reflect.TypeOf(b) && typ.Comparable() && a == b
+}
@@ -0,0 +1,211 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/protsack-stephan/dev-toolkit/lib/mem"
+	"github.com/protsack-stephan/dev-toolkit/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+var transferTestData = []byte("hello transfer")
+
+type transferTestError struct{}
+
+func (transferTestError) Error() string   { return "temporary failure" }
+func (transferTestError) Temporary() bool { return true }
+
+// unstableStorage fails the first uploads with temporary error after reading part of the body
+type unstableStorage struct {
+	*mem.Storage
+	failures int
+}
+
+func (s *unstableStorage) PutWithContext(ctx context.Context, path string, body io.Reader) error {
+	if s.failures > 0 {
+		s.failures--
+		_, _ = body.Read(make([]byte, 4))
+		return transferTestError{}
+	}
+
+	return s.Storage.PutWithContext(ctx, path, body)
+}
+
+func TestTransferManager(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	read := func(store storage.Storage, path string) []byte {
+		body, err := store.Get(path)
+
+		if err != nil {
+			return nil
+		}
+
+		defer body.Close()
+		data, _ := io.ReadAll(body)
+
+		return data
+	}
+
+	t.Run("copy, upload and download", func(t *testing.T) {
+		dir := t.TempDir()
+		src, dst := mem.NewStorage(), mem.NewStorage()
+		assert.NoError(src.Put("a.txt", bytes.NewReader(transferTestData)))
+		assert.NoError(os.WriteFile(filepath.Join(dir, "b.txt"), transferTestData, 0644))
+
+		manager := storage.NewTransferManager(2)
+		summary := manager.RunJobs(ctx,
+			storage.CopyJob(src, "a.txt", dst, "copy/a.txt"),
+			storage.CopyJob(src, "a.txt", src, "copy/a.txt"),
+			storage.UploadJob(filepath.Join(dir, "b.txt"), dst, "upload/b.txt"),
+			storage.DownloadJob(src, "a.txt", filepath.Join(dir, "download", "a.txt")),
+		)
+		assert.NoError(summary.Err())
+		assert.Len(summary.Succeeded, 4)
+		assert.Empty(summary.Failed)
+		assert.Equal(int64(len(transferTestData)*4), summary.Bytes)
+		assert.Equal(transferTestData, read(dst, "copy/a.txt"))
+		assert.Equal(transferTestData, read(src, "copy/a.txt"))
+		assert.Equal(transferTestData, read(dst, "upload/b.txt"))
+
+		data, err := os.ReadFile(filepath.Join(dir, "download", "a.txt"))
+		assert.NoError(err)
+		assert.Equal(transferTestData, data)
+
+		entries, err := os.ReadDir(filepath.Join(dir, "download"))
+		assert.NoError(err)
+		assert.Len(entries, 1)
+	})
+
+	t.Run("report progress", func(t *testing.T) {
+		data := bytes.Repeat([]byte("a"), 7<<19)
+		src, dst := mem.NewStorage(), mem.NewStorage()
+		assert.NoError(src.Put("a.txt", bytes.NewReader(data)))
+		progress := []storage.TransferProgress{}
+
+		manager := storage.NewTransferManager(1)
+		manager.Progress = func(p *storage.TransferProgress) {
+			progress = append(progress, *p)
+		}
+		summary := manager.RunJobs(ctx, storage.CopyJob(src, "a.txt", dst, "a.txt"))
+		assert.NoError(summary.Err())
+		assert.Greater(len(progress), 1)
+		assert.LessOrEqual(len(progress), 4)
+
+		last := progress[len(progress)-1]
+		assert.True(last.Done)
+		assert.NoError(last.Err)
+		assert.Equal(1, last.Attempt)
+		assert.Equal(int64(len(data)), last.Bytes)
+		assert.Equal(int64(len(data)), last.Size)
+
+		reported := int64(0)
+
+		for _, p := range progress[:len(progress)-1] {
+			assert.False(p.Done)
+			assert.GreaterOrEqual(p.Bytes-reported, int64(1<<20))
+			assert.LessOrEqual(p.Bytes, last.Bytes)
+			reported = p.Bytes
+		}
+	})
+
+	t.Run("retry failed job", func(t *testing.T) {
+		src, dst := mem.NewStorage(), &unstableStorage{Storage: mem.NewStorage(), failures: 2}
+		assert.NoError(src.Put("a.txt", bytes.NewReader(transferTestData)))
+		var last storage.TransferProgress
+
+		manager := storage.NewTransferManager(1)
+		manager.Retry = storage.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+		manager.Progress = func(p *storage.TransferProgress) {
+			last = *p
+		}
+		summary := manager.RunJobs(ctx, storage.CopyJob(src, "a.txt", dst, "a.txt"))
+		assert.NoError(summary.Err())
+		assert.Equal(3, last.Attempt)
+		assert.Equal(int64(len(transferTestData)), last.Bytes)
+		assert.Equal(int64(len(transferTestData)), summary.Bytes)
+		assert.Equal(transferTestData, read(dst, "a.txt"))
+	})
+
+	t.Run("summary of failures", func(t *testing.T) {
+		src, dst := mem.NewStorage(), mem.NewStorage()
+		assert.NoError(src.Put("a.txt", bytes.NewReader(transferTestData)))
+
+		manager := storage.NewTransferManager(4)
+		manager.Retry = storage.RetryPolicy{MaxAttempts: 1}
+		summary := manager.RunJobs(ctx,
+			storage.CopyJob(src, "a.txt", dst, "a.txt"),
+			storage.CopyJob(src, "b.txt", dst, "b.txt"),
+			storage.UploadJob(filepath.Join(t.TempDir(), "c.txt"), dst, "c.txt"),
+			storage.CopyJob(src, "a.txt", &unstableStorage{Storage: mem.NewStorage(), failures: 1}, "d.txt"),
+		)
+		assert.Len(summary.Succeeded, 1)
+		assert.Len(summary.Failed, 3)
+		assert.Error(summary.Err())
+
+		paths := []string{}
+
+		for _, failure := range summary.Failed {
+			assert.Error(failure.Err)
+			paths = append(paths, failure.Job.DstPath)
+		}
+
+		sort.Strings(paths)
+		assert.Equal([]string{"b.txt", "c.txt", "d.txt"}, paths)
+		assert.ErrorIs(summary.Err(), summary.Failed[0].Err)
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		src, dst := mem.NewStorage(), mem.NewStorage()
+		assert.NoError(src.Put("a.txt", bytes.NewReader(transferTestData)))
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		summary := storage.NewTransferManager(0).RunJobs(cctx,
+			storage.CopyJob(src, "a.txt", dst, "a.txt"),
+			storage.CopyJob(src, "a.txt", dst, "b.txt"),
+		)
+		assert.Empty(summary.Succeeded)
+		assert.Len(summary.Failed, 2)
+		assert.ErrorIs(summary.Err(), context.Canceled)
+		assert.Nil(read(dst, "a.txt"))
+	})
+
+	t.Run("run jobs from the queue", func(t *testing.T) {
+		src, dst := mem.NewStorage(), mem.NewStorage()
+		assert.NoError(src.Put("a.txt", bytes.NewReader(transferTestData)))
+		jobs := make(chan *storage.TransferJob)
+
+		go func() {
+			defer close(jobs)
+
+			for _, path := range []string{"a.txt", "b.txt", "c.txt"} {
+				jobs <- storage.CopyJob(src, "a.txt", dst, path)
+			}
+		}()
+
+		summary := storage.NewTransferManager(2).Run(ctx, jobs)
+		assert.NoError(summary.Err())
+		assert.Len(summary.Succeeded, 3)
+		assert.Equal(transferTestData, read(dst, "c.txt"))
+	})
+}
+
+func TestSameStorage(t *testing.T) {
+	assert := assert.New(t)
+	store := mem.NewStorage()
+
+	assert.True(storage.SameStorage(store, store))
+	assert.False(storage.SameStorage(store, mem.NewStorage()))
+	assert.False(storage.SameStorage(store, nil))
+	assert.False(storage.SameStorage(nil, store))
+	assert.False(storage.SameStorage(nil, nil))
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+)
+
+// WalkIter walks flat listing for the backends that don't have real directories.
+// Directories are reported before the first file inside of them, SkipDir skips the rest of the directory.
+// The iterator has to return paths under the walk path in lexical order.
+func WalkIter(iter Iterator, path string, callback func(entry *Entry) error) error {
+	root := path[:strings.LastIndex(path, "/")+1]
+	dirs := []string{}
+	skip := ""
+
+	for iter.Next() {
+		entry := iter.Entry()
+
+		if len(skip) > 0 && strings.HasPrefix(entry.Path, skip) {
+			continue
+		}
+
+		skip = ""
+
+		for len(dirs) > 0 && !strings.HasPrefix(entry.Path, dirs[len(dirs)-1]+"/") {
+			dirs = dirs[:len(dirs)-1]
+		}
+
+		for dir := openDir(entry.Path, root, dirs); len(dir) > 0; dir = openDir(entry.Path, root, dirs) {
+			dirs = append(dirs, dir)
+			err := callback(&Entry{Path: dir, IsDir: true})
+
+			if errors.Is(err, SkipDir) {
+				skip = dir + "/"
+				break
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(skip) > 0 {
+			continue
+		}
+
+		if err := callback(entry); errors.Is(err, SkipDir) {
+			skip = entry.Path[:strings.LastIndex(entry.Path, "/")+1]
+
+			if len(skip) <= len(root) {
+				return nil
+			}
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return iter.Err()
+}
+
+// openDir get next directory of the path that is not opened yet
+func openDir(path string, root string, dirs []string) string {
+	start := len(root)
+
+	if len(dirs) > 0 {
+		start = len(dirs[len(dirs)-1]) + 1
+	}
+
+	if start > len(path) {
+		return ""
+	}
+
+	if idx := strings.Index(path[start:], "/"); idx >= 0 {
+		return path[:start+idx]
+	}
+
+	return ""
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errWalkTestStop = errors.New("stop walk")
+
+type sliceIterator struct {
+	paths []string
+	idx   int
+}
+
+func (it *sliceIterator) Next() bool {
+	it.idx++
+	return it.idx < len(it.paths)
+}
+
+func (it *sliceIterator) Entry() *Entry {
+	return &Entry{Path: it.paths[it.idx]}
+}
+
+func (it *sliceIterator) Err() error {
+	return nil
+}
+
+func (it *sliceIterator) Token() string {
+	return it.paths[it.idx]
+}
+
+func TestWalkIter(t *testing.T) {
+	assert := assert.New(t)
+	paths := []string{
+		"walk/a.txt",
+		"walk/b/c/d.txt",
+		"walk/b/c/e.txt",
+		"walk/b/f.txt",
+		"walk/g/h.txt",
+		"walk/i.txt",
+	}
+
+	walk := func(skip string) []string {
+		visited := []string{}
+
+		assert.NoError(WalkIter(&sliceIterator{paths: paths, idx: -1}, "walk/", func(entry *Entry) error {
+			path := entry.Path
+
+			if entry.IsDir {
+				path += "/"
+			}
+
+			visited = append(visited, path)
+
+			if entry.Path == skip {
+				return SkipDir
+			}
+
+			return nil
+		}))
+
+		return visited
+	}
+
+	t.Run("walk all entries", func(t *testing.T) {
+		assert.Equal([]string{
+			"walk/a.txt",
+			"walk/b/",
+			"walk/b/c/",
+			"walk/b/c/d.txt",
+			"walk/b/c/e.txt",
+			"walk/b/f.txt",
+			"walk/g/",
+			"walk/g/h.txt",
+			"walk/i.txt",
+		}, walk(""))
+	})
+
+	t.Run("skip directory", func(t *testing.T) {
+		assert.Equal([]string{"walk/a.txt", "walk/b/", "walk/g/", "walk/g/h.txt", "walk/i.txt"}, walk("walk/b"))
+	})
+
+	t.Run("skip rest of the directory", func(t *testing.T) {
+		assert.Equal([]string{
+			"walk/a.txt",
+			"walk/b/",
+			"walk/b/c/",
+			"walk/b/c/d.txt",
+			"walk/b/f.txt",
+			"walk/g/",
+			"walk/g/h.txt",
+			"walk/i.txt",
+		}, walk("walk/b/c/d.txt"))
+	})
+
+	t.Run("skip rest of the root", func(t *testing.T) {
+		assert.Equal([]string{"walk/a.txt"}, walk("walk/a.txt"))
+	})
+
+	t.Run("stop with error", func(t *testing.T) {
+		err := WalkIter(&sliceIterator{paths: paths, idx: -1}, "walk/", func(entry *Entry) error {
+			return errWalkTestStop
+		})
+		assert.ErrorIs(err, errWalkTestStop)
+	})
+}